@@ -0,0 +1,102 @@
+// Package exchange describes the operations the trading bots need from a venue. The qryptos clients implement these
+// interfaces as they are, so strategies can depend on them and be pointed at fakes, simulators or other venues.
+package exchange
+
+import (
+	"context"
+	"time"
+
+	"github.com/tobyjsullivan/shifty/qryptos"
+)
+
+// MarketData is the public, unauthenticated view of a venue.
+type MarketData interface {
+	FetchProductsContext(ctx context.Context) ([]*qryptos.ProductDetails, error)
+	FetchOrderBookContext(ctx context.Context, productId, depth int) (*qryptos.OrderBook, error)
+	FetchExecutionsContext(ctx context.Context, productId int, since time.Time) ([]*qryptos.Trade, error)
+}
+
+// OrderManager places and tracks the account's orders.
+type OrderManager interface {
+	FetchAllOrdersContext(ctx context.Context, q *qryptos.OrdersQuery) ([]*qryptos.OrderDetails, error)
+	CreateOrderContext(ctx context.Context, order *qryptos.OrderRequest) (int, error)
+	EditOrderContext(ctx context.Context, orderId int, quantity, price qryptos.Amount) error
+	CancelOrderContext(ctx context.Context, orderId int) error
+}
+
+// Balances reports the account's holdings.
+type Balances interface {
+	FetchAccountBalancesContext(ctx context.Context) ([]*qryptos.AccountBalance, error)
+}
+
+// Exchange is everything a bot needs to trade on a venue.
+type Exchange interface {
+	MarketData
+	OrderManager
+	Balances
+}
+
+// StatsReporter is implemented by exchanges that count their requests.
+type StatsReporter interface {
+	Stats() qryptos.ClientStats
+}
+
+// BulkCanceller is implemented by venues that can cancel many orders in one call.
+type BulkCanceller interface {
+	CancelOrdersContext(ctx context.Context, f *qryptos.CancelFilter) (*qryptos.CancelReport, error)
+}
+
+var (
+	_ MarketData    = (*qryptos.PublicClient)(nil)
+	_ OrderManager  = (*qryptos.PrivateClient)(nil)
+	_ Balances      = (*qryptos.PrivateClient)(nil)
+	_ BulkCanceller = (*qryptos.PrivateClient)(nil)
+	_ Exchange      = (*Qryptos)(nil)
+)
+
+// CancelOrders cancels every live order matching f, using the venue's own bulk cancel when it has one. Like
+// PrivateClient.CancelOrders, it only returns an error if the orders couldn't be listed.
+func CancelOrders(ctx context.Context, orders OrderManager, f *qryptos.CancelFilter) (*qryptos.CancelReport, error) {
+	if bulk, ok := orders.(BulkCanceller); ok {
+		return bulk.CancelOrdersContext(ctx, f)
+	}
+
+	if f == nil {
+		f = &qryptos.CancelFilter{}
+	}
+	live, err := orders.FetchAllOrdersContext(ctx, &qryptos.OrdersQuery{
+		Status:    qryptos.OrderStatusLive,
+		ProductID: f.ProductID,
+		Side:      f.Side,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return qryptos.CancelMatching(ctx, live, f, orders.CancelOrderContext), nil
+}
+
+// Qryptos combines the public and private qryptos clients into an Exchange.
+type Qryptos struct {
+	*qryptos.PublicClient
+	*qryptos.PrivateClient
+}
+
+func NewQryptos(public *qryptos.PublicClient, private *qryptos.PrivateClient) *Qryptos {
+	return &Qryptos{
+		PublicClient:  public,
+		PrivateClient: private,
+	}
+}
+
+// Stats totals the request counts of both clients.
+func (q *Qryptos) Stats() qryptos.ClientStats {
+	public := q.PublicClient.Stats()
+	private := q.PrivateClient.Stats()
+
+	return qryptos.ClientStats{
+		Requests:  public.Requests + private.Requests,
+		Retries:   public.Retries + private.Retries,
+		Throttled: public.Throttled + private.Throttled,
+	}
+}
@@ -0,0 +1,183 @@
+package paper
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/tobyjsullivan/shifty/exchange"
+	"github.com/tobyjsullivan/shifty/qryptos"
+)
+
+// Quote is the best bid and ask for a product at a point in the feed.
+type Quote struct {
+	ProductID int
+	Bid       qryptos.Amount
+	Ask       qryptos.Amount
+}
+
+// ApplyQuote moves the market. Resting orders the new quote crosses fill in full at their own price as makers,
+// since the quote moving through them means someone traded against them.
+func (e *Exchange) ApplyQuote(q Quote) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	product, ok := e.products[q.ProductID]
+	if !ok {
+		return nil
+	}
+	product.MarketBid = q.Bid
+	product.MarketAsk = q.Ask
+
+	for _, o := range e.restingOrders(q.ProductID, "") {
+		if !e.crosses(product, o.details.Side, o.details.Price) {
+			continue
+		}
+		if err := e.fill(o, o.remaining(), o.details.Price, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ApplyTrade replays a public trade. Resting orders on the side the taker traded against, priced at or better than
+// the trade, fill as makers in price then time priority until the trade's quantity is used up. Orders at exactly the
+// trade price are assumed to be at the front of the queue, so results are optimistic for thin books.
+func (e *Exchange) ApplyTrade(trade *qryptos.Trade) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	copied := *trade
+	e.tape = append(e.tape, &copied)
+
+	// A taker sell hits resting buys and a taker buy lifts resting sells. An unknown side may fill either.
+	makerSide := ""
+	switch trade.TakerSide {
+	case qryptos.OrderSideSell:
+		makerSide = qryptos.OrderSideBuy
+	case qryptos.OrderSideBuy:
+		makerSide = qryptos.OrderSideSell
+	}
+
+	available := trade.Quantity
+	for _, o := range e.restingOrders(trade.ProductID, makerSide) {
+		if available <= qryptos.AmountZero {
+			break
+		}
+
+		crossed := o.details.Price >= trade.Price
+		if o.details.Side == qryptos.OrderSideSell {
+			crossed = o.details.Price <= trade.Price
+		}
+		if !crossed {
+			continue
+		}
+
+		quantity := o.remaining()
+		if quantity > available {
+			quantity = available
+		}
+		if err := e.fill(o, quantity, o.details.Price, false); err != nil {
+			return err
+		}
+		available -= quantity
+	}
+
+	return nil
+}
+
+// Replay applies each trade in turn.
+func (e *Exchange) Replay(trades []*qryptos.Trade) error {
+	for _, trade := range trades {
+		if err := e.ApplyTrade(trade); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restingOrders returns the product's live orders on side (or both sides when side is ""), best priced first and
+// then oldest first. The caller must hold e.mu.
+func (e *Exchange) restingOrders(productId int, side string) []*order {
+	var out []*order
+	for _, o := range e.orders {
+		if o.details.ProductID != productId || o.details.Status != qryptos.OrderStatusLive {
+			continue
+		}
+		if side != "" && o.details.Side != side {
+			continue
+		}
+		out = append(out, o)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i].details, out[j].details
+		if a.Price != b.Price && a.Side == b.Side {
+			if a.Side == qryptos.OrderSideBuy {
+				return a.Price > b.Price
+			}
+			return a.Price < b.Price
+		}
+		return a.ID < b.ID
+	})
+
+	return out
+}
+
+// RandomWalk generates a synthetic feed of n quotes. The mid price takes a normally distributed step of volatility
+// (as a fraction of the price) each quote and the spread stays as it started. Prices are rounded to tick.
+func RandomWalk(rng *rand.Rand, start Quote, tick qryptos.Amount, volatility float64, n int) []Quote {
+	if tick <= 0 {
+		tick = qryptos.MinimalUnit
+	}
+
+	spread := start.Ask - start.Bid
+	mid := float64(start.Bid+start.Ask) / 2
+
+	out := make([]Quote, n)
+	for i := range out {
+		mid *= math.Exp(rng.NormFloat64() * volatility)
+
+		bid := qryptos.Amount(mid-float64(spread)/2) / tick * tick
+		if bid < tick {
+			bid = tick
+		}
+		out[i] = Quote{
+			ProductID: start.ProductID,
+			Bid:       bid,
+			Ask:       bid + spread,
+		}
+	}
+
+	return out
+}
+
+// Follow polls live market data and applies each product's quote until the context is cancelled. It lets a bot
+// trade on paper against the real market.
+func (e *Exchange) Follow(ctx context.Context, md exchange.MarketData, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		products, err := md.FetchProductsContext(ctx)
+		if err != nil {
+			fmt.Println("ERROR [Follow] Error fetching products:", err.Error())
+		}
+		for _, product := range products {
+			if err := e.ApplyQuote(Quote{ProductID: product.ProductID, Bid: product.MarketBid, Ask: product.MarketAsk}); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
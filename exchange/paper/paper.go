@@ -0,0 +1,635 @@
+// Package paper is a simulated exchange for running strategies without real money. Orders rest in an in-memory book
+// and fill as quotes and trades from a replayed or synthetic feed cross them.
+package paper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tobyjsullivan/shifty/exchange"
+	"github.com/tobyjsullivan/shifty/qryptos"
+)
+
+const (
+	endpointOrders = "/orders"
+
+	// Rejections use the same codes as the exchange so the qryptos error predicates recognise them
+	errorCodeInsufficientFunds = "not_enough_free_balance"
+	errorCodeOrderSize         = "less_than_order_size"
+	errorCodeTickSize          = "invalid_tick_size"
+	errorCodeWouldTake         = "post_only_order_would_take"
+	errorCodeNotLive           = "order_not_live"
+	errorCodeUnknownProduct    = "product_not_found"
+	errorCodeUnsupportedType   = "order_type_not_supported"
+	errorCodeNoMarket          = "no_market"
+
+	defaultQuoteDepth = qryptos.AmountRatio
+)
+
+var _ exchange.Exchange = (*Exchange)(nil)
+
+// Fees are charged in the quoted currency as a fraction of the traded value, so Amount(100000) is 0.1%.
+type Fees = qryptos.Fees
+
+// Option configures an Exchange.
+type Option func(*Exchange)
+
+// WithFees charges the same fees on every product in place of each product's own MakerFee and TakerFee. The
+// products are listed with the fees that are charged.
+func WithFees(fees Fees) Option {
+	return func(e *Exchange) {
+		e.fees = &fees
+	}
+}
+
+// WithClock replaces time.Now for order and execution timestamps.
+func WithClock(now func() time.Time) Option {
+	return func(e *Exchange) {
+		e.now = now
+	}
+}
+
+// WithQuoteDepth sets the quantity shown at the quoted bid and ask in FetchOrderBook.
+func WithQuoteDepth(depth qryptos.Amount) Option {
+	return func(e *Exchange) {
+		e.quoteDepth = depth
+	}
+}
+
+type order struct {
+	details  *qryptos.OrderDetails
+	postOnly bool
+
+	// reserved is the part of the balance held for the unfilled remainder: quoted currency for buys, base
+	// currency for sells.
+	reserved qryptos.Amount
+}
+
+func (o *order) remaining() qryptos.Amount {
+	return o.details.Quantity - o.details.FilledQuantity
+}
+
+func (o *order) reservedCurrency() string {
+	if o.details.Side == qryptos.OrderSideBuy {
+		return o.details.QuoteCurrency
+	}
+	return o.details.BaseCurrency
+}
+
+// Exchange implements exchange.Exchange in memory. It is safe for concurrent use.
+type Exchange struct {
+	fees       *Fees
+	now        func() time.Time
+	quoteDepth qryptos.Amount
+
+	mu              sync.Mutex
+	products        map[int]*qryptos.ProductDetails
+	balances        map[string]qryptos.Amount
+	orders          []*order
+	executions      []*qryptos.Execution
+	tape            []*qryptos.Trade
+	nextOrderId     int
+	nextExecutionId int
+}
+
+// New starts a simulated account holding the given balances. The products' MarketBid and MarketAsk are the
+// opening quotes, and their MakerFee and TakerFee are charged on fills unless WithFees overrides them.
+func New(products []*qryptos.ProductDetails, balances map[string]qryptos.Amount, opts ...Option) *Exchange {
+	e := &Exchange{
+		now:        time.Now,
+		quoteDepth: defaultQuoteDepth,
+		products:   make(map[int]*qryptos.ProductDetails),
+		balances:   make(map[string]qryptos.Amount),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	for _, product := range products {
+		copied := *product
+		if e.fees != nil {
+			copied.MakerFee, copied.TakerFee = e.fees.Maker, e.fees.Taker
+		}
+		e.products[product.ProductID] = &copied
+	}
+	for currency, balance := range balances {
+		e.balances[currency] = balance
+	}
+
+	return e
+}
+
+func reject(method, endpoint string, status int, field, code string) error {
+	return &qryptos.APIError{
+		Method:     method,
+		Endpoint:   endpoint,
+		StatusCode: status,
+		Errors:     map[string][]string{field: {code}},
+	}
+}
+
+func orderEndpoint(orderId int) string {
+	return fmt.Sprintf("%s/%d", endpointOrders, orderId)
+}
+
+// freeBalance is the balance not held by live orders. The caller must hold e.mu.
+func (e *Exchange) freeBalance(currency string) qryptos.Amount {
+	free := e.balances[currency]
+	for _, o := range e.orders {
+		if o.details.Status == qryptos.OrderStatusLive && o.reservedCurrency() == currency {
+			free -= o.reserved
+		}
+	}
+
+	return free
+}
+
+// feesFor is the fee schedule charged on productId. The caller must hold e.mu.
+func (e *Exchange) feesFor(productId int) Fees {
+	if product, ok := e.products[productId]; ok {
+		return product.Fees()
+	}
+	return Fees{}
+}
+
+// reservation is what an order must hold for its unfilled remainder. Buys reserve enough to pay the taker fee in
+// case an edit makes them marketable.
+func (e *Exchange) reservation(o *order) (qryptos.Amount, error) {
+	if o.details.Side == qryptos.OrderSideSell {
+		return o.remaining(), nil
+	}
+
+	return e.feesFor(o.details.ProductID).BuyCost(o.remaining(), o.details.Price, true)
+}
+
+// checkFunds reports whether the account can hold need of currency on top of what is already reserved, ignoring
+// the reservation of the order being replaced, if any.
+func (e *Exchange) checkFunds(currency string, need qryptos.Amount, replacing *order) bool {
+	free := e.freeBalance(currency)
+	if replacing != nil && replacing.details.Status == qryptos.OrderStatusLive {
+		free += replacing.reserved
+	}
+
+	return need <= free
+}
+
+func (e *Exchange) CreateOrderContext(ctx context.Context, req *qryptos.OrderRequest) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err := req.Validate(); err != nil {
+		return 0, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	product, ok := e.products[req.ProductID]
+	if !ok {
+		return 0, reject(http.MethodPost, endpointOrders, http.StatusUnprocessableEntity, "product_id", errorCodeUnknownProduct)
+	}
+	if req.Type != qryptos.OrderTypeLimit && req.Type != qryptos.OrderTypeMarket {
+		return 0, reject(http.MethodPost, endpointOrders, http.StatusUnprocessableEntity, "order_type", errorCodeUnsupportedType)
+	}
+
+	price := req.Price
+	if req.Type == qryptos.OrderTypeMarket {
+		price = product.MarketAsk
+		if req.Side == qryptos.OrderSideSell {
+			price = product.MarketBid
+		}
+		if price <= qryptos.AmountZero {
+			return 0, reject(http.MethodPost, endpointOrders, http.StatusUnprocessableEntity, "price", errorCodeNoMarket)
+		}
+	}
+	if err := product.CheckOrder(req.Quantity, product.QuantizePrice(price, req.Side)); err != nil {
+		return 0, reject(http.MethodPost, endpointOrders, http.StatusUnprocessableEntity, "quantity", errorCodeOrderSize)
+	}
+	if req.Type == qryptos.OrderTypeLimit && product.QuantizePrice(price, req.Side) != price {
+		return 0, reject(http.MethodPost, endpointOrders, http.StatusUnprocessableEntity, "price", errorCodeTickSize)
+	}
+
+	marketable := e.crosses(product, req.Side, price)
+	if req.PostOnly && marketable {
+		return 0, reject(http.MethodPost, endpointOrders, http.StatusUnprocessableEntity, "price", errorCodeWouldTake)
+	}
+
+	now := e.now()
+	e.nextOrderId++
+	o := &order{
+		details: &qryptos.OrderDetails{
+			ID:               e.nextOrderId,
+			ProductID:        product.ProductID,
+			Side:             req.Side,
+			Status:           qryptos.OrderStatusLive,
+			CurrencyPairCode: product.CurrencyPairCode,
+			BaseCurrency:     product.BaseCurrency,
+			QuoteCurrency:    product.QuotedCurrency,
+			Price:            price,
+			Quantity:         req.Quantity,
+			CreatedAt:        now,
+			UpdatedAt:        now,
+		},
+		postOnly: req.PostOnly,
+	}
+
+	reserved, err := e.reservation(o)
+	if err != nil {
+		return 0, err
+	}
+	if !e.checkFunds(o.reservedCurrency(), reserved, nil) {
+		e.nextOrderId--
+		return 0, reject(http.MethodPost, endpointOrders, http.StatusUnprocessableEntity, "user", errorCodeInsufficientFunds)
+	}
+	o.reserved = reserved
+	e.orders = append(e.orders, o)
+
+	// Marketable orders take liquidity at the quoted price, which is the best we could have got
+	if marketable {
+		fillPrice := product.MarketAsk
+		if req.Side == qryptos.OrderSideSell {
+			fillPrice = product.MarketBid
+		}
+		if err := e.fill(o, o.remaining(), fillPrice, true); err != nil {
+			return 0, err
+		}
+	}
+
+	return o.details.ID, nil
+}
+
+// crosses reports whether an order at price would trade against the current quote.
+func (e *Exchange) crosses(product *qryptos.ProductDetails, side string, price qryptos.Amount) bool {
+	if side == qryptos.OrderSideBuy {
+		return product.MarketAsk > qryptos.AmountZero && price >= product.MarketAsk
+	}
+	return product.MarketBid > qryptos.AmountZero && price <= product.MarketBid
+}
+
+func (e *Exchange) findOrder(orderId int) *order {
+	for _, o := range e.orders {
+		if o.details.ID == orderId {
+			return o
+		}
+	}
+	return nil
+}
+
+// EditOrderContext changes the quantity and price of a live order. An edit that crosses the quote fills as a taker.
+func (e *Exchange) EditOrderContext(ctx context.Context, orderId int, quantity, price qryptos.Amount) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	endpoint := orderEndpoint(orderId)
+	o := e.findOrder(orderId)
+	if o == nil {
+		return reject(http.MethodPut, endpoint, http.StatusNotFound, "id", "not_found")
+	}
+	if o.details.Status != qryptos.OrderStatusLive {
+		return reject(http.MethodPut, endpoint, http.StatusUnprocessableEntity, "status", errorCodeNotLive)
+	}
+
+	product := e.products[o.details.ProductID]
+	if quantity <= o.details.FilledQuantity || product.CheckOrder(quantity, price) != nil {
+		return reject(http.MethodPut, endpoint, http.StatusUnprocessableEntity, "quantity", errorCodeOrderSize)
+	}
+
+	marketable := e.crosses(product, o.details.Side, price)
+	if o.postOnly && marketable {
+		return reject(http.MethodPut, endpoint, http.StatusUnprocessableEntity, "price", errorCodeWouldTake)
+	}
+
+	edited := &order{details: &qryptos.OrderDetails{}, postOnly: o.postOnly}
+	*edited.details = *o.details
+	edited.details.Quantity = quantity
+	edited.details.Price = price
+	reserved, err := e.reservation(edited)
+	if err != nil {
+		return err
+	}
+	if !e.checkFunds(o.reservedCurrency(), reserved, o) {
+		return reject(http.MethodPut, endpoint, http.StatusUnprocessableEntity, "user", errorCodeInsufficientFunds)
+	}
+
+	o.details.Quantity = quantity
+	o.details.Price = price
+	o.details.UpdatedAt = e.now()
+	o.reserved = reserved
+
+	if marketable {
+		fillPrice := product.MarketAsk
+		if o.details.Side == qryptos.OrderSideSell {
+			fillPrice = product.MarketBid
+		}
+		return e.fill(o, o.remaining(), fillPrice, true)
+	}
+
+	return nil
+}
+
+func (e *Exchange) CancelOrderContext(ctx context.Context, orderId int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	endpoint := orderEndpoint(orderId) + "/cancel"
+	o := e.findOrder(orderId)
+	if o == nil {
+		return reject(http.MethodPut, endpoint, http.StatusNotFound, "id", "not_found")
+	}
+	if o.details.Status != qryptos.OrderStatusLive {
+		return reject(http.MethodPut, endpoint, http.StatusUnprocessableEntity, "status", errorCodeNotLive)
+	}
+
+	o.details.Status = qryptos.OrderStatusCancelled
+	o.details.UpdatedAt = e.now()
+	o.reserved = qryptos.AmountZero
+
+	return nil
+}
+
+// fill executes quantity of the order at price and settles balances and fees. The caller must hold e.mu.
+func (e *Exchange) fill(o *order, quantity, price qryptos.Amount, taker bool) error {
+	feeRate := e.feesFor(o.details.ProductID).Rate(taker)
+
+	// Round in the exchange's favour: buyers pay up and sellers receive less
+	rounding := qryptos.RoundCeil
+	if o.details.Side == qryptos.OrderSideSell {
+		rounding = qryptos.RoundFloor
+	}
+	value, err := quantity.Multiply(price, rounding)
+	if err != nil {
+		return err
+	}
+	fee, err := value.Multiply(feeRate, qryptos.RoundCeil)
+	if err != nil {
+		return err
+	}
+
+	base, quote := o.details.BaseCurrency, o.details.QuoteCurrency
+	if o.details.Side == qryptos.OrderSideBuy {
+		e.balances[quote] -= value + fee
+		e.balances[base] += quantity
+	} else {
+		e.balances[base] -= quantity
+		e.balances[quote] += value - fee
+	}
+
+	now := e.now()
+	e.nextExecutionId++
+	o.details.FilledQuantity += quantity
+	o.details.UpdatedAt = now
+	o.details.Executions = append(o.details.Executions, &qryptos.ExecutionDetails{
+		ID:       e.nextExecutionId,
+		Quantity: quantity,
+		Price:    price,
+	})
+
+	takerSide := o.details.Side
+	if !taker {
+		takerSide = qryptos.OrderSideBuy
+		if o.details.Side == qryptos.OrderSideBuy {
+			takerSide = qryptos.OrderSideSell
+		}
+	}
+	e.executions = append(e.executions, &qryptos.Execution{
+		ID:        e.nextExecutionId,
+		OrderID:   o.details.ID,
+		ProductID: o.details.ProductID,
+		Side:      o.details.Side,
+		Price:     price,
+		Quantity:  quantity,
+		Fee:       fee,
+		Taker:     taker,
+		CreatedAt: now,
+	})
+	e.tape = append(e.tape, &qryptos.Trade{
+		ID:        e.nextExecutionId,
+		ProductID: o.details.ProductID,
+		Price:     price,
+		Quantity:  quantity,
+		TakerSide: takerSide,
+		CreatedAt: now,
+	})
+
+	if o.remaining() <= qryptos.AmountZero {
+		o.details.Status = qryptos.OrderStatusFilled
+		o.reserved = qryptos.AmountZero
+		return nil
+	}
+
+	o.reserved, err = e.reservation(o)
+	return err
+}
+
+func copyOrderDetails(details *qryptos.OrderDetails) *qryptos.OrderDetails {
+	copied := *details
+	copied.Executions = make([]*qryptos.ExecutionDetails, len(details.Executions))
+	for i, execution := range details.Executions {
+		executionCopy := *execution
+		copied.Executions[i] = &executionCopy
+	}
+
+	return &copied
+}
+
+func matchesQuery(q *qryptos.OrdersQuery, o *qryptos.OrderDetails) bool {
+	switch {
+	case q.Status != "" && o.Status != q.Status:
+		return false
+	case q.ProductID != 0 && o.ProductID != q.ProductID:
+		return false
+	case q.FundingCurrency != "" && o.QuoteCurrency != q.FundingCurrency:
+		return false
+	case q.Side != "" && o.Side != q.Side:
+		return false
+	case !q.CreatedAfter.IsZero() && o.CreatedAt.Before(q.CreatedAfter):
+		return false
+	case !q.CreatedBefore.IsZero() && !o.CreatedAt.Before(q.CreatedBefore):
+		return false
+	}
+
+	return true
+}
+
+// FetchOrderContext returns a copy of a single order.
+func (e *Exchange) FetchOrderContext(ctx context.Context, orderId int) (*qryptos.OrderDetails, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	o := e.findOrder(orderId)
+	if o == nil {
+		return nil, reject(http.MethodGet, orderEndpoint(orderId), http.StatusNotFound, "id", "not_found")
+	}
+
+	return copyOrderDetails(o.details), nil
+}
+
+// FetchAllOrdersContext returns copies of the matching orders, newest first like the exchange.
+func (e *Exchange) FetchAllOrdersContext(ctx context.Context, q *qryptos.OrdersQuery) ([]*qryptos.OrderDetails, error) {
+	if err := ctx.Err(); err != nil {
+		return []*qryptos.OrderDetails{}, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	out := []*qryptos.OrderDetails{}
+	for i := len(e.orders) - 1; i >= 0; i-- {
+		if matchesQuery(q, e.orders[i].details) {
+			out = append(out, copyOrderDetails(e.orders[i].details))
+		}
+	}
+
+	return out, nil
+}
+
+func (e *Exchange) FetchAccountBalancesContext(ctx context.Context) ([]*qryptos.AccountBalance, error) {
+	if err := ctx.Err(); err != nil {
+		return []*qryptos.AccountBalance{}, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	currencies := make([]string, 0, len(e.balances))
+	for currency := range e.balances {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	out := make([]*qryptos.AccountBalance, len(currencies))
+	for i, currency := range currencies {
+		out[i] = &qryptos.AccountBalance{
+			Currency: currency,
+			Balance:  e.balances[currency],
+		}
+	}
+
+	return out, nil
+}
+
+// Executions returns every fill so far, with fees, oldest first.
+func (e *Exchange) Executions() []*qryptos.Execution {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	out := make([]*qryptos.Execution, len(e.executions))
+	for i, execution := range e.executions {
+		copied := *execution
+		out[i] = &copied
+	}
+
+	return out
+}
+
+func (e *Exchange) FetchProductsContext(ctx context.Context) ([]*qryptos.ProductDetails, error) {
+	if err := ctx.Err(); err != nil {
+		return []*qryptos.ProductDetails{}, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	out := make([]*qryptos.ProductDetails, 0, len(e.products))
+	for _, product := range e.products {
+		copied := *product
+		out = append(out, &copied)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ProductID < out[j].ProductID })
+
+	return out, nil
+}
+
+// FetchOrderBookContext shows the quote, at the configured depth, merged with the account's own resting orders.
+func (e *Exchange) FetchOrderBookContext(ctx context.Context, productId, depth int) (*qryptos.OrderBook, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	product, ok := e.products[productId]
+	if !ok {
+		return nil, reject(http.MethodGet, fmt.Sprintf("/products/%d/price_levels", productId), http.StatusNotFound, "id", errorCodeUnknownProduct)
+	}
+
+	bids := make(map[qryptos.Amount]qryptos.Amount)
+	asks := make(map[qryptos.Amount]qryptos.Amount)
+	if product.MarketBid > qryptos.AmountZero {
+		bids[product.MarketBid] += e.quoteDepth
+	}
+	if product.MarketAsk > qryptos.AmountZero {
+		asks[product.MarketAsk] += e.quoteDepth
+	}
+	for _, o := range e.orders {
+		if o.details.ProductID != productId || o.details.Status != qryptos.OrderStatusLive {
+			continue
+		}
+		if o.details.Side == qryptos.OrderSideBuy {
+			bids[o.details.Price] += o.remaining()
+		} else {
+			asks[o.details.Price] += o.remaining()
+		}
+	}
+
+	book := &qryptos.OrderBook{
+		ProductID: productId,
+		Bids:      priceLevels(bids),
+		Asks:      priceLevels(asks),
+	}
+	sort.SliceStable(book.Bids, func(i, j int) bool { return book.Bids[i].Price > book.Bids[j].Price })
+	sort.SliceStable(book.Asks, func(i, j int) bool { return book.Asks[i].Price < book.Asks[j].Price })
+	if depth > 0 && len(book.Bids) > depth {
+		book.Bids = book.Bids[:depth]
+	}
+	if depth > 0 && len(book.Asks) > depth {
+		book.Asks = book.Asks[:depth]
+	}
+
+	return book, nil
+}
+
+func priceLevels(quantities map[qryptos.Amount]qryptos.Amount) []qryptos.PriceLevel {
+	out := make([]qryptos.PriceLevel, 0, len(quantities))
+	for price, quantity := range quantities {
+		out = append(out, qryptos.PriceLevel{Price: price, Quantity: quantity})
+	}
+	return out
+}
+
+// FetchExecutionsContext returns the simulated trade tape: replayed trades and the account's own fills.
+func (e *Exchange) FetchExecutionsContext(ctx context.Context, productId int, since time.Time) ([]*qryptos.Trade, error) {
+	if err := ctx.Err(); err != nil {
+		return []*qryptos.Trade{}, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	out := []*qryptos.Trade{}
+	for _, trade := range e.tape {
+		if trade.ProductID == productId && !trade.CreatedAt.Before(since) {
+			copied := *trade
+			out = append(out, &copied)
+		}
+	}
+
+	return out, nil
+}
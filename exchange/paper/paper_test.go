@@ -0,0 +1,243 @@
+package paper
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/tobyjsullivan/shifty/qryptos"
+)
+
+var testFees = Fees{
+	Maker: qryptos.Amount(100000),
+	Taker: qryptos.Amount(200000),
+}
+
+func newTestExchange(balances map[string]qryptos.Amount) *Exchange {
+	return New([]*qryptos.ProductDetails{{
+		ProductID:        56,
+		Currency:         "BTC",
+		BaseCurrency:     "VZT",
+		QuotedCurrency:   "BTC",
+		CurrencyPairCode: "VZTBTC",
+		MarketAsk:        qryptos.Amount(10400),
+		MarketBid:        qryptos.Amount(10000),
+		PriceTick:        qryptos.Amount(1),
+		QuantityStep:     qryptos.Amount(1000000),
+		MinimumQuantity:  qryptos.Amount(100000000),
+	}}, balances, WithFees(testFees))
+}
+
+func balanceOf(t *testing.T, e *Exchange, currency string) qryptos.Amount {
+	balances, err := e.FetchAccountBalancesContext(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	for _, b := range balances {
+		if b.Currency == currency {
+			return b.Balance
+		}
+	}
+	return qryptos.AmountZero
+}
+
+func fetchOrder(t *testing.T, e *Exchange, orderId int) *qryptos.OrderDetails {
+	orders, err := e.FetchAllOrdersContext(context.Background(), &qryptos.OrdersQuery{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	for _, o := range orders {
+		if o.ID == orderId {
+			return o
+		}
+	}
+	t.Fatalf("Order %d not found.", orderId)
+	return nil
+}
+
+func limitOrder(side string, quantity, price qryptos.Amount) *qryptos.OrderRequest {
+	return &qryptos.OrderRequest{
+		ProductID: 56,
+		Type:      qryptos.OrderTypeLimit,
+		Side:      side,
+		Quantity:  quantity,
+		Price:     price,
+	}
+}
+
+func TestExchange_RestingBuyFillsAsMaker(t *testing.T) {
+	e := newTestExchange(map[string]qryptos.Amount{"BTC": qryptos.Amount(1000000)})
+	ctx := context.Background()
+
+	// 50 VZT at 0.0001 holds 0.005 BTC plus the taker fee
+	orderId, err := e.CreateOrderContext(ctx, limitOrder(qryptos.OrderSideBuy, qryptos.Amount(5000000000), qryptos.Amount(10000)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	_, err = e.CreateOrderContext(ctx, limitOrder(qryptos.OrderSideBuy, qryptos.Amount(5000000000), qryptos.Amount(10000)))
+	if !qryptos.IsInsufficientFunds(err) {
+		t.Errorf("Expected an insufficient funds error; Actual: %v.", err)
+	}
+
+	if err := e.ApplyQuote(Quote{ProductID: 56, Bid: qryptos.Amount(9900), Ask: qryptos.Amount(10000)}); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	order := fetchOrder(t, e, orderId)
+	if order.Status != qryptos.OrderStatusFilled || order.FilledQuantity != order.Quantity {
+		t.Errorf("Expected a filled order: %+v", order)
+	}
+	if len(order.Executions) != 1 || order.Executions[0].Price != qryptos.Amount(10000) {
+		t.Errorf("Unexpected executions: %+v", order.Executions)
+	}
+
+	// 0.005 BTC plus the 0.1% maker fee
+	if expected := qryptos.Amount(499500); balanceOf(t, e, "BTC") != expected {
+		t.Errorf("Unexpected BTC balance. Expected: %d; Actual: %d.", expected, balanceOf(t, e, "BTC"))
+	}
+	if expected := qryptos.Amount(5000000000); balanceOf(t, e, "VZT") != expected {
+		t.Errorf("Unexpected VZT balance. Expected: %d; Actual: %d.", expected, balanceOf(t, e, "VZT"))
+	}
+}
+
+func TestExchange_MarketableOrderTakes(t *testing.T) {
+	e := newTestExchange(map[string]qryptos.Amount{"BTC": qryptos.Amount(1000000)})
+	ctx := context.Background()
+
+	postOnly := limitOrder(qryptos.OrderSideBuy, qryptos.Amount(1000000000), qryptos.Amount(10400))
+	postOnly.PostOnly = true
+	if _, err := e.CreateOrderContext(ctx, postOnly); err == nil {
+		t.Error("Expected a post-only order that would take to be rejected.")
+	}
+
+	orderId, err := e.CreateOrderContext(ctx, limitOrder(qryptos.OrderSideBuy, qryptos.Amount(1000000000), qryptos.Amount(10400)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if order := fetchOrder(t, e, orderId); order.Status != qryptos.OrderStatusFilled {
+		t.Errorf("Expected the order to fill immediately: %+v", order)
+	}
+
+	executions := e.Executions()
+	if len(executions) != 1 || !executions[0].Taker {
+		t.Fatalf("Expected one taker execution: %+v", executions)
+	}
+	if expected := qryptos.Amount(208); executions[0].Fee != expected {
+		t.Errorf("Unexpected fee. Expected: %d; Actual: %d.", expected, executions[0].Fee)
+	}
+	if expected := qryptos.Amount(895792); balanceOf(t, e, "BTC") != expected {
+		t.Errorf("Unexpected BTC balance. Expected: %d; Actual: %d.", expected, balanceOf(t, e, "BTC"))
+	}
+}
+
+func TestExchange_ChargesProductFees(t *testing.T) {
+	e := New([]*qryptos.ProductDetails{{
+		ProductID:        56,
+		Currency:         "BTC",
+		BaseCurrency:     "VZT",
+		QuotedCurrency:   "BTC",
+		CurrencyPairCode: "VZTBTC",
+		MarketAsk:        qryptos.Amount(10400),
+		MarketBid:        qryptos.Amount(10000),
+		MakerFee:         qryptos.Amount(100000),
+		TakerFee:         qryptos.Amount(300000),
+	}}, map[string]qryptos.Amount{"BTC": qryptos.Amount(1000000)})
+
+	if _, err := e.CreateOrderContext(context.Background(), limitOrder(qryptos.OrderSideBuy, qryptos.Amount(1000000000), qryptos.Amount(10400))); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	// 0.3% of 0.00104 BTC
+	executions := e.Executions()
+	if len(executions) != 1 {
+		t.Fatalf("Expected one execution: %+v", executions)
+	}
+	if expected := qryptos.Amount(312); executions[0].Fee != expected {
+		t.Errorf("Unexpected fee. Expected: %d; Actual: %d.", expected, executions[0].Fee)
+	}
+}
+
+func TestExchange_TradePartiallyFills(t *testing.T) {
+	e := newTestExchange(map[string]qryptos.Amount{"VZT": qryptos.Amount(2000000000)})
+	ctx := context.Background()
+
+	orderId, err := e.CreateOrderContext(ctx, limitOrder(qryptos.OrderSideSell, qryptos.Amount(1000000000), qryptos.Amount(10500)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	// A taker sell doesn't touch our sell order
+	e.ApplyTrade(&qryptos.Trade{ProductID: 56, Price: qryptos.Amount(10600), Quantity: qryptos.Amount(400000000), TakerSide: qryptos.OrderSideSell})
+	if order := fetchOrder(t, e, orderId); order.FilledQuantity != 0 {
+		t.Errorf("Unexpected fill from a taker sell: %+v", order)
+	}
+
+	e.ApplyTrade(&qryptos.Trade{ProductID: 56, Price: qryptos.Amount(10600), Quantity: qryptos.Amount(400000000), TakerSide: qryptos.OrderSideBuy})
+
+	order := fetchOrder(t, e, orderId)
+	if order.Status != qryptos.OrderStatusLive {
+		t.Errorf("Expected the order to stay live: %+v", order)
+	}
+	if expected := qryptos.Amount(400000000); order.FilledQuantity != expected {
+		t.Errorf("Unexpected filled quantity. Expected: %d; Actual: %d.", expected, order.FilledQuantity)
+	}
+	if expected := qryptos.Amount(41958); balanceOf(t, e, "BTC") != expected {
+		t.Errorf("Unexpected BTC balance. Expected: %d; Actual: %d.", expected, balanceOf(t, e, "BTC"))
+	}
+}
+
+func TestExchange_EditAndCancel(t *testing.T) {
+	e := newTestExchange(map[string]qryptos.Amount{"BTC": qryptos.Amount(1000000)})
+	ctx := context.Background()
+
+	orderId, err := e.CreateOrderContext(ctx, limitOrder(qryptos.OrderSideBuy, qryptos.Amount(5000000000), qryptos.Amount(10000)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	// The order's own reservation counts towards an edit
+	if err := e.EditOrderContext(ctx, orderId, qryptos.Amount(9000000000), qryptos.Amount(10000)); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if err := e.EditOrderContext(ctx, orderId, qryptos.Amount(11000000000), qryptos.Amount(10000)); !qryptos.IsInsufficientFunds(err) {
+		t.Errorf("Expected an insufficient funds error; Actual: %v.", err)
+	}
+	if order := fetchOrder(t, e, orderId); order.Quantity != qryptos.Amount(9000000000) {
+		t.Errorf("Unexpected quantity after edit: %d", order.Quantity)
+	}
+
+	if err := e.CancelOrderContext(ctx, orderId); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if order := fetchOrder(t, e, orderId); order.Status != qryptos.OrderStatusCancelled {
+		t.Errorf("Expected a cancelled order: %+v", order)
+	}
+	if err := e.CancelOrderContext(ctx, 999); !qryptos.IsOrderNotFound(err) {
+		t.Errorf("Expected an order not found error; Actual: %v.", err)
+	}
+
+	// The cancelled order no longer holds any balance
+	if _, err := e.CreateOrderContext(ctx, limitOrder(qryptos.OrderSideBuy, qryptos.Amount(9000000000), qryptos.Amount(10000))); err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+}
+
+func TestRandomWalk(t *testing.T) {
+	start := Quote{ProductID: 56, Bid: qryptos.Amount(10000), Ask: qryptos.Amount(10400)}
+
+	quotes := RandomWalk(rand.New(rand.NewSource(1)), start, qryptos.Amount(1), 0.01, 100)
+	if len(quotes) != 100 {
+		t.Fatalf("Unexpected quote count. Expected: 100; Actual: %d.", len(quotes))
+	}
+	for _, q := range quotes {
+		if q.Ask-q.Bid != start.Ask-start.Bid || q.Bid <= 0 {
+			t.Fatalf("Unexpected quote: %+v", q)
+		}
+	}
+
+	again := RandomWalk(rand.New(rand.NewSource(1)), start, qryptos.Amount(1), 0.01, 100)
+	if again[99] != quotes[99] {
+		t.Error("Expected the same seed to produce the same feed.")
+	}
+}
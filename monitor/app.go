@@ -1,8 +1,10 @@
 package main
 
 import (
+	"os"
 	"time"
 	"github.com/tobyjsullivan/shifty/qryptos"
+	"github.com/tobyjsullivan/shifty/qryptos/valuation"
 	"fmt"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/aws/aws-sdk-go/aws"
@@ -12,6 +14,14 @@ import (
 
 const (
 	loopDelay = 30 * time.Second
+
+	// Account equity is reported in this currency
+	equityCurrency = "BTC"
+)
+
+var (
+	apiTokenId   = os.Getenv("QRYPTOS_API_TOKEN_ID")
+	apiSecretKey = os.Getenv("QRYPTOS_API_SECRET_KEY")
 )
 
 func main() {
@@ -19,27 +29,76 @@ func main() {
 	sess := session.Must(session.NewSession(aws.NewConfig().WithCredentials(credentials.NewEnvCredentials())))
 	cw := cloudwatch.New(sess, aws.NewConfig().WithRegion("us-east-1"))
 
+	var private *qryptos.PrivateClient
+	if apiTokenId != "" && apiSecretKey != "" {
+		private = qryptos.NewPrivateClient(apiTokenId, apiSecretKey)
+	} else {
+		fmt.Println("INFO [main] API keys not configured. Equity will not be reported.")
+	}
+
 	productBuffer := make(chan *qryptos.ProductDetails, 40)
 	go metricsLoop(cw, productBuffer)
 
 	ticker := time.NewTicker(loopDelay)
 	for range ticker.C {
-		fetchProducts(productBuffer)
+		products := fetchProducts(productBuffer)
+		if private != nil && products != nil {
+			go reportEquity(cw, private, products)
+		}
 	}
 }
 
-func fetchProducts(productBuffer chan *qryptos.ProductDetails) {
+func fetchProducts(productBuffer chan *qryptos.ProductDetails) []*qryptos.ProductDetails {
 	fmt.Println("DEBUG [fetchProducts] Fetching products...")
 	products, err := qryptos.DefaultClient().FetchProducts()
 	if err != nil {
 		fmt.Println("ERROR [fetchProducts] Error fetching products:", err.Error())
-		return
+		return nil
 	}
 
 	fmt.Println("DEBUG [fetchProducts] Product details received.")
 	for _, product := range products {
 		productBuffer <- product
 	}
+
+	return products
+}
+
+// reportEquity values every balance in the account at the market bid and reports the total.
+func reportEquity(cw *cloudwatch.CloudWatch, private *qryptos.PrivateClient, products []*qryptos.ProductDetails) {
+	balances, err := private.FetchAccountBalances()
+	if err != nil {
+		fmt.Println("ERROR [reportEquity] Error fetching balances:", err.Error())
+		return
+	}
+
+	equity, err := valuation.NewGraph(products).Value(balances, equityCurrency, valuation.Bid)
+	if err != nil {
+		fmt.Println("ERROR [reportEquity] Error valuing balances:", err.Error())
+		return
+	}
+	if len(equity.Unpriced) > 0 {
+		fmt.Println("DEBUG [reportEquity] Left out unpriced currencies:", equity.Unpriced)
+	}
+
+	_, err = cw.PutMetricData(&cloudwatch.PutMetricDataInput{
+		Namespace: aws.String("Shifty/Account"),
+		MetricData: []*cloudwatch.MetricDatum{{
+			Dimensions: []*cloudwatch.Dimension{
+				{
+					Name:  aws.String("Currency"),
+					Value: aws.String(equityCurrency),
+				},
+			},
+			MetricName: aws.String("Equity"),
+			Value:      aws.Float64(equity.Total.Amount.ToDecimal()),
+		}},
+	})
+	if err != nil {
+		fmt.Println("ERROR [reportEquity] Error during PutMetricData:", err.Error())
+		return
+	}
+	fmt.Println("DEBUG [reportEquity] Equity reported:", equity.Total)
 }
 
 func metricsLoop(cw *cloudwatch.CloudWatch, productBuffer chan *qryptos.ProductDetails) {
@@ -1,12 +1,15 @@
 package main
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	"github.com/tobyjsullivan/shifty/exchange"
+	"github.com/tobyjsullivan/shifty/exchange/paper"
 	"github.com/tobyjsullivan/shifty/qryptos"
+	"github.com/tobyjsullivan/shifty/qryptos/candles"
 	"os"
-	"time"
 	"strconv"
+	"time"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -16,6 +19,15 @@ import (
 const (
 	capitalAmount = qryptos.Amount(1000000)
 	loopDelay     = 20 * time.Second
+
+	orderBookDepth = 20
+
+	// Market prices come from the order book each tick, so the product list only needs an occasional refresh
+	productCacheTTL = 10 * time.Minute
+
+	// Trend and volatility are measured over the last hour of trades in five minute candles
+	signalWindow   = time.Hour
+	signalInterval = candles.FiveMinutes
 )
 
 var (
@@ -23,18 +35,47 @@ var (
 	apiSecretKey  = os.Getenv("QRYPTOS_API_SECRET_KEY")
 	baseCurrency  = os.Getenv("POSITION_BASE_CURRENCY")
 	quoteCurrency = os.Getenv("POSITION_QUOTE_CURRENCY")
-	minimumSplit = 1.01
 
-	client = qryptos.NewPrivateClient(apiTokenId, apiSecretKey)
+	// Profit to aim for on each position once fees are paid, as a fraction: Amount(500000) is 0.5%
+	targetMargin = qryptos.Amount(500000)
+
+	// How far the price may have fallen over the signal window before we stop placing buy orders: 0.02 is 2%
+	maxDownTrend = 0.02
+
+	paperTrading = os.Getenv("PAPER_TRADING") == "true"
+
+	// Persisting nonces keeps a restart on a host with a lagging clock from being rejected
+	nonceFile = os.Getenv("QRYPTOS_NONCE_FILE")
+
+	// Replaced by a paper exchange when paper trading
+	venue exchange.Exchange = exchange.NewQryptos(qryptos.DefaultClient(), qryptos.NewPrivateClient(apiTokenId, apiSecretKey))
+
+	// Allow for clock skew between this host and the exchange
+	startedAt = time.Now().Add(-time.Minute)
 )
 
 func init() {
-	minSplitVar := os.Getenv("MIN_SPLIT")
-	if minSplitVar != "" {
+	// MIN_SPLIT was a multiple of the opening price before fees. There's no safe translation to a margin after fees,
+	// so refuse to start rather than quietly trade at the default margin.
+	if os.Getenv("MIN_SPLIT") != "" {
+		panic("MIN_SPLIT is no longer supported. Set TARGET_MARGIN to the profit wanted after fees instead, e.g. 0.005 for 0.5%.")
+	}
+
+	marginVar := os.Getenv("TARGET_MARGIN")
+	if marginVar != "" {
+		var err error
+		targetMargin, err = qryptos.ParseAmount(marginVar)
+		if err != nil {
+			panic("Error parsing TARGET_MARGIN: "+err.Error())
+		}
+	}
+
+	trendVar := os.Getenv("MAX_DOWN_TREND")
+	if trendVar != "" {
 		var err error
-		minimumSplit, err = strconv.ParseFloat(minSplitVar, 64)
+		maxDownTrend, err = strconv.ParseFloat(trendVar, 64)
 		if err != nil {
-			panic("Error parsing MIN_SPLIT: "+err.Error())
+			panic("Error parsing MAX_DOWN_TREND: "+err.Error())
 		}
 	}
 }
@@ -42,6 +83,25 @@ func init() {
 func main() {
 	fmt.Println("[main] Running with token ID:", apiTokenId)
 
+	if nonceFile != "" {
+		nonces, err := qryptos.NewFileNonceSource(nonceFile)
+		if err != nil {
+			panic("Error loading nonce file: " + err.Error())
+		}
+		qryptos.SetSharedNonceSource(apiTokenId, nonces)
+	}
+
+	if paperTrading {
+		fmt.Println("INFO [main] Paper trading enabled. No real orders will be placed.")
+		products, err := qryptos.DefaultClient().FetchProducts()
+		if err != nil {
+			panic("Error fetching products for paper trading: " + err.Error())
+		}
+		sim := paper.New(products, map[string]qryptos.Amount{quoteCurrency: capitalAmount})
+		go sim.Follow(context.Background(), qryptos.DefaultClient(), loopDelay)
+		venue = sim
+	}
+
 	productUpdates := make(chan *qryptos.ProductDetails)
 
 	if os.Getenv("AWS_ACCESS_KEY_ID") != "" && os.Getenv("AWS_SECRET_ACCESS_KEY") != "" {
@@ -53,7 +113,7 @@ func main() {
 		fmt.Println("INFO [main] AWS keys not configured.")
 	}
 
-	runBudget(productUpdates)
+	runBudget(venue, productUpdates)
 }
 
 func reportMarketMetrics(cw *cloudwatch.CloudWatch, productUpdates chan *qryptos.ProductDetails) {
@@ -93,20 +153,3 @@ func buildMetricDatum(orderBook, metricName string, value float64) (*cloudwatch.
 			Value: aws.Float64(value),
 	}
 }
-
-func getProductDetails() (*qryptos.ProductDetails, error) {
-	// Get currency details
-	allProducts, err := qryptos.DefaultClient().FetchProducts()
-	if err != nil {
-		fmt.Println("[getProductDetails] Error fetching products:", err.Error())
-		return nil, err
-	}
-
-	for _, product := range allProducts {
-		if product.BaseCurrency == baseCurrency && product.QuotedCurrency == quoteCurrency {
-			return product, nil
-		}
-	}
-
-	return nil, errors.New("product details not found")
-}
@@ -1,18 +1,28 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"github.com/tobyjsullivan/shifty/exchange"
 	"github.com/tobyjsullivan/shifty/qryptos"
+	"github.com/tobyjsullivan/shifty/qryptos/candles"
+	"github.com/tobyjsullivan/shifty/qryptos/tracker"
 	"time"
 )
 
-
-type context struct {
+// tickContext is everything known about the market and our orders at a single tick.
+type tickContext struct {
+	exchange       exchange.Exchange
 	productDetails *qryptos.ProductDetails
+	orderBook      *qryptos.OrderBook
 	orders         []*qryptos.OrderDetails
+
+	// Signals from the recent trade tape
+	volatility float64
+	trend      float64
 }
 
-func (ctx *context) findOrder(orderId int) *qryptos.OrderDetails {
+func (ctx *tickContext) findOrder(orderId int) *qryptos.OrderDetails {
 	for _, order := range ctx.orders {
 		if order.ID == orderId {
 			return order
@@ -24,22 +34,26 @@ func (ctx *context) findOrder(orderId int) *qryptos.OrderDetails {
 
 type position struct {
 	openingExecutionId int
-	openingPrice       qryptos.Amount
-	quantity           qryptos.Amount
+	openingPrice       qryptos.Price
+	quantity           qryptos.Money
 	closingOrderId     int
 	closed             bool
 }
 
-func runBudget(productUpdates chan *qryptos.ProductDetails) {
+func runBudget(ex exchange.Exchange, productUpdates chan *qryptos.ProductDetails) {
 	fmt.Println("INFO [runBudget] Starting run...")
 	var buyOrderIds []int
 	var openedPositions []*position
+	orderTracker := tracker.New()
+	products := qryptos.NewCatalog(ex, productCacheTTL)
 
 	ticker := time.NewTicker(loopDelay)
 	for range ticker.C {
-		fmt.Println("DEBUG [runBudget] Tick.")
+		if reporter, ok := ex.(exchange.StatsReporter); ok {
+			fmt.Printf("DEBUG [runBudget] Tick. Client stats: %+v\n", reporter.Stats())
+		}
 		// Load up the current context
-		ctx, err := fetchContext()
+		ctx, err := fetchContext(ex, products)
 		if err != nil {
 			fmt.Println("ERROR [runBudget]", "error in fetchContext:", err.Error())
 			continue
@@ -52,31 +66,22 @@ func runBudget(productUpdates chan *qryptos.ProductDetails) {
 			fmt.Println("DEBUG [runBudget] productUpdates buffer is full.")
 		}
 
-		markPositionsClosed(ctx, openedPositions)
+		// Every order placed since startup is in the snapshot, so anything missing from it really is gone
+		events := orderTracker.Update(ctx.orders)
+
+		markPositionsClosed(events, openedPositions)
 
 		// Check for and record any new open position
-		checkForNewPositions(ctx, &openedPositions, buyOrderIds)
+		checkForNewPositions(ctx, events, &openedPositions)
 
 		// Compute remaining budget
-		remainingBudget := capitalAmount
-		for _, position := range openedPositions {
-			if position.closed {
-				continue
-			}
-
-			remainingBudget -= position.quantity.Multiply(position.openingPrice)
-
-			if position.closingOrderId == 0 {
-				continue
-			}
-
-			// Any filled quantity can be available in budget
-			closingOrder := ctx.findOrder(position.closingOrderId)
-			if closingOrder != nil {
-				remainingBudget += closingOrder.FilledQuantity.Multiply(position.openingPrice)
-			}
+		remainingBudget, err := computeRemainingBudget(ctx, openedPositions)
+		if err != nil {
+			fmt.Println("ERROR [runBudget]", "error computing remaining budget:", err.Error())
+			continue
 		}
 		fmt.Println("DEBUG [runBudget] Computed remaining budget:", remainingBudget)
+		fmt.Printf("DEBUG [runBudget] Volatility: %.04f; Trend: %.04f\n", ctx.volatility, ctx.trend)
 
 		// Update bid with remaining budget by editing order if possible or cancelling and creating a new order
 		go updateBuyOrder(ctx, remainingBudget, buyOrderIds)
@@ -86,43 +91,172 @@ func runBudget(productUpdates chan *qryptos.ProductDetails) {
 	}
 }
 
-func markPositionsClosed(ctx *context, openedPositions []*position) {
+// withBookQuotes returns a copy of the cached product details with the market bid and ask taken from the book.
+func withBookQuotes(details *qryptos.ProductDetails, book *qryptos.OrderBook) *qryptos.ProductDetails {
+	quoted := *details
+	if len(book.Bids) > 0 {
+		quoted.MarketBid = book.Bids[0].Price
+	}
+	if len(book.Asks) > 0 {
+		quoted.MarketAsk = book.Asks[0].Price
+	}
+
+	return &quoted
+}
+
+func computeRemainingBudget(ctx *tickContext, openedPositions []*position) (qryptos.Money, error) {
+	remainingBudget := qryptos.NewMoney(capitalAmount, quoteCurrency)
 	for _, position := range openedPositions {
+		if position.closed {
+			continue
+		}
+
+		// Round costs up and recovered funds down so the budget is never overstated
+		cost, err := position.openingPrice.Convert(position.quantity, qryptos.RoundCeil)
+		if err != nil {
+			return qryptos.Money{}, err
+		}
+		remainingBudget, err = remainingBudget.Sub(cost)
+		if err != nil {
+			return qryptos.Money{}, err
+		}
+
+		if position.closingOrderId == 0 {
+			continue
+		}
+
+		// Any filled quantity can be available in budget
 		closingOrder := ctx.findOrder(position.closingOrderId)
-		if closingOrder != nil && closingOrder.Status != qryptos.OrderStatusLive {
-			position.closed = true
-			fmt.Println("INFO [runBudget] Closed position. Order #", closingOrder.ID)
+		if closingOrder != nil {
+			recovered, err := position.openingPrice.Convert(closingOrder.Filled(), qryptos.RoundFloor)
+			if err != nil {
+				return qryptos.Money{}, err
+			}
+			remainingBudget, err = remainingBudget.Add(recovered)
+			if err != nil {
+				return qryptos.Money{}, err
+			}
+		}
+	}
+
+	return remainingBudget, nil
+}
+
+// markPositionsClosed closes the positions whose closing order filled. A cancelled closing order, which is how the
+// exchange rejects a post-only sell that would take, leaves the unsold part of the position to be listed again.
+func markPositionsClosed(events []tracker.Event, openedPositions []*position) {
+	for _, ev := range events {
+		_, filled := ev.(*tracker.Filled)
+		_, cancelled := ev.(*tracker.Cancelled)
+		if !filled && !cancelled {
+			continue
+		}
+
+		order := ev.Order()
+		for _, position := range openedPositions {
+			if position.closingOrderId == 0 || position.closingOrderId != order.ID {
+				continue
+			}
+
+			if filled {
+				position.closed = true
+				fmt.Println("INFO [runBudget] Closed position. Order #", order.ID)
+				continue
+			}
+
+			remaining, err := position.quantity.Sub(qryptos.NewMoney(order.FilledQuantity, position.quantity.Currency))
+			if err != nil {
+				fmt.Println("ERROR [runBudget] Error reducing cancelled position:", err.Error())
+				continue
+			}
+			position.quantity = remaining
+			position.closingOrderId = 0
+			if remaining.Amount <= qryptos.AmountZero {
+				position.closed = true
+				fmt.Println("INFO [runBudget] Closed position. Order #", order.ID)
+				continue
+			}
+			fmt.Println("INFO [runBudget] Closing order cancelled. Order #", order.ID, "Remaining:", remaining)
 		}
 	}
 }
 
-func fetchContext() (*context, error) {
-	details, err := getProductDetails()
+func fetchContext(ex exchange.Exchange, products *qryptos.Catalog) (*tickContext, error) {
+	reqCtx, cancel := context.WithTimeout(context.Background(), loopDelay)
+	defer cancel()
+
+	details, err := products.ProductFor(reqCtx, baseCurrency, quoteCurrency)
+	if err != nil {
+		fmt.Println("ERROR [fetchContext] Error finding product:", err.Error())
+		return nil, err
+	}
+
+	// Every order we track was placed by this process, so older history can be skipped
+	orders, err := ex.FetchAllOrdersContext(reqCtx, &qryptos.OrdersQuery{
+		ProductID:    details.ProductID,
+		CreatedAfter: startedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	book, err := ex.FetchOrderBookContext(reqCtx, details.ProductID, orderBookDepth)
 	if err != nil {
 		return nil, err
 	}
 
-	orders, err := client.FetchOrders()
+	trades, err := ex.FetchExecutionsContext(reqCtx, details.ProductID, time.Now().Add(-signalWindow))
+	if err != nil {
+		return nil, err
+	}
+	bars, err := candles.Build(trades, signalInterval)
 	if err != nil {
 		return nil, err
 	}
 
-	return &context{
-		productDetails: details,
+	return &tickContext{
+		exchange:       ex,
+		productDetails: withBookQuotes(details, book),
+		orderBook:      book,
 		orders:         orders,
+		volatility:     candles.Volatility(bars),
+		trend:          candles.Trend(bars),
 	}, nil
 }
 
-func closePosition(ctx *context, minPrice, quantity qryptos.Amount) (int, error) {
+// minimumClosePrice is the lowest price that closes pos with targetMargin profit after fees. Buys are priced below
+// the ask and sells are post-only, so both trades pay the maker rate.
+func minimumClosePrice(product *qryptos.ProductDetails, pos *position) (qryptos.Amount, error) {
+	return product.Fees().MinimumSellPrice(pos.openingPrice.Amount, targetMargin, false, false)
+}
+
+func closePosition(ctx *tickContext, minPrice qryptos.Amount, quantity qryptos.Money) (int, error) {
 	fmt.Println("[closePosition]", "Creating sell order...")
 
-	productId := ctx.productDetails.ProductID
-	price := ctx.productDetails.MarketAsk
+	product := ctx.productDetails
+	price := product.MarketAsk
 	if price < minPrice {
 		price = minPrice
 	}
+	price = product.QuantizePrice(price, qryptos.OrderSideSell)
 
-	orderId, err := client.CreateLimitOrder(productId, qryptos.OrderSideSell, quantity, price)
+	if quantity.Currency != product.BaseCurrency {
+		return 0, &qryptos.CurrencyMismatchError{Op: "closePosition", Expected: product.BaseCurrency, Actual: quantity.Currency}
+	}
+	qty := product.QuantizeQuantity(quantity.Amount)
+	if err := product.CheckOrder(qty, price); err != nil {
+		return 0, err
+	}
+
+	// Post-only so that a stale market ask can never turn our sell into a taker order
+	orderId, err := ctx.exchange.CreateOrderContext(context.Background(), &qryptos.OrderRequest{
+		ProductID: product.ProductID,
+		Type:      qryptos.OrderTypeLimit,
+		Side:      qryptos.OrderSideSell,
+		Quantity:  qty,
+		Price:     price,
+		PostOnly:  true,
+	})
 	if err != nil {
 		return 0, err
 	}
@@ -132,15 +266,31 @@ func closePosition(ctx *context, minPrice, quantity qryptos.Amount) (int, error)
 	return orderId, nil
 }
 
-func updateBuyOrder(ctx *context, remainingBudget qryptos.Amount, buyOrderIds []int) {
+func updateBuyOrder(ctx *tickContext, remainingBudget qryptos.Money, buyOrderIds []int) {
 	fmt.Println("DEBUG [runBudget] Managing buy order(s)")
-	maxBid := ctx.productDetails.MarketAsk - qryptos.MinimalUnit
-	buyPrice := ctx.productDetails.MarketBid
+	// Don't keep buying into a falling market
+	if ctx.trend < -maxDownTrend {
+		fmt.Printf("INFO [runBudget] Not placing buy orders. Trend: %.04f\n", ctx.trend)
+		return
+	}
+	product := ctx.productDetails
+	maxBid := product.MarketAsk - product.Tick()
+	buyPrice := product.MarketBid
 	if buyPrice > maxBid {
 		buyPrice = maxBid
 	}
-	var buyQuantity qryptos.Amount
-	buyQuantity.FromDecimal(float64(remainingBudget) / float64(buyPrice))
+	buyPrice = product.QuantizePrice(buyPrice, qryptos.OrderSideBuy)
+	buyMoney, err := qryptos.NewPrice(buyPrice, product.BaseCurrency, product.QuotedCurrency).
+		ConvertToBase(remainingBudget, qryptos.RoundFloor)
+	if err != nil {
+		fmt.Println("ERROR [runBudget] Error computing buy quantity:", err.Error())
+		return
+	}
+	buyQuantity := product.QuantizeQuantity(buyMoney.Amount)
+	if err := product.CheckOrder(buyQuantity, buyPrice); err != nil {
+		fmt.Println("DEBUG [runBudget] Not placing buy order:", err.Error())
+		return
+	}
 	var editableBuyOrderFound bool
 	shouldUpdateOrder := true
 	for _, buyOrderId := range buyOrderIds {
@@ -150,24 +300,31 @@ func updateBuyOrder(ctx *context, remainingBudget qryptos.Amount, buyOrderIds []
 		}
 
 		// No need to update if we're already the best price
-		// TODO Determine if we're ahead of the pack and, if so, drop back
 		if buyOrder.Price >= ctx.productDetails.MarketBid {
 			fmt.Println("DEBUG [runBudget] Current buy order is at market bid.", buyOrderId)
 			shouldUpdateOrder = false
+
+			// If we're ahead of the pack, drop back to just above the next best bid
+			if dropTo, ok := dropBackPrice(ctx, buyOrder); ok && buyOrder.CanEdit() {
+				fmt.Println("INFO [runBudget] Dropping buy order back.", buyOrderId, "New price:", dropTo)
+				if err := ctx.exchange.EditOrderContext(context.Background(), buyOrder.ID, buyOrder.Quantity, dropTo); err != nil {
+					fmt.Println("ERROR [runBudget] Error while dropping back buy order:", err.Error())
+				}
+			}
 			return
 		}
 
 		if buyOrder.CanEdit() {
 			fmt.Println("INFO [runBudget] Editing buy order.", buyOrderId, "Current market bid:", ctx.productDetails.MarketBid)
 			editableBuyOrderFound = true
-			err := client.EditOrder(buyOrder.ID, buyQuantity, buyPrice)
+			err := ctx.exchange.EditOrderContext(context.Background(), buyOrder.ID, buyQuantity, buyPrice)
 			if err != nil {
 				fmt.Println("ERROR [runBudget] Error while editing order:", err.Error())
 				return
 			}
 		} else {
 			fmt.Println("DEBUG [runBudget] Cancelling current buy order.")
-			err := client.CancelOrder(buyOrder.ID)
+			err := ctx.exchange.CancelOrderContext(context.Background(), buyOrder.ID)
 			if err != nil {
 				fmt.Println("ERROR [runBudget] Error while cancelling order:", err.Error())
 				return
@@ -175,9 +332,15 @@ func updateBuyOrder(ctx *context, remainingBudget qryptos.Amount, buyOrderIds []
 		}
 	}
 	// Create a new buy order if none was found to edit (and there's budget)
-	if shouldUpdateOrder && !editableBuyOrderFound && remainingBudget > 0.0 {
+	if shouldUpdateOrder && !editableBuyOrderFound && remainingBudget.Amount > 0.0 {
 		fmt.Println("INFO [runBudget] Creating new order")
-		orderId, err := client.CreateLimitOrder(ctx.productDetails.ProductID, qryptos.OrderSideBuy, buyQuantity, buyPrice)
+		orderId, err := ctx.exchange.CreateOrderContext(context.Background(), &qryptos.OrderRequest{
+			ProductID: ctx.productDetails.ProductID,
+			Type:      qryptos.OrderTypeLimit,
+			Side:      qryptos.OrderSideBuy,
+			Quantity:  buyQuantity,
+			Price:     buyPrice,
+		})
 		if err != nil {
 			fmt.Println("ERROR [runBudget] Error while creating buy order:", err.Error())
 			return
@@ -187,7 +350,33 @@ func updateBuyOrder(ctx *context, remainingBudget qryptos.Amount, buyOrderIds []
 	}
 }
 
-func updateSellOrders(ctx *context, openedPositions []*position) {
+// dropBackPrice reports a lower price for a buy order that sits alone at the top of the book with a gap beneath it.
+func dropBackPrice(ctx *tickContext, buyOrder *qryptos.OrderDetails) (qryptos.Amount, bool) {
+	if ctx.orderBook == nil {
+		return qryptos.AmountZero, false
+	}
+
+	remaining := buyOrder.Quantity - buyOrder.FilledQuantity
+	if ahead := ctx.orderBook.QueueAhead(qryptos.OrderSideBuy, buyOrder.Price) - remaining; ahead > 0 {
+		return qryptos.AmountZero, false
+	}
+
+	for _, level := range ctx.orderBook.Bids {
+		if level.Price >= buyOrder.Price {
+			continue
+		}
+
+		dropTo := ctx.productDetails.QuantizePrice(level.Price+ctx.productDetails.Tick(), qryptos.OrderSideBuy)
+		if dropTo >= buyOrder.Price {
+			return qryptos.AmountZero, false
+		}
+		return dropTo, true
+	}
+
+	return qryptos.AmountZero, false
+}
+
+func updateSellOrders(ctx *tickContext, openedPositions []*position) {
 	fmt.Println("DEBUG [runBudget] Managing sell orders")
 	for i, pos := range openedPositions {
 		if pos.closed {
@@ -213,20 +402,26 @@ func updateSellOrders(ctx *context, openedPositions []*position) {
 						continue
 					}
 				}
-				if current.openingPrice == pos.openingPrice {
+				if current.openingPrice.Amount == pos.openingPrice.Amount {
 					mergeCandidate = current
 					break
 				}
 			}
 			if mergeCandidate != nil {
 				// Add this positions quantity to the mergeCandidate
-				mergeCandidate.quantity += pos.quantity
-				pos.quantity = 0
+				merged, err := mergeCandidate.quantity.Add(pos.quantity)
+				if err != nil {
+					fmt.Println("ERROR [runBudget] Error merging positions:", err.Error())
+					continue
+				}
+				mergeCandidate.quantity = merged
+				pos.quantity = qryptos.NewMoney(qryptos.AmountZero, pos.quantity.Currency)
 
 				// Edit the quantity on the mergeCandidates order
 				if mergeCandidate.closingOrderId != 0 {
 					sellOrder := ctx.findOrder(mergeCandidate.closingOrderId)
-					err := client.EditOrder(mergeCandidate.closingOrderId, mergeCandidate.quantity, sellOrder.Price)
+					qty := ctx.productDetails.QuantizeQuantity(mergeCandidate.quantity.Amount)
+					err := ctx.exchange.EditOrderContext(context.Background(), mergeCandidate.closingOrderId, qty, sellOrder.Price)
 					if err != nil {
 						fmt.Println("ERROR [runBudget] Error editing order after position merge:", err.Error())
 						continue
@@ -237,7 +432,11 @@ func updateSellOrders(ctx *context, openedPositions []*position) {
 				continue
 			}
 
-			minPrice := qryptos.Amount(float64(pos.openingPrice) * minimumSplit)
+			minPrice, err := minimumClosePrice(ctx.productDetails, pos)
+			if err != nil {
+				fmt.Println("ERROR [runBudget] Error computing minimum close price:", err.Error())
+				continue
+			}
 			closingId, err := closePosition(ctx, minPrice, pos.quantity)
 			if err != nil {
 				fmt.Println("ERROR [runBudget] Error closing position:", err.Error())
@@ -249,7 +448,11 @@ func updateSellOrders(ctx *context, openedPositions []*position) {
 
 
 		mktAsk := ctx.productDetails.MarketAsk
-		minAsk := qryptos.Amount(float64(pos.openingPrice) * minimumSplit)
+		minAsk, err := minimumClosePrice(ctx.productDetails, pos)
+		if err != nil {
+			fmt.Println("ERROR [runBudget] Error computing minimum close price:", err.Error())
+			continue
+		}
 		if mktAsk < minAsk {
 			fmt.Println("DEBUG [runBudget] Current market ask is below minimum ask for sell order.", sellOrderId)
 		} else {
@@ -271,12 +474,16 @@ func updateSellOrders(ctx *context, openedPositions []*position) {
 			if price < minAsk {
 				price = minAsk
 			}
+			price = ctx.productDetails.QuantizePrice(price, qryptos.OrderSideSell)
+			if price >= sellOrder.Price {
+				continue
+			}
 			fmt.Println(fmt.Sprintf(
-				"INFO [runBudget] Sell price is %.08f but current market ask is %.08f so editing order",
+				"INFO [runBudget] Sell price is %s but current market ask is %s so editing order",
 				sellOrder.Price,
 				mktAsk,
 			))
-			if err := client.EditOrder(sellOrderId, sellOrder.Quantity, price); err != nil {
+			if err := ctx.exchange.EditOrderContext(context.Background(), sellOrderId, sellOrder.Quantity, price); err != nil {
 				fmt.Println("ERROR [runBudget] Error editing sell order:", err.Error())
 				continue
 			}
@@ -284,32 +491,21 @@ func updateSellOrders(ctx *context, openedPositions []*position) {
 	}
 }
 
-func checkForNewPositions(ctx *context, openedPositions *[]*position, buyOrderIds []int) {
-	priorExecutionIds := make(map[int]bool)
-	for _, position := range *openedPositions {
-		if position.closed {
+// checkForNewPositions opens a position for every new execution of a buy order. The tracker reports each execution
+// once, even after the position it opened has been closed.
+func checkForNewPositions(ctx *tickContext, events []tracker.Event, openedPositions *[]*position) {
+	for _, ev := range events {
+		if ev.Order().Side != qryptos.OrderSideBuy {
 			continue
 		}
 
-		fmt.Println("DEBUG [runBudget] Found execution in opened positions.", position.openingExecutionId)
-		priorExecutionIds[position.openingExecutionId] = true
-	}
-	for _, buyOrderId := range buyOrderIds {
-		buyOrder := ctx.findOrder(buyOrderId)
-		if buyOrder == nil {
-			fmt.Println("DEBUG [runBudget] Could not find buyOrder.", buyOrderId)
-			continue
-		}
-
-		for _, execution := range buyOrder.Executions {
-			if !priorExecutionIds[execution.ID] {
-				fmt.Println("INFO [runBudget] Detected new opened position from execution.", execution.ID)
-				*openedPositions = append(*openedPositions, &position{
-					openingExecutionId: execution.ID,
-					openingPrice: execution.Price,
-					quantity: execution.Quantity,
-				})
-			}
+		for _, execution := range tracker.NewExecutions(ev) {
+			fmt.Println("INFO [runBudget] Detected new opened position from execution.", execution.ID)
+			*openedPositions = append(*openedPositions, &position{
+				openingExecutionId: execution.ID,
+				openingPrice: qryptos.NewPrice(execution.Price, ctx.productDetails.BaseCurrency, ctx.productDetails.QuotedCurrency),
+				quantity: qryptos.NewMoney(execution.Quantity, ctx.productDetails.BaseCurrency),
+			})
 		}
 	}
 }
@@ -1,3 +1,310 @@
 package main
 
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
 
+	"github.com/tobyjsullivan/shifty/exchange"
+	"github.com/tobyjsullivan/shifty/qryptos"
+	"github.com/tobyjsullivan/shifty/qryptos/qryptostest"
+	"github.com/tobyjsullivan/shifty/qryptos/tracker"
+)
+
+// fakeExchange serves fixed market data and records every order change the strategy makes.
+type fakeExchange struct {
+	products []*qryptos.ProductDetails
+	book     *qryptos.OrderBook
+	orders   []*qryptos.OrderDetails
+	trades   []*qryptos.Trade
+
+	mu      sync.Mutex
+	created []*qryptos.OrderRequest
+	edited  []int
+	nextId  int
+}
+
+func (f *fakeExchange) FetchProductsContext(ctx context.Context) ([]*qryptos.ProductDetails, error) {
+	return f.products, nil
+}
+
+func (f *fakeExchange) FetchOrderBookContext(ctx context.Context, productId, depth int) (*qryptos.OrderBook, error) {
+	return f.book, nil
+}
+
+func (f *fakeExchange) FetchExecutionsContext(ctx context.Context, productId int, since time.Time) ([]*qryptos.Trade, error) {
+	return f.trades, nil
+}
+
+func (f *fakeExchange) FetchAllOrdersContext(ctx context.Context, q *qryptos.OrdersQuery) ([]*qryptos.OrderDetails, error) {
+	return f.orders, nil
+}
+
+func (f *fakeExchange) CreateOrderContext(ctx context.Context, order *qryptos.OrderRequest) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nextId++
+	f.created = append(f.created, order)
+	return f.nextId, nil
+}
+
+func (f *fakeExchange) EditOrderContext(ctx context.Context, orderId int, quantity, price qryptos.Amount) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.edited = append(f.edited, orderId)
+	return nil
+}
+
+func (f *fakeExchange) CancelOrderContext(ctx context.Context, orderId int) error {
+	return nil
+}
+
+func (f *fakeExchange) FetchAccountBalancesContext(ctx context.Context) ([]*qryptos.AccountBalance, error) {
+	return []*qryptos.AccountBalance{}, nil
+}
+
+func testProduct() *qryptos.ProductDetails {
+	return &qryptos.ProductDetails{
+		ProductID:        56,
+		Currency:         "BTC",
+		BaseCurrency:     "VZT",
+		QuotedCurrency:   "BTC",
+		CurrencyPairCode: "VZTBTC",
+		MarketAsk:        qryptos.Amount(10400),
+		MarketBid:        qryptos.Amount(10000),
+		PriceTick:        qryptos.Amount(1),
+		QuantityStep:     qryptos.Amount(1000000),
+		MinimumQuantity:  qryptos.Amount(100000000),
+	}
+}
+
+func TestFetchContext(t *testing.T) {
+	baseCurrency, quoteCurrency = "VZT", "BTC"
+
+	other := testProduct()
+	other.ProductID = 57
+	other.BaseCurrency = "ETH"
+	ex := &fakeExchange{
+		products: []*qryptos.ProductDetails{other, testProduct()},
+		book:     &qryptos.OrderBook{ProductID: 56},
+		orders:   []*qryptos.OrderDetails{{ID: 7}},
+		trades: []*qryptos.Trade{
+			{ID: 1, ProductID: 56, Price: qryptos.Amount(10000), Quantity: qryptos.AmountRatio, CreatedAt: time.Now().Add(-30 * time.Minute)},
+			{ID: 2, ProductID: 56, Price: qryptos.Amount(9000), Quantity: qryptos.AmountRatio, CreatedAt: time.Now()},
+		},
+	}
+
+	ctx, err := fetchContext(ex, qryptos.NewCatalog(ex, time.Minute))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	if expected := 56; ctx.productDetails.ProductID != expected {
+		t.Errorf("Unexpected product. Expected: %d; Actual: %d.", expected, ctx.productDetails.ProductID)
+	}
+	if ctx.findOrder(7) == nil {
+		t.Error("Expected to find order 7.")
+	}
+	// The price fell 10% over the trades
+	if ctx.trend > -0.09 || ctx.trend < -0.11 {
+		t.Errorf("Unexpected trend: %f", ctx.trend)
+	}
+}
+
+func TestUpdateBuyOrder_CreatesOrderAtMarketBid(t *testing.T) {
+	ex := &fakeExchange{}
+	ctx := &tickContext{exchange: ex, productDetails: testProduct()}
+
+	updateBuyOrder(ctx, qryptos.NewMoney(capitalAmount, "BTC"), nil)
+
+	if len(ex.created) != 1 {
+		t.Fatalf("Unexpected order count. Expected: 1; Actual: %d.", len(ex.created))
+	}
+	order := ex.created[0]
+	if order.Side != qryptos.OrderSideBuy || order.Type != qryptos.OrderTypeLimit {
+		t.Errorf("Unexpected order: %+v", order)
+	}
+	if expected := qryptos.Amount(10000); order.Price != expected {
+		t.Errorf("Unexpected price. Expected: %d; Actual: %d.", expected, order.Price)
+	}
+	// 0.01 BTC buys 100 VZT at 0.0001
+	if expected := qryptos.Amount(10000000000); order.Quantity != expected {
+		t.Errorf("Unexpected quantity. Expected: %d; Actual: %d.", expected, order.Quantity)
+	}
+}
+
+func TestUpdateBuyOrder_HoldsOffInFallingMarket(t *testing.T) {
+	ex := &fakeExchange{}
+	ctx := &tickContext{exchange: ex, productDetails: testProduct(), trend: -0.05}
+
+	updateBuyOrder(ctx, qryptos.NewMoney(capitalAmount, "BTC"), nil)
+
+	if len(ex.created) != 0 {
+		t.Errorf("Unexpected orders: %+v", ex.created)
+	}
+}
+
+func TestClosePosition_RespectsMinimumPrice(t *testing.T) {
+	ex := &fakeExchange{}
+	ctx := &tickContext{exchange: ex, productDetails: testProduct()}
+
+	minPrice := qryptos.Amount(10500)
+	if _, err := closePosition(ctx, minPrice, qryptos.NewMoney(qryptos.Amount(250000000), "VZT")); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	if len(ex.created) != 1 {
+		t.Fatalf("Unexpected order count. Expected: 1; Actual: %d.", len(ex.created))
+	}
+	order := ex.created[0]
+	if order.Side != qryptos.OrderSideSell || !order.PostOnly {
+		t.Errorf("Expected a post-only sell: %+v", order)
+	}
+	if order.Price != minPrice {
+		t.Errorf("Unexpected price. Expected: %d; Actual: %d.", minPrice, order.Price)
+	}
+}
+
+func TestClosePosition_WrongCurrency(t *testing.T) {
+	ex := &fakeExchange{}
+	ctx := &tickContext{exchange: ex, productDetails: testProduct()}
+
+	if _, err := closePosition(ctx, qryptos.Amount(10500), qryptos.NewMoney(qryptos.Amount(250000000), "ETH")); err == nil {
+		t.Error("Expected a currency mismatch error.")
+	}
+	if len(ex.created) != 0 {
+		t.Errorf("Unexpected order count. Expected: 0; Actual: %d.", len(ex.created))
+	}
+}
+
+func TestUpdateBuyOrder_AgainstServer(t *testing.T) {
+	baseCurrency, quoteCurrency = "VZT", "BTC"
+
+	s := qryptostest.NewServer([]*qryptos.ProductDetails{testProduct()}, map[string]qryptos.Amount{"BTC": capitalAmount})
+	defer s.Close()
+	opts := []qryptos.ClientOption{qryptos.WithBaseURL(s.URL), qryptos.WithRateLimiter(nil)}
+	ex := exchange.NewQryptos(
+		qryptos.NewPublicClient(opts...),
+		qryptos.NewPrivateClient(qryptostest.DefaultTokenID, qryptostest.DefaultSecret, opts...),
+	)
+
+	products := qryptos.NewCatalog(ex, time.Minute)
+
+	ctx, err := fetchContext(ex, products)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	updateBuyOrder(ctx, qryptos.NewMoney(capitalAmount, "BTC"), nil)
+
+	// The next tick sees the order the last one placed
+	ctx, err = fetchContext(ex, products)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if len(ctx.orders) != 1 {
+		t.Fatalf("Unexpected order count. Expected: 1; Actual: %d.", len(ctx.orders))
+	}
+	order := ctx.orders[0]
+	if order.Side != qryptos.OrderSideBuy || order.Status != qryptos.OrderStatusLive {
+		t.Errorf("Expected a live buy: %+v", order)
+	}
+	if expected := qryptos.Amount(10000000000); order.Quantity != expected {
+		t.Errorf("Unexpected quantity. Expected: %d; Actual: %d.", expected, order.Quantity)
+	}
+	if len(ctx.orderBook.Bids) == 0 || ctx.orderBook.Bids[0].Price != qryptos.Amount(10000) {
+		t.Errorf("Expected our bid in the book: %+v", ctx.orderBook.Bids)
+	}
+	if len(ctx.orderBook.Bids) > 0 && ctx.productDetails.MarketBid != ctx.orderBook.Bids[0].Price {
+		t.Errorf("Unexpected market bid. Expected: %d; Actual: %d.", ctx.orderBook.Bids[0].Price, ctx.productDetails.MarketBid)
+	}
+
+	// The product list is cached between ticks
+	var productRequests int
+	for _, req := range s.Requests() {
+		if req.Path == "/products" {
+			productRequests++
+		}
+	}
+	if productRequests != 1 {
+		t.Errorf("Unexpected product requests. Expected: 1; Actual: %d.", productRequests)
+	}
+}
+
+func TestPositions_FromTrackerEvents(t *testing.T) {
+	ctx := &tickContext{productDetails: testProduct()}
+	orderTracker := tracker.New()
+	var openedPositions []*position
+
+	tick := func(orders ...*qryptos.OrderDetails) {
+		events := orderTracker.Update(orders)
+		markPositionsClosed(events, openedPositions)
+		checkForNewPositions(ctx, events, &openedPositions)
+	}
+
+	buy := &qryptos.OrderDetails{ID: 1, Side: qryptos.OrderSideBuy, Status: qryptos.OrderStatusLive,
+		Executions: []*qryptos.ExecutionDetails{{ID: 10, Quantity: qryptos.Amount(100000000), Price: qryptos.Amount(10000)}}}
+	tick(buy)
+	if len(openedPositions) != 1 {
+		t.Fatalf("Unexpected position count. Expected: 1; Actual: %d.", len(openedPositions))
+	}
+	openedPositions[0].closingOrderId = 2
+
+	// Closing the position doesn't reopen it from the same execution
+	sell := &qryptos.OrderDetails{ID: 2, Side: qryptos.OrderSideSell, Status: qryptos.OrderStatusFilled}
+	tick(buy, sell)
+	if !openedPositions[0].closed {
+		t.Error("Expected the position to be closed.")
+	}
+	if len(openedPositions) != 1 {
+		t.Errorf("Unexpected position count. Expected: 1; Actual: %d.", len(openedPositions))
+	}
+}
+
+func TestPositions_CancelledCloseIsRelisted(t *testing.T) {
+	pos := &position{
+		openingPrice:   qryptos.NewPrice(qryptos.Amount(10000), "VZT", "BTC"),
+		quantity:       qryptos.NewMoney(qryptos.Amount(500000000), "VZT"),
+		closingOrderId: 2,
+	}
+	orderTracker := tracker.New()
+	sell := &qryptos.OrderDetails{ID: 2, Side: qryptos.OrderSideSell, Status: qryptos.OrderStatusLive, Quantity: qryptos.Amount(500000000)}
+	orderTracker.Update([]*qryptos.OrderDetails{sell})
+
+	cancelled := *sell
+	cancelled.Status = qryptos.OrderStatusCancelled
+	cancelled.FilledQuantity = qryptos.Amount(200000000)
+	markPositionsClosed(orderTracker.Update([]*qryptos.OrderDetails{&cancelled}), []*position{pos})
+
+	if pos.closed || pos.closingOrderId != 0 {
+		t.Errorf("Expected an open position without a closing order: %+v", pos)
+	}
+	if expected := qryptos.Amount(300000000); pos.quantity.Amount != expected {
+		t.Errorf("Unexpected quantity. Expected: %d; Actual: %d.", expected, pos.quantity.Amount)
+	}
+}
+
+func TestMinimumClosePrice_CoversFees(t *testing.T) {
+	product := testProduct()
+	product.MakerFee = qryptos.Amount(100000)
+	pos := &position{openingPrice: qryptos.NewPrice(qryptos.Amount(10000), "VZT", "BTC")}
+
+	minPrice, err := minimumClosePrice(product, pos)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	// 0.1% to buy, 0.1% to sell and the target margin on top, give or take the rounding
+	quantity := qryptos.Amount(100000000000)
+	cost, _ := product.Fees().BuyCost(quantity, pos.openingPrice.Amount, false)
+	proceeds, _ := product.Fees().SellProceeds(quantity, minPrice, false)
+	target, _ := cost.Multiply(qryptos.AmountRatio+targetMargin, qryptos.RoundCeil)
+	if proceeds < target {
+		t.Errorf("Closing at %s returns %s; Expected at least: %s.", minPrice, proceeds, target)
+	}
+	if minPrice > qryptos.Amount(10072) {
+		t.Errorf("Unexpected minimum close price. Expected at most: %s; Actual: %s.", qryptos.Amount(10072), minPrice)
+	}
+}
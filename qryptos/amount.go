@@ -1,35 +1,267 @@
 package qryptos
 
-import "math"
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
 
 const (
 	AmountRatio = 100000000
 	MinimalUnit = Amount(1)
-	AmountZero = Amount(0)
+	AmountZero  = Amount(0)
+
+	amountDecimals = 8
+	// maxAmountExponent bounds exponent notation well beyond anything an int64 of minimal units can hold, so a
+	// hostile exponent cannot make ParseAmount scale for billions of iterations.
+	maxAmountExponent = 30
+)
+
+var (
+	ErrInvalidAmount   = errors.New("invalid amount")
+	ErrAmountPrecision = errors.New("amount has more than 8 decimal places")
+	ErrAmountRange     = errors.New("amount out of range")
+	ErrDivisionByZero  = errors.New("amount division by zero")
 )
 
 type Amount int
 
+// RoundingMode controls how Multiply and Divide resolve results that fall between two minimal units.
+type RoundingMode int
+
+const (
+	// RoundFloor rounds towards negative infinity.
+	RoundFloor RoundingMode = iota
+	// RoundCeil rounds towards positive infinity.
+	RoundCeil
+	// RoundHalfEven rounds to the nearest unit, with ties going to the even neighbour.
+	RoundHalfEven
+	// RoundHalfUp rounds to the nearest unit, with ties going away from zero.
+	RoundHalfUp
+)
+
+// ParseAmount parses a decimal string such as "0.00010366" into an Amount without going through a float.
+// Exponent notation ("1.5e-05") is accepted since the exchange sometimes renders prices as JSON numbers.
+// Digits beyond the 8th decimal place must be zero; anything else is an error rather than being rounded.
+func ParseAmount(s string) (Amount, error) {
+	return parseAmount(s, false)
+}
+
+// parseExchangeAmount is ParseAmount for values reported by the exchange, which are rounded half up to 8 decimal
+// places instead of being refused. A stray extra digit on a field we only display shouldn't fail a whole listing.
+func parseExchangeAmount(s string) (Amount, error) {
+	return parseAmount(s, true)
+}
+
+func parseAmount(s string, round bool) (Amount, error) {
+	if s == "" {
+		return AmountZero, ErrInvalidAmount
+	}
+
+	str := s
+	negative := false
+	switch str[0] {
+	case '-':
+		negative = true
+		str = str[1:]
+	case '+':
+		str = str[1:]
+	}
+
+	exp := 0
+	if i := strings.IndexAny(str, "eE"); i >= 0 {
+		e, err := strconv.Atoi(str[i+1:])
+		if err != nil {
+			return AmountZero, ErrInvalidAmount
+		}
+		if e > maxAmountExponent || e < -maxAmountExponent {
+			return AmountZero, ErrAmountRange
+		}
+		exp = e
+		str = str[:i]
+	}
+
+	intPart, fracPart := str, ""
+	if i := strings.IndexByte(str, '.'); i >= 0 {
+		intPart, fracPart = str[:i], str[i+1:]
+	}
+	if intPart == "" && fracPart == "" {
+		return AmountZero, ErrInvalidAmount
+	}
+
+	digits := intPart + fracPart
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return AmountZero, ErrInvalidAmount
+		}
+	}
+
+	// digits * 10^shift is the value in minimal units
+	shift := amountDecimals + exp - len(fracPart)
+	roundUp := false
+	if shift < 0 {
+		// When every digit is dropped the first dropped digit is an implied zero, so nothing rounds up
+		roundUp = -shift <= len(digits) && digits[len(digits)+shift] >= '5'
+		if -shift > len(digits) {
+			shift = -len(digits)
+		}
+		dropped := digits[len(digits)+shift:]
+		if strings.Trim(dropped, "0") != "" && !round {
+			return AmountZero, ErrAmountPrecision
+		}
+		digits = digits[:len(digits)+shift]
+		shift = 0
+	}
+
+	var units uint64
+	for _, r := range digits {
+		if units > (math.MaxInt64-uint64(r-'0'))/10 {
+			return AmountZero, ErrAmountRange
+		}
+		units = units*10 + uint64(r-'0')
+	}
+	for ; shift > 0; shift-- {
+		if units > math.MaxInt64/10 {
+			return AmountZero, ErrAmountRange
+		}
+		units *= 10
+	}
+	if round && roundUp {
+		if units == math.MaxInt64 {
+			return AmountZero, ErrAmountRange
+		}
+		units++
+	}
+
+	if negative {
+		return Amount(-int64(units)), nil
+	}
+	return Amount(units), nil
+}
+
+// String renders the amount with exactly 8 decimal places, the format the exchange expects.
+func (ca Amount) String() string {
+	sign := ""
+	abs := uint64(ca)
+	if ca < 0 {
+		sign = "-"
+		abs = uint64(-int64(ca))
+	}
+
+	return fmt.Sprintf("%s%d.%08d", sign, abs/AmountRatio, abs%AmountRatio)
+}
+
+func (ca Amount) MarshalText() ([]byte, error) {
+	return []byte(ca.String()), nil
+}
+
+func (ca *Amount) UnmarshalText(text []byte) error {
+	amt, err := ParseAmount(string(text))
+	if err != nil {
+		return err
+	}
+	*ca = amt
+
+	return nil
+}
+
+// MarshalJSON renders the amount as a quoted decimal string, matching the exchange's own responses.
+func (ca Amount) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(ca.String())), nil
+}
+
+// UnmarshalJSON accepts both quoted decimal strings and bare JSON numbers. A null leaves the amount unchanged.
+func (ca *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+
+	if strings.HasPrefix(s, `"`) {
+		unquoted, err := strconv.Unquote(s)
+		if err != nil {
+			return ErrInvalidAmount
+		}
+		s = unquoted
+	}
+
+	return ca.UnmarshalText([]byte(s))
+}
+
 func (ca Amount) ToDecimal() float64 {
 	return float64(ca) / float64(AmountRatio)
 }
 
-func (ca *Amount) FromDecimal(dec float64)  {
+func (ca *Amount) FromDecimal(dec float64) {
 	*ca = Amount(dec * AmountRatio)
 }
 
-func (ca Amount) Multiply(o Amount) Amount {
-	return (ca * o) / AmountRatio
+// Multiply returns ca * o. The intermediate product is computed at 128 bits so it cannot wrap; ErrAmountRange is
+// returned when the result itself does not fit in an Amount.
+func (ca Amount) Multiply(o Amount, mode RoundingMode) (Amount, error) {
+	return mulDiv(int64(ca), int64(o), AmountRatio, mode)
+}
+
+// Divide returns ca / o, rounded according to mode.
+func (ca Amount) Divide(o Amount, mode RoundingMode) (Amount, error) {
+	if o == AmountZero {
+		return AmountZero, ErrDivisionByZero
+	}
+
+	return mulDiv(int64(ca), AmountRatio, int64(o), mode)
 }
 
-func (ca Amount) Divide(o Amount) Amount {
-	f := (float64(ca) / float64(o)) * AmountRatio
-	var rounded float64
-	if f - math.Floor(f) < 0.5 {
-		rounded = math.Floor(f)
-	} else {
-		rounded = math.Ceil(f)
+// mulDiv computes (a * b) / d with a 128-bit intermediate and applies the rounding mode to the remainder.
+func mulDiv(a, b, d int64, mode RoundingMode) (Amount, error) {
+	negative := (a < 0) != (b < 0) != (d < 0)
+
+	hi, lo := bits.Mul64(absInt64(a), absInt64(b))
+	divisor := absInt64(d)
+	if hi >= divisor {
+		return AmountZero, ErrAmountRange
 	}
+	q, r := bits.Div64(hi, lo, divisor)
 
-	return Amount(rounded)
+	if r != 0 {
+		var roundAway bool
+		switch mode {
+		case RoundFloor:
+			roundAway = negative
+		case RoundCeil:
+			roundAway = !negative
+		case RoundHalfUp:
+			roundAway = r >= divisor-r
+		case RoundHalfEven:
+			roundAway = r > divisor-r || (r == divisor-r && q%2 == 1)
+		default:
+			return AmountZero, fmt.Errorf("unknown rounding mode: %d", mode)
+		}
+		if roundAway {
+			if q == math.MaxUint64 {
+				return AmountZero, ErrAmountRange
+			}
+			q++
+		}
+	}
+
+	if negative {
+		if q > 1<<63 {
+			return AmountZero, ErrAmountRange
+		}
+		return Amount(-int64(q)), nil
+	}
+	if q > math.MaxInt64 {
+		return AmountZero, ErrAmountRange
+	}
+	return Amount(q), nil
+}
+
+func absInt64(v int64) uint64 {
+	if v < 0 {
+		return uint64(-v)
+	}
+	return uint64(v)
 }
@@ -1,6 +1,10 @@
 package qryptos
 
-import "testing"
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
 
 func TestAmount_FromDecimal(t *testing.T) {
 	dec := 0.347234
@@ -27,7 +31,10 @@ func TestAmount_Multiply(t *testing.T) {
 	am1 := Amount(297349782)
 	am2 := Amount(874301822)
 
-	product := am1.Multiply(am2)
+	product, err := am1.Multiply(am2, RoundFloor)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
 
 	expected := Amount(2599734561)
 	if product != expected {
@@ -39,7 +46,10 @@ func TestAmount_Divide(t *testing.T) {
 	am1 := Amount(297349782)
 	am2 := Amount(874301822)
 
-	quotient := am1.Divide(am2)
+	quotient, err := am1.Divide(am2, RoundHalfUp)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
 
 	expected := Amount(34009969)
 	if quotient != expected {
@@ -51,10 +61,192 @@ func TestAmount_Divide_RoundUp(t *testing.T) {
 	am1 := Amount(500000000)
 	am2 := Amount(300000000)
 
-	quotient := am1.Divide(am2)
+	quotient, err := am1.Divide(am2, RoundHalfUp)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
 
 	expected := Amount(166666667)
 	if quotient != expected {
 		t.Error("Unexpected quotient. Expected:", expected, "; Actual:", quotient)
 	}
-}
\ No newline at end of file
+}
+
+func TestParseAmount(t *testing.T) {
+	cases := map[string]Amount{
+		"0.00010366":     Amount(10366),
+		"105.8632":       Amount(10586320000),
+		"0.0":            AmountZero,
+		"1":              Amount(100000000),
+		".5":             Amount(50000000),
+		"-2.5":           Amount(-250000000),
+		"0.000103660000": Amount(10366),
+		"1.5e-05":        Amount(1500),
+		"1E2":            Amount(10000000000),
+	}
+
+	for input, expected := range cases {
+		actual, err := ParseAmount(input)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %q: %s", input, err.Error())
+			continue
+		}
+		if actual != expected {
+			t.Errorf("Unexpected value for %q. Expected: %d; Actual: %d.", input, expected, actual)
+		}
+	}
+}
+
+func TestParseAmount_Invalid(t *testing.T) {
+	cases := map[string]error{
+		"":                     ErrInvalidAmount,
+		".":                    ErrInvalidAmount,
+		"abc":                  ErrInvalidAmount,
+		"1.2.3":                ErrInvalidAmount,
+		"0.000000001":          ErrAmountPrecision,
+		"99999999999999999999": ErrAmountRange,
+		"0e999999999":          ErrAmountRange,
+		"1e-999999999":         ErrAmountRange,
+	}
+
+	for input, expected := range cases {
+		if _, err := ParseAmount(input); err != expected {
+			t.Errorf("Unexpected error for %q. Expected: %v; Actual: %v.", input, expected, err)
+		}
+	}
+}
+
+func TestParseExchangeAmount(t *testing.T) {
+	cases := map[string]Amount{
+		"0.00010366":        Amount(10366),
+		"105373.450000004":  Amount(10537345000000),
+		"105373.450000005":  Amount(10537345000001),
+		"-0.000000015":      Amount(-2),
+		"0.000000000000009": AmountZero,
+		"1.5e-09":           AmountZero,
+	}
+
+	for input, expected := range cases {
+		actual, err := parseExchangeAmount(input)
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %s", input, err.Error())
+			continue
+		}
+		if actual != expected {
+			t.Errorf("Unexpected value for %q. Expected: %d; Actual: %d.", input, expected, actual)
+		}
+	}
+
+	if _, err := parseExchangeAmount("92233720368.547758075"); err != ErrAmountRange {
+		t.Errorf("Expected ErrAmountRange; Actual: %v.", err)
+	}
+}
+
+func TestAmount_String(t *testing.T) {
+	cases := map[Amount]string{
+		Amount(10366):      "0.00010366",
+		Amount(297349782):  "2.97349782",
+		AmountZero:         "0.00000000",
+		Amount(-250000000): "-2.50000000",
+	}
+
+	for input, expected := range cases {
+		if actual := input.String(); actual != expected {
+			t.Errorf("Unexpected string. Expected: %s; Actual: %s.", expected, actual)
+		}
+	}
+}
+
+func TestAmount_JSON(t *testing.T) {
+	var parsed struct {
+		Price    Amount `json:"price"`
+		Quantity Amount `json:"quantity"`
+	}
+	if err := json.Unmarshal([]byte(`{"price": 0.00004754, "quantity": "231.8068"}`), &parsed); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	if expected := Amount(4754); parsed.Price != expected {
+		t.Errorf("Unexpected price. Expected: %d; Actual: %d.", expected, parsed.Price)
+	}
+	if expected := Amount(23180680000); parsed.Quantity != expected {
+		t.Errorf("Unexpected quantity. Expected: %d; Actual: %d.", expected, parsed.Quantity)
+	}
+
+	out, err := json.Marshal(parsed)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	expected := `{"price":"0.00004754","quantity":"231.80680000"}`
+	if actual := string(out); actual != expected {
+		t.Errorf("Unexpected JSON. Expected: %s; Actual: %s.", expected, actual)
+	}
+}
+
+func TestAmount_Multiply_Large(t *testing.T) {
+	// 50 BTC at a price of 500,000 overflows a naive int64 product
+	am1 := Amount(5000000000)
+	am2 := Amount(50000000000000)
+
+	product, err := am1.Multiply(am2, RoundFloor)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	expected := Amount(2500000000000000)
+	if product != expected {
+		t.Errorf("The product did not match expected value. Expected: %d; Actual: %d.", expected, product)
+	}
+}
+
+func TestAmount_Multiply_Overflow(t *testing.T) {
+	am1 := Amount(math.MaxInt64 / 2)
+	am2 := Amount(300000000)
+
+	if _, err := am1.Multiply(am2, RoundFloor); err != ErrAmountRange {
+		t.Errorf("Expected ErrAmountRange; Actual: %v.", err)
+	}
+
+	// The truncated quotient is the largest uint64, so rounding it up must not wrap to zero
+	if product, err := Amount(300000006).Multiply(Amount(6148914568258225840), RoundCeil); err != ErrAmountRange {
+		t.Errorf("Expected ErrAmountRange; Actual: %s, %v.", product, err)
+	}
+}
+
+func TestAmount_Divide_ByZero(t *testing.T) {
+	if _, err := Amount(100).Divide(AmountZero, RoundFloor); err != ErrDivisionByZero {
+		t.Errorf("Expected ErrDivisionByZero; Actual: %v.", err)
+	}
+}
+
+func TestAmount_Divide_RoundingModes(t *testing.T) {
+	cases := []struct {
+		numerator   Amount
+		denominator Amount
+		mode        RoundingMode
+		expected    Amount
+	}{
+		{Amount(500000000), Amount(300000000), RoundFloor, Amount(166666666)},
+		{Amount(500000000), Amount(300000000), RoundCeil, Amount(166666667)},
+		{Amount(-500000000), Amount(300000000), RoundFloor, Amount(-166666667)},
+		{Amount(-500000000), Amount(300000000), RoundCeil, Amount(-166666666)},
+		// 0.00000005 / 2 lands exactly halfway between 2 and 3 units
+		{Amount(5), Amount(200000000), RoundHalfUp, Amount(3)},
+		{Amount(5), Amount(200000000), RoundHalfEven, Amount(2)},
+		{Amount(7), Amount(200000000), RoundHalfEven, Amount(4)},
+		{Amount(-5), Amount(200000000), RoundHalfUp, Amount(-3)},
+	}
+
+	for _, c := range cases {
+		quotient, err := c.numerator.Divide(c.denominator, c.mode)
+		if err != nil {
+			t.Errorf("Unexpected error: %s", err.Error())
+			continue
+		}
+		if quotient != c.expected {
+			t.Errorf("Unexpected quotient for %s / %s (mode %d). Expected: %d; Actual: %d.",
+				c.numerator, c.denominator, c.mode, c.expected, quotient)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Command qryptosauth prints, verifies and decodes X-Quoine-Auth tokens for debugging signed requests.
+//
+//	qryptosauth [sign]        sign REQUEST_PATH with TOKEN_ID and USER_SECRET
+//	qryptosauth verify TOKEN  check TOKEN was signed with USER_SECRET and print its claims
+//	qryptosauth decode TOKEN  print TOKEN's claims without checking the signature
+//
+// It used to be the qryptos/auth package itself, which is now the signing library. Install it with
+//
+//	go install github.com/tobyjsullivan/shifty/qryptos/auth/cmd/qryptosauth
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/tobyjsullivan/shifty/qryptos/auth"
+)
+
+var (
+	tokenId    = os.Getenv("TOKEN_ID")
+	userSecret = os.Getenv("USER_SECRET")
+	path       = os.Getenv("REQUEST_PATH")
+)
+
+func main() {
+	command := "sign"
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+
+	switch command {
+	case "sign":
+		sign()
+	case "verify", "decode":
+		if len(os.Args) != 3 {
+			usage()
+		}
+		inspect(command, os.Args[2])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: qryptosauth [sign | verify TOKEN | decode TOKEN]")
+	os.Exit(2)
+}
+
+func sign() {
+	fmt.Println("Token ID:", tokenId)
+
+	tokenString, err := auth.NewSigner(auth.Static(tokenId, userSecret), nil).Sign(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+
+	claims, err := auth.Decode(tokenString)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+	fmt.Println("Nonce:", claims.Nonce)
+
+	fmt.Println()
+	fmt.Println("JWT:", tokenString)
+}
+
+func inspect(command, tokenString string) {
+	var claims *auth.Claims
+	var err error
+	if command == "verify" {
+		claims, err = auth.Verify(tokenString, userSecret)
+	} else {
+		claims, err = auth.Decode(tokenString)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+
+	if command == "verify" {
+		fmt.Println("Signature: valid")
+	}
+	fmt.Println("Token ID:", claims.TokenID)
+	fmt.Println("Path:", claims.Path)
+	fmt.Println("Nonce:", claims.Nonce, "("+time.Unix(0, claims.Nonce*int64(time.Millisecond)).UTC().Format(time.RFC3339Nano)+")")
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+var ErrNoCredentials = errors.New("no API credentials found")
+
+// Credentials are an API token's ID and secret.
+type Credentials struct {
+	TokenID string
+	Secret  string
+}
+
+// CredentialSource looks up credentials each time a request is signed, so a source can rotate them.
+type CredentialSource interface {
+	Credentials() (Credentials, error)
+}
+
+type staticCredentials Credentials
+
+func (c staticCredentials) Credentials() (Credentials, error) {
+	return Credentials(c), nil
+}
+
+// Static always returns the same credentials.
+func Static(tokenId, secret string) CredentialSource {
+	return staticCredentials{TokenID: tokenId, Secret: secret}
+}
+
+// EnvCredentials reads credentials from environment variables.
+type EnvCredentials struct {
+	TokenIDVar string
+	SecretVar  string
+}
+
+// DefaultEnv reads the variables every bot in this repo is configured with.
+var DefaultEnv = EnvCredentials{
+	TokenIDVar: "QRYPTOS_API_TOKEN_ID",
+	SecretVar:  "QRYPTOS_API_SECRET_KEY",
+}
+
+func (e EnvCredentials) Credentials() (Credentials, error) {
+	c := Credentials{
+		TokenID: os.Getenv(e.TokenIDVar),
+		Secret:  os.Getenv(e.SecretVar),
+	}
+	if c.TokenID == "" && c.Secret == "" {
+		return Credentials{}, ErrNoCredentials
+	}
+	if c.TokenID == "" || c.Secret == "" {
+		return Credentials{}, fmt.Errorf("auth: must set both %s and %s", e.TokenIDVar, e.SecretVar)
+	}
+
+	return c, nil
+}
+
+type chain []CredentialSource
+
+func (ch chain) Credentials() (Credentials, error) {
+	for _, src := range ch {
+		c, err := src.Credentials()
+		if err == ErrNoCredentials {
+			continue
+		}
+		return c, err
+	}
+
+	return Credentials{}, ErrNoCredentials
+}
+
+// Chain tries each source in turn and returns the first credentials found. Any error other than ErrNoCredentials
+// stops the search.
+func Chain(sources ...CredentialSource) CredentialSource {
+	return chain(sources)
+}
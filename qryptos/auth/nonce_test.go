@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClockNonceSource_Concurrent(t *testing.T) {
+	src := NewClockNonceSource()
+
+	var mu sync.Mutex
+	seen := make(map[int64]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			last := int64(0)
+			for j := 0; j < 1000; j++ {
+				nonce, _ := src.Next()
+				if nonce <= last {
+					t.Errorf("Nonce went backwards: %d after %d.", nonce, last)
+				}
+				last = nonce
+
+				mu.Lock()
+				if seen[nonce] {
+					t.Errorf("Duplicate nonce: %d", nonce)
+				}
+				seen[nonce] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestClockNonceSource_ClockStepsBack(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	src := &ClockNonceSource{now: func() time.Time { return now }}
+
+	first, _ := src.Next()
+	now = now.Add(-time.Hour)
+	second, _ := src.Next()
+
+	if second != first+1 {
+		t.Errorf("Unexpected nonce. Expected: %d; Actual: %d.", first+1, second)
+	}
+}
+
+func TestFileNonceSource_Resumes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nonce")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "nonce")
+
+	// A nonce recorded from a clock running ahead of ours
+	future := time.Now().Add(time.Hour).UnixNano() / int64(time.Millisecond)
+	if err := ioutil.WriteFile(path, []byte(strconv.FormatInt(future, 10)), 0600); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	src, err := NewFileNonceSource(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	nonce, err := src.Next()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if nonce != future+1 {
+		t.Errorf("Unexpected nonce. Expected: %d; Actual: %d.", future+1, nonce)
+	}
+
+	restarted, err := NewFileNonceSource(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if next, _ := restarted.Next(); next != nonce+1 {
+		t.Errorf("Unexpected nonce after restart. Expected: %d; Actual: %d.", nonce+1, next)
+	}
+}
+
+func TestSharedNonceSource(t *testing.T) {
+	if SharedNonceSource("shared-a") != SharedNonceSource("shared-a") {
+		t.Error("Expected clients with the same token to share a source.")
+	}
+	if SharedNonceSource("shared-a") == SharedNonceSource("shared-b") {
+		t.Error("Expected tokens to have separate sources.")
+	}
+}
@@ -1,34 +1,150 @@
-package main
+// Package auth signs requests to the exchange's private API. Each request carries an HS256 JWT in the X-Quoine-Auth
+// header whose claims name the API token, the request path and a nonce.
+//
+// This package used to be a command that printed a token. That command is now qryptos/auth/cmd/qryptosauth.
+package auth
 
 import (
-	"github.com/dgrijalva/jwt-go"
-	"os"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
 	"strconv"
-	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	HeaderAuth       = "X-Quoine-Auth"
+	HeaderAPIVersion = "X-Quoine-API-Version"
+	APIVersion       = "2"
 )
 
 var (
-	tokenId    = os.Getenv("TOKEN_ID")
-	userSecret = os.Getenv("USER_SECRET")
-	path       = os.Getenv("REQUEST_PATH")
-	nonce      = time.Now().UnixNano() / 1000000
+	ErrInvalidToken = errors.New("invalid auth token")
+	ErrInvalidNonce = errors.New("auth token has a missing or invalid nonce")
 )
 
-func main() {
-	println("Token ID:", tokenId)
-	println("Nonce:", nonce)
+// Claims are the contents of an auth token.
+type Claims struct {
+	TokenID string
+	Path    string
+	Nonce   int64
+}
+
+// Signer issues auth tokens. It is safe for concurrent use.
+type Signer struct {
+	creds  CredentialSource
+	nonces NonceSource
+}
+
+// NewSigner signs with credentials from creds. A nil nonces uses the token's SharedNonceSource.
+func NewSigner(creds CredentialSource, nonces NonceSource) *Signer {
+	return &Signer{
+		creds:  creds,
+		nonces: nonces,
+	}
+}
+
+// Sign issues a token for a request to path, which must include any query string.
+func (s *Signer) Sign(path string) (string, error) {
+	creds, err := s.creds.Credentials()
+	if err != nil {
+		return "", err
+	}
+
+	nonces := s.nonces
+	if nonces == nil {
+		nonces = SharedNonceSource(creds.TokenID)
+	}
+	nonce, err := nonces.Next()
+	if err != nil {
+		return "", err
+	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"path":     path,
 		"nonce":    strconv.FormatInt(nonce, 10),
-		"token_id": tokenId,
+		"token_id": creds.TokenID,
 	})
 
-	tokenString, err := token.SignedString([]byte(userSecret))
+	return token.SignedString([]byte(creds.Secret))
+}
+
+// SignRequest sets the auth and API version headers on req.
+func (s *Signer) SignRequest(req *http.Request) error {
+	token, err := s.Sign(RequestPath(req.URL))
 	if err != nil {
-		panic(err.Error())
+		return err
+	}
+
+	req.Header.Set(HeaderAuth, token)
+	req.Header.Set(HeaderAPIVersion, APIVersion)
+
+	return nil
+}
+
+// RequestPath is the path a token for a request to u is signed with.
+func RequestPath(u *url.URL) string {
+	path := u.Path
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	return path
+}
+
+func parseClaims(token *jwt.Token) (*Claims, error) {
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	claims := &Claims{}
+	claims.TokenID, _ = mapClaims["token_id"].(string)
+	claims.Path, _ = mapClaims["path"].(string)
+
+	// The exchange wants the nonce as a string but older tokens may carry a number
+	switch nonce := mapClaims["nonce"].(type) {
+	case string:
+		n, err := strconv.ParseInt(nonce, 10, 64)
+		if err != nil {
+			return nil, ErrInvalidNonce
+		}
+		claims.Nonce = n
+	case float64:
+		claims.Nonce = int64(nonce)
+	default:
+		return nil, ErrInvalidNonce
+	}
+
+	return claims, nil
+}
+
+// Decode reads a token's claims without checking its signature.
+func Decode(tokenString string) (*Claims, error) {
+	token, err := new(jwt.Parser).ParseWithClaims(tokenString, jwt.MapClaims{}, nil)
+	if validationErr, ok := err.(*jwt.ValidationError); token == nil || (ok && validationErr.Errors&jwt.ValidationErrorMalformed != 0) {
+		return nil, err
+	}
+
+	return parseClaims(token)
+}
+
+// Verify checks a token was signed with secret and returns its claims.
+func Verify(tokenString, secret string) (*Claims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("auth: unexpected signing method %v", token.Header["alg"])
+		}
+		return []byte(secret), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, ErrInvalidToken
 	}
 
-	println()
-	println("JWT:", tokenString)
+	return parseClaims(token)
 }
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+type fixedNonces int64
+
+func (n *fixedNonces) Next() (int64, error) {
+	*n++
+	return int64(*n), nil
+}
+
+func TestSigner_SignAndVerify(t *testing.T) {
+	nonces := fixedNonces(1000)
+	signer := NewSigner(Static("123456", "secret"), &nonces)
+
+	token, err := signer.Sign("/orders?page=2")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	claims, err := Verify(token, "secret")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if claims.TokenID != "123456" || claims.Path != "/orders?page=2" || claims.Nonce != 1001 {
+		t.Errorf("Unexpected claims: %+v", claims)
+	}
+
+	if _, err := Verify(token, "wrong"); err == nil {
+		t.Error("Expected a token signed with another secret to fail verification.")
+	}
+	if decoded, err := Decode(token); err != nil || *decoded != *claims {
+		t.Errorf("Unexpected decoded claims: %+v (%v)", decoded, err)
+	}
+	if _, err := Decode("not.a-token"); err == nil {
+		t.Error("Expected a malformed token to fail decoding.")
+	}
+}
+
+func TestVerify_MissingNonce(t *testing.T) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"path":     "/orders",
+		"token_id": "123456",
+	}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	if _, err := Verify(token, "secret"); err != ErrInvalidNonce {
+		t.Errorf("Unexpected error. Expected: %v; Actual: %v.", ErrInvalidNonce, err)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var claims *Claims
+	var version string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version = r.Header.Get(HeaderAPIVersion)
+
+		var err error
+		if claims, err = Verify(r.Header.Get(HeaderAuth), "secret"); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer ts.Close()
+
+	hc := &http.Client{Transport: &Transport{Signer: NewSigner(Static("123456", "secret"), nil)}}
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/accounts/balance?currency=BTC", nil)
+	res, err := hc.Do(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Unexpected status. Expected: %d; Actual: %d.", http.StatusOK, res.StatusCode)
+	}
+	if claims.Path != "/accounts/balance?currency=BTC" {
+		t.Errorf("Unexpected path. Expected: %s; Actual: %s.", "/accounts/balance?currency=BTC", claims.Path)
+	}
+	if version != APIVersion {
+		t.Errorf("Unexpected API version. Expected: %s; Actual: %s.", APIVersion, version)
+	}
+	if req.Header.Get(HeaderAuth) != "" {
+		t.Error("Expected the caller's request to be left unsigned.")
+	}
+}
+
+func TestChain(t *testing.T) {
+	env := EnvCredentials{TokenIDVar: "AUTH_TEST_TOKEN_ID", SecretVar: "AUTH_TEST_SECRET"}
+	os.Unsetenv(env.TokenIDVar)
+	os.Unsetenv(env.SecretVar)
+
+	creds, err := Chain(env, Static("fallback", "secret")).Credentials()
+	if err != nil || creds.TokenID != "fallback" {
+		t.Errorf("Expected the static fallback; Actual: %+v (%v).", creds, err)
+	}
+
+	os.Setenv(env.TokenIDVar, "from-env")
+	defer os.Unsetenv(env.TokenIDVar)
+	if _, err := Chain(env, Static("fallback", "secret")).Credentials(); err == nil {
+		t.Error("Expected a half-configured environment to be an error.")
+	}
+}
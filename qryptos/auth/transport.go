@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"net/http"
+)
+
+// Transport signs every request before passing it to Base, so any http.Client can call the private API:
+//
+//	hc := &http.Client{Transport: &auth.Transport{Signer: auth.NewSigner(auth.DefaultEnv, nil)}}
+type Transport struct {
+	Signer *Signer
+
+	// Base sends the signed request. A nil Base uses http.DefaultTransport.
+	Base http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the caller's request
+	signed := req.Clone(req.Context())
+	if err := t.Signer.SignRequest(signed); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return base.RoundTrip(signed)
+}
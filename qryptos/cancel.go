@@ -0,0 +1,149 @@
+package qryptos
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+const defaultCancelConcurrency = 4
+
+// CancelFilter selects live orders for CancelOrders. Zero fields match every order, so an empty filter cancels
+// everything. Match narrows the selection further, for example to orders priced above the market:
+//
+//	&CancelFilter{ProductID: 56, Side: OrderSideSell, Match: func(o *OrderDetails) bool { return o.Price > ask }}
+type CancelFilter struct {
+	ProductID int
+	Side      string
+	Match     func(*OrderDetails) bool
+
+	// Concurrency bounds how many cancellations are in flight at once. Requests still wait on the client's rate
+	// limiter, so this only helps while there is burst capacity to spare.
+	Concurrency int
+}
+
+func (f *CancelFilter) query() *OrdersQuery {
+	return &OrdersQuery{
+		Status:    OrderStatusLive,
+		ProductID: f.ProductID,
+		Side:      f.Side,
+	}
+}
+
+func (f *CancelFilter) matches(o *OrderDetails) bool {
+	if o.Status != OrderStatusLive {
+		return false
+	}
+	if f.ProductID != 0 && o.ProductID != f.ProductID {
+		return false
+	}
+	if f.Side != "" && o.Side != f.Side {
+		return false
+	}
+
+	return f.Match == nil || f.Match(o)
+}
+
+func (f *CancelFilter) concurrency() int {
+	if f == nil || f.Concurrency <= 0 {
+		return defaultCancelConcurrency
+	}
+	return f.Concurrency
+}
+
+// CancelResult is the outcome of cancelling one order.
+type CancelResult struct {
+	Order *OrderDetails
+	Err   error
+}
+
+// CancelReport lists the outcome for every order CancelOrders tried to cancel, in the order they were listed.
+type CancelReport struct {
+	Results []*CancelResult
+}
+
+// Cancelled returns the orders that were cancelled, or were already gone by the time we got to them.
+func (r *CancelReport) Cancelled() []*OrderDetails {
+	var out []*OrderDetails
+	for _, result := range r.Results {
+		if result.Err == nil || IsOrderNotFound(result.Err) {
+			out = append(out, result.Order)
+		}
+	}
+
+	return out
+}
+
+// Failed returns the results for orders that may still be live.
+func (r *CancelReport) Failed() []*CancelResult {
+	var out []*CancelResult
+	for _, result := range r.Results {
+		if result.Err != nil && !IsOrderNotFound(result.Err) {
+			out = append(out, result)
+		}
+	}
+
+	return out
+}
+
+// Err summarises the failures, or returns nil if every order was cancelled.
+func (r *CancelReport) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("qryptos: failed to cancel %d of %d orders: order %d: %s", len(failed), len(r.Results), failed[0].Order.ID, failed[0].Err)
+}
+
+// CancelMatching cancels the orders f matches using cancel, at most f.Concurrency at a time. A nil f cancels every
+// order given. It lets any venue share PrivateClient's bulk cancellation.
+func CancelMatching(ctx context.Context, orders []*OrderDetails, f *CancelFilter, cancel func(ctx context.Context, orderId int) error) *CancelReport {
+	report := &CancelReport{}
+	for _, o := range orders {
+		if f == nil || f.matches(o) {
+			report.Results = append(report.Results, &CancelResult{Order: o})
+		}
+	}
+
+	sem := make(chan struct{}, f.concurrency())
+	var wg sync.WaitGroup
+	for _, result := range report.Results {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			result.Err = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+		go func(result *CancelResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			result.Err = cancel(ctx, result.Order.ID)
+		}(result)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// CancelOrders cancels every live order matching f. An error is returned only if the orders couldn't be listed;
+// failures to cancel individual orders are in the report.
+func (c *PrivateClient) CancelOrders(f *CancelFilter) (*CancelReport, error) {
+	return c.CancelOrdersContext(context.Background(), f)
+}
+
+func (c *PrivateClient) CancelOrdersContext(ctx context.Context, f *CancelFilter) (*CancelReport, error) {
+	if f == nil {
+		f = &CancelFilter{}
+	}
+
+	orders, err := c.FetchAllOrdersContext(ctx, f.query())
+	if err != nil {
+		return nil, err
+	}
+
+	return CancelMatching(ctx, orders, f, c.CancelOrderContext), nil
+}
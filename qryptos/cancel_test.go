@@ -0,0 +1,98 @@
+package qryptos
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPrivateClient_CancelOrders(t *testing.T) {
+	var inFlight, maxInFlight int32
+	var mu sync.Mutex
+	var cancelled []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			if r.URL.Query().Get("status") != OrderStatusLive || r.URL.Query().Get("product_id") != "56" {
+				t.Errorf("Unexpected query: %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"models": [
+				{"id": 1, "product_id": 56, "side": "sell", "status": "live", "price": "0.0002"},
+				{"id": 2, "product_id": 56, "side": "sell", "status": "live", "price": "0.0001"},
+				{"id": 3, "product_id": 56, "side": "buy", "status": "live", "price": "0.0003"},
+				{"id": 4, "product_id": 56, "side": "sell", "status": "live", "price": "0.0004"},
+				{"id": 5, "product_id": 56, "side": "sell", "status": "live", "price": "0.0005"}
+			], "current_page": 1, "total_pages": 1}`))
+			return
+		}
+
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if current <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		switch r.URL.Path {
+		case "/orders/4/cancel":
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case "/orders/5/cancel":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"message": "Order cannot be cancelled"}`))
+			return
+		}
+
+		mu.Lock()
+		cancelled = append(cancelled, strings.Split(r.URL.Path, "/")[2])
+		mu.Unlock()
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	client := NewPrivateClient("123456", "secret", WithBaseURL(ts.URL), WithRetryPolicy(NoRetries))
+
+	report, err := client.CancelOrders(&CancelFilter{
+		ProductID:   56,
+		Side:        OrderSideSell,
+		Match:       func(o *OrderDetails) bool { return o.Price > Amount(10000) },
+		Concurrency: 2,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	if len(report.Results) != 3 {
+		t.Fatalf("Unexpected result count. Expected: 3; Actual: %d.", len(report.Results))
+	}
+	for i, expected := range []int{1, 4, 5} {
+		if report.Results[i].Order.ID != expected {
+			t.Errorf("Unexpected order at %d. Expected: %d; Actual: %d.", i, expected, report.Results[i].Order.ID)
+		}
+	}
+	if len(cancelled) != 1 || cancelled[0] != "1" {
+		t.Errorf("Unexpected cancellations: %v", cancelled)
+	}
+
+	// An order that's already gone counts as cancelled
+	if len(report.Cancelled()) != 2 {
+		t.Errorf("Unexpected cancelled count. Expected: 2; Actual: %d.", len(report.Cancelled()))
+	}
+	failed := report.Failed()
+	if len(failed) != 1 || failed[0].Order.ID != 5 {
+		t.Errorf("Unexpected failures: %+v", failed)
+	}
+	if report.Err() == nil {
+		t.Error("Expected the report to summarise the failure.")
+	}
+
+	if max := atomic.LoadInt32(&maxInFlight); max > 2 {
+		t.Errorf("Unexpected concurrency. Expected at most: 2; Actual: %d.", max)
+	}
+}
@@ -0,0 +1,228 @@
+// Package candles aggregates the public trade tape into OHLCV bars.
+package candles
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/tobyjsullivan/shifty/qryptos"
+)
+
+// Interval is the length of time covered by a single candle.
+type Interval time.Duration
+
+const (
+	OneMinute   = Interval(time.Minute)
+	FiveMinutes = Interval(5 * time.Minute)
+	OneHour     = Interval(time.Hour)
+	OneDay      = Interval(24 * time.Hour)
+)
+
+var intervalNames = map[string]Interval{
+	"1m": OneMinute,
+	"5m": FiveMinutes,
+	"1h": OneHour,
+	"1d": OneDay,
+}
+
+var (
+	ErrInvalidInterval = errors.New("interval must be positive")
+	ErrOutOfOrder      = errors.New("trade is older than the current candle")
+)
+
+// ParseInterval accepts the shorthand names 1m, 5m, 1h and 1d as well as any positive time.ParseDuration string.
+func ParseInterval(s string) (Interval, error) {
+	if interval, ok := intervalNames[s]; ok {
+		return interval, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("unknown interval: %s", s)
+	}
+	if d <= 0 {
+		return 0, ErrInvalidInterval
+	}
+
+	return Interval(d), nil
+}
+
+func (i Interval) Duration() time.Duration {
+	return time.Duration(i)
+}
+
+func (i Interval) String() string {
+	for name, interval := range intervalNames {
+		if interval == i {
+			return name
+		}
+	}
+	return time.Duration(i).String()
+}
+
+// Candle summarises the trades in [Start, Start+Interval). A candle with no trades carries the previous close
+// forward as a flat bar with zero volume.
+type Candle struct {
+	Start    time.Time
+	Interval Interval
+	Open     qryptos.Amount
+	High     qryptos.Amount
+	Low      qryptos.Amount
+	Close    qryptos.Amount
+	Volume   qryptos.Amount
+	Trades   int
+}
+
+func (c *Candle) End() time.Time {
+	return c.Start.Add(c.Interval.Duration())
+}
+
+func (c *Candle) add(trade *qryptos.Trade) {
+	if c.Trades == 0 {
+		c.Open = trade.Price
+		c.High = trade.Price
+		c.Low = trade.Price
+	}
+	if trade.Price > c.High {
+		c.High = trade.Price
+	}
+	if trade.Price < c.Low {
+		c.Low = trade.Price
+	}
+	c.Close = trade.Price
+	c.Volume += trade.Quantity
+	c.Trades++
+}
+
+// Builder folds trades into candles as they arrive. Trades must be added in chronological order.
+type Builder struct {
+	interval Interval
+	candles  []*Candle
+}
+
+func NewBuilder(interval Interval) (*Builder, error) {
+	if interval <= 0 {
+		return nil, ErrInvalidInterval
+	}
+
+	return &Builder{interval: interval}, nil
+}
+
+// Add folds a trade into the current candle, opening new candles (and flat candles for any gap) as needed.
+func (b *Builder) Add(trade *qryptos.Trade) error {
+	start := trade.CreatedAt.Truncate(b.interval.Duration())
+
+	if len(b.candles) == 0 {
+		b.candles = append(b.candles, &Candle{Start: start, Interval: b.interval})
+	}
+
+	last := b.candles[len(b.candles)-1]
+	if start.Before(last.Start) {
+		return ErrOutOfOrder
+	}
+
+	for last.Start.Before(start) {
+		last = &Candle{
+			Start:    last.End(),
+			Interval: b.interval,
+			Open:     last.Close,
+			High:     last.Close,
+			Low:      last.Close,
+			Close:    last.Close,
+		}
+		b.candles = append(b.candles, last)
+	}
+
+	last.add(trade)
+
+	return nil
+}
+
+// Candles returns every candle built so far, oldest first. The last candle may still be open.
+func (b *Builder) Candles() []*Candle {
+	out := make([]*Candle, len(b.candles))
+	copy(out, b.candles)
+
+	return out
+}
+
+// Build sorts the trades and aggregates them into candles of the given interval.
+func Build(trades []*qryptos.Trade, interval Interval) ([]*Candle, error) {
+	builder, err := NewBuilder(interval)
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := make([]*qryptos.Trade, len(trades))
+	copy(sorted, trades)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].CreatedAt.Equal(sorted[j].CreatedAt) {
+			return sorted[i].ID < sorted[j].ID
+		}
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+
+	for _, trade := range sorted {
+		if err := builder.Add(trade); err != nil {
+			return nil, err
+		}
+	}
+
+	return builder.Candles(), nil
+}
+
+// Returns are the log returns between consecutive closes.
+func Returns(candles []*Candle) []float64 {
+	var out []float64
+	for i := 1; i < len(candles); i++ {
+		prev := candles[i-1].Close.ToDecimal()
+		cur := candles[i].Close.ToDecimal()
+		if prev <= 0 || cur <= 0 {
+			continue
+		}
+		out = append(out, math.Log(cur/prev))
+	}
+
+	return out
+}
+
+// Volatility is the sample standard deviation of the log returns, per candle interval. It is zero when there are
+// fewer than two returns.
+func Volatility(candles []*Candle) float64 {
+	returns := Returns(candles)
+	if len(returns) < 2 {
+		return 0
+	}
+
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(len(returns) - 1)
+
+	return math.Sqrt(variance)
+}
+
+// Trend is the fractional change from the first candle's open to the last candle's close. A positive trend means
+// the price rose over the period.
+func Trend(candles []*Candle) float64 {
+	if len(candles) == 0 {
+		return 0
+	}
+
+	open := candles[0].Open.ToDecimal()
+	if open <= 0 {
+		return 0
+	}
+
+	return candles[len(candles)-1].Close.ToDecimal()/open - 1
+}
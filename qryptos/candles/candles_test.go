@@ -0,0 +1,127 @@
+package candles
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/tobyjsullivan/shifty/qryptos"
+)
+
+func trade(id int, secs int64, price, quantity qryptos.Amount) *qryptos.Trade {
+	return &qryptos.Trade{
+		ID:        id,
+		Price:     price,
+		Quantity:  quantity,
+		CreatedAt: time.Unix(1516007400+secs, 0),
+	}
+}
+
+func TestBuild(t *testing.T) {
+	trades := []*qryptos.Trade{
+		trade(3, 50, 10300, 100),
+		trade(1, 0, 10000, 200),
+		trade(2, 30, 10500, 300),
+		trade(4, 200, 9900, 50),
+	}
+
+	bars, err := Build(trades, OneMinute)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	// The trades span minutes 0 and 3 so two flat candles fill the gap
+	if len(bars) != 4 {
+		t.Fatalf("Unexpected candle count. Expected: 4; Actual: %d.", len(bars))
+	}
+
+	first := bars[0]
+	if first.Open != 10000 || first.High != 10500 || first.Low != 10000 || first.Close != 10300 {
+		t.Errorf("Unexpected first candle: %+v", first)
+	}
+	if expected := qryptos.Amount(600); first.Volume != expected {
+		t.Errorf("Unexpected volume. Expected: %d; Actual: %d.", expected, first.Volume)
+	}
+	if first.Trades != 3 {
+		t.Errorf("Unexpected trade count. Expected: 3; Actual: %d.", first.Trades)
+	}
+
+	gap := bars[1]
+	if gap.Open != 10300 || gap.Close != 10300 || gap.Volume != 0 || gap.Trades != 0 {
+		t.Errorf("Unexpected gap candle: %+v", gap)
+	}
+	if !gap.Start.Equal(first.End()) {
+		t.Errorf("Unexpected gap start. Expected: %s; Actual: %s.", first.End(), gap.Start)
+	}
+
+	if last := bars[3]; last.Open != 9900 || last.Close != 9900 || last.Volume != 50 {
+		t.Errorf("Unexpected last candle: %+v", last)
+	}
+}
+
+func TestBuilder_OutOfOrder(t *testing.T) {
+	builder, err := NewBuilder(OneMinute)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	if err := builder.Add(trade(2, 120, 10000, 1)); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if err := builder.Add(trade(1, 10, 10000, 1)); err != ErrOutOfOrder {
+		t.Errorf("Expected ErrOutOfOrder; Actual: %v.", err)
+	}
+}
+
+func TestParseInterval(t *testing.T) {
+	cases := map[string]Interval{
+		"1m":  OneMinute,
+		"5m":  FiveMinutes,
+		"1h":  OneHour,
+		"1d":  OneDay,
+		"15m": Interval(15 * time.Minute),
+	}
+	for s, expected := range cases {
+		interval, err := ParseInterval(s)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %s: %s", s, err.Error())
+			continue
+		}
+		if interval != expected {
+			t.Errorf("Unexpected interval for %s. Expected: %s; Actual: %s.", s, expected, interval)
+		}
+	}
+
+	if _, err := ParseInterval("-1m"); err != ErrInvalidInterval {
+		t.Errorf("Expected ErrInvalidInterval; Actual: %v.", err)
+	}
+	if _, err := ParseInterval("weekly"); err == nil {
+		t.Error("Expected an error for an unknown interval.")
+	}
+}
+
+func TestVolatilityAndTrend(t *testing.T) {
+	bars := []*Candle{
+		{Open: 10000, Close: 10000},
+		{Open: 10000, Close: 11000},
+		{Open: 11000, Close: 9900},
+	}
+
+	returns := Returns(bars)
+	if len(returns) != 2 {
+		t.Fatalf("Unexpected return count. Expected: 2; Actual: %d.", len(returns))
+	}
+
+	expected := math.Abs(math.Log(1.1)-math.Log(0.9)) / math.Sqrt(2)
+	if actual := Volatility(bars); math.Abs(actual-expected) > 1e-9 {
+		t.Errorf("Unexpected volatility. Expected: %f; Actual: %f.", expected, actual)
+	}
+
+	if actual := Trend(bars); math.Abs(actual-(-0.01)) > 1e-9 {
+		t.Errorf("Unexpected trend. Expected: -0.01; Actual: %f.", actual)
+	}
+
+	if Volatility(bars[:2]) != 0 {
+		t.Error("Expected zero volatility from a single return.")
+	}
+}
@@ -0,0 +1,258 @@
+package qryptos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrProductNotFound = errors.New("product not found")
+
+// ProductSource lists the tradable products. PublicClient and the exchange simulators implement it.
+type ProductSource interface {
+	FetchProductsContext(ctx context.Context) ([]*ProductDetails, error)
+}
+
+// CatalogChanges describes how the product list changed between two refreshes.
+type CatalogChanges struct {
+	Added    []*ProductDetails
+	Removed  []*ProductDetails
+	Disabled []*ProductDetails
+	Enabled  []*ProductDetails
+}
+
+// Empty reports whether nothing changed.
+func (c *CatalogChanges) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Disabled) == 0 && len(c.Enabled) == 0
+}
+
+// Catalog caches the product list and indexes it by ID, pair code and currencies. Lookups refetch the products
+// once they are older than the TTL, so a catalog shared by several goroutines makes one request per TTL at most.
+// Market prices in the cached details are only as fresh as the last refresh.
+type Catalog struct {
+	src ProductSource
+	ttl time.Duration
+	now func() time.Time
+
+	// refreshMu serialises fetches so that concurrent lookups of a stale catalog share one request
+	refreshMu sync.Mutex
+
+	mu           sync.RWMutex
+	products     []*ProductDetails
+	byID         map[int]*ProductDetails
+	byPair       map[string]*ProductDetails
+	byCurrencies map[[2]string]*ProductDetails
+	fetchedAt    time.Time
+	loaded       bool
+	handlers     []func(*CatalogChanges)
+}
+
+// NewCatalog caches the products from src for ttl. Nothing is fetched until the first lookup or Refresh.
+func NewCatalog(src ProductSource, ttl time.Duration) *Catalog {
+	return &Catalog{
+		src: src,
+		ttl: ttl,
+		now: time.Now,
+	}
+}
+
+// OnChange registers fn to be called after any refresh that adds, removes, disables or enables a product. The
+// first load is not reported as a change.
+func (c *Catalog) OnChange(fn func(*CatalogChanges)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.handlers = append(c.handlers, fn)
+}
+
+// Refresh fetches the products now, regardless of the TTL, and reports what changed.
+func (c *Catalog) Refresh(ctx context.Context) (*CatalogChanges, error) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	return c.refresh(ctx)
+}
+
+func (c *Catalog) refresh(ctx context.Context) (*CatalogChanges, error) {
+	products, err := c.src.FetchProductsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]*ProductDetails, len(products))
+	byPair := make(map[string]*ProductDetails, len(products))
+	byCurrencies := make(map[[2]string]*ProductDetails, len(products))
+	for _, p := range products {
+		byID[p.ProductID] = p
+		byPair[p.CurrencyPairCode] = p
+
+		// Should two products trade the same currencies, the first one listed wins
+		currencies := [2]string{p.BaseCurrency, p.QuotedCurrency}
+		if _, ok := byCurrencies[currencies]; !ok {
+			byCurrencies[currencies] = p
+		}
+	}
+
+	c.mu.Lock()
+	changes := &CatalogChanges{}
+	if c.loaded {
+		changes = diffProducts(c.products, c.byID, products, byID)
+	}
+	c.products = products
+	c.byID = byID
+	c.byPair = byPair
+	c.byCurrencies = byCurrencies
+	c.fetchedAt = c.now()
+	c.loaded = true
+	handlers := c.handlers
+	c.mu.Unlock()
+
+	if !changes.Empty() {
+		for _, fn := range handlers {
+			fn(changes)
+		}
+	}
+
+	return changes, nil
+}
+
+// diffProducts walks the product lists rather than the maps so changes are reported in listing order.
+func diffProducts(prev []*ProductDetails, prevByID map[int]*ProductDetails, next []*ProductDetails, nextByID map[int]*ProductDetails) *CatalogChanges {
+	changes := &CatalogChanges{}
+	for _, p := range next {
+		old, ok := prevByID[p.ProductID]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, p)
+		case p.Disabled && !old.Disabled:
+			changes.Disabled = append(changes.Disabled, p)
+		case !p.Disabled && old.Disabled:
+			changes.Enabled = append(changes.Enabled, p)
+		}
+	}
+	for _, p := range prev {
+		if _, ok := nextByID[p.ProductID]; !ok {
+			changes.Removed = append(changes.Removed, p)
+		}
+	}
+
+	return changes
+}
+
+func (c *Catalog) stale() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return !c.loaded || c.now().Sub(c.fetchedAt) >= c.ttl
+}
+
+// ensure refreshes the catalog if it is stale.
+func (c *Catalog) ensure(ctx context.Context) error {
+	if !c.stale() {
+		return nil
+	}
+
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	// Someone else may have refreshed while we waited
+	if !c.stale() {
+		return nil
+	}
+
+	_, err := c.refresh(ctx)
+	return err
+}
+
+// Products returns every product, refreshing first if the cache is stale.
+func (c *Catalog) Products(ctx context.Context) ([]*ProductDetails, error) {
+	if err := c.ensure(ctx); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.products, nil
+}
+
+// Product looks up a product by ID.
+func (c *Catalog) Product(ctx context.Context, productId int) (*ProductDetails, error) {
+	if err := c.ensure(ctx); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	p, ok := c.byID[productId]
+	if !ok {
+		return nil, ErrProductNotFound
+	}
+	return p, nil
+}
+
+// ProductByPair looks up a product by its currency pair code, such as "ETHBTC".
+func (c *Catalog) ProductByPair(ctx context.Context, pairCode string) (*ProductDetails, error) {
+	if err := c.ensure(ctx); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	p, ok := c.byPair[pairCode]
+	if !ok {
+		return nil, ErrProductNotFound
+	}
+	return p, nil
+}
+
+// ProductFor looks up the product that trades base for quote.
+func (c *Catalog) ProductFor(ctx context.Context, base, quote string) (*ProductDetails, error) {
+	if err := c.ensure(ctx); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	p, ok := c.byCurrencies[[2]string{base, quote}]
+	if !ok {
+		return nil, ErrProductNotFound
+	}
+	return p, nil
+}
+
+// PairCode returns the pair code of a cached product without fetching, or "" if it isn't known. It is meant for
+// log messages.
+func (c *Catalog) PairCode(productId int) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if p, ok := c.byID[productId]; ok {
+		return p.CurrencyPairCode
+	}
+	return ""
+}
+
+// Run refreshes the catalog every interval until ctx is done, so lookups rarely have to wait on a fetch. Errors
+// are logged and the stale products kept until the next attempt.
+func (c *Catalog) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := c.Refresh(ctx); err != nil && ctx.Err() == nil {
+			fmt.Println("[Catalog::Run] Error refreshing products:", err.Error())
+		}
+	}
+}
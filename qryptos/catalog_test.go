@@ -0,0 +1,150 @@
+package qryptos
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeProductSource struct {
+	mu       sync.Mutex
+	products []*ProductDetails
+	fetches  int
+}
+
+func (s *fakeProductSource) FetchProductsContext(ctx context.Context) ([]*ProductDetails, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fetches++
+	out := make([]*ProductDetails, 0, len(s.products))
+	for _, p := range s.products {
+		c := *p
+		out = append(out, &c)
+	}
+	return out, nil
+}
+
+func (s *fakeProductSource) set(products ...*ProductDetails) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.products = products
+}
+
+func TestCatalog_Lookups(t *testing.T) {
+	src := &fakeProductSource{products: []*ProductDetails{
+		{ProductID: 1, BaseCurrency: "ETH", QuotedCurrency: "BTC", CurrencyPairCode: "ETHBTC"},
+		{ProductID: 56, BaseCurrency: "VZT", QuotedCurrency: "BTC", CurrencyPairCode: "VZTBTC"},
+	}}
+	now := time.Unix(1500000000, 0)
+	c := NewCatalog(src, time.Minute)
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if p, err := c.Product(ctx, 56); err != nil || p.CurrencyPairCode != "VZTBTC" {
+		t.Errorf("Unexpected product by ID: %+v; Error: %v", p, err)
+	}
+	if p, err := c.ProductByPair(ctx, "ETHBTC"); err != nil || p.ProductID != 1 {
+		t.Errorf("Unexpected product by pair: %+v; Error: %v", p, err)
+	}
+	if p, err := c.ProductFor(ctx, "VZT", "BTC"); err != nil || p.ProductID != 56 {
+		t.Errorf("Unexpected product by currencies: %+v; Error: %v", p, err)
+	}
+	if _, err := c.ProductByPair(ctx, "XRPBTC"); err != ErrProductNotFound {
+		t.Errorf("Unexpected error. Expected: %v; Actual: %v.", ErrProductNotFound, err)
+	}
+	if pairCode := c.PairCode(1); pairCode != "ETHBTC" {
+		t.Errorf("Unexpected pair code. Expected: ETHBTC; Actual: %s.", pairCode)
+	}
+
+	if src.fetches != 1 {
+		t.Errorf("Unexpected fetch count within the TTL. Expected: 1; Actual: %d.", src.fetches)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := c.Products(ctx); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if src.fetches != 2 {
+		t.Errorf("Unexpected fetch count after the TTL. Expected: 2; Actual: %d.", src.fetches)
+	}
+}
+
+func TestCatalog_Changes(t *testing.T) {
+	src := &fakeProductSource{products: []*ProductDetails{
+		{ProductID: 1, CurrencyPairCode: "ETHBTC"},
+		{ProductID: 2, CurrencyPairCode: "LTCBTC"},
+		{ProductID: 3, CurrencyPairCode: "XMRBTC", Disabled: true},
+	}}
+	c := NewCatalog(src, time.Hour)
+	var notified []*CatalogChanges
+	c.OnChange(func(changes *CatalogChanges) { notified = append(notified, changes) })
+
+	// The first load isn't a change
+	changes, err := c.Refresh(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if !changes.Empty() {
+		t.Errorf("Unexpected changes on first load: %+v", changes)
+	}
+
+	src.set(
+		&ProductDetails{ProductID: 1, CurrencyPairCode: "ETHBTC", Disabled: true},
+		&ProductDetails{ProductID: 3, CurrencyPairCode: "XMRBTC"},
+		&ProductDetails{ProductID: 4, CurrencyPairCode: "UBTCBTC"},
+	)
+	changes, err = c.Refresh(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	for name, pair := range map[string][]*ProductDetails{
+		"ETHBTC":  changes.Disabled,
+		"XMRBTC":  changes.Enabled,
+		"UBTCBTC": changes.Added,
+		"LTCBTC":  changes.Removed,
+	} {
+		if len(pair) != 1 || pair[0].CurrencyPairCode != name {
+			t.Errorf("Unexpected change for %s: %+v", name, pair)
+		}
+	}
+	if len(notified) != 1 || notified[0] != changes {
+		t.Errorf("Unexpected notifications: %+v", notified)
+	}
+	if _, err := c.ProductByPair(context.Background(), "UBTCBTC"); err != nil {
+		t.Errorf("Expected the added product to be found: %v", err)
+	}
+}
+
+func TestCatalog_Run(t *testing.T) {
+	src := &fakeProductSource{products: []*ProductDetails{{ProductID: 1, CurrencyPairCode: "ETHBTC"}}}
+	c := NewCatalog(src, time.Hour)
+	if _, err := c.Refresh(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	added := make(chan *CatalogChanges, 1)
+	c.OnChange(func(changes *CatalogChanges) {
+		select {
+		case added <- changes:
+		default:
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx, 5*time.Millisecond)
+
+	src.set(&ProductDetails{ProductID: 1, CurrencyPairCode: "ETHBTC"}, &ProductDetails{ProductID: 2, CurrencyPairCode: "LTCBTC"})
+	select {
+	case changes := <-added:
+		if len(changes.Added) != 1 || changes.Added[0].ProductID != 2 {
+			t.Errorf("Unexpected changes: %+v", changes)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for a background refresh.")
+	}
+}
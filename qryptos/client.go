@@ -0,0 +1,244 @@
+package qryptos
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+const defaultRequestTimeout = 30 * time.Second
+
+// ClientOption configures a PublicClient or PrivateClient.
+type ClientOption func(*clientConfig)
+
+type clientConfig struct {
+	httpClient  *http.Client
+	apiBaseUrl  string
+	timeout     time.Duration
+	rateLimiter *RateLimiter
+	retryPolicy RetryPolicy
+	nonceSource NonceSource
+	logOutput   io.Writer
+	stats       *clientStats
+}
+
+// WithHTTPClient sets the http.Client used to send requests. The default is http.DefaultClient.
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.httpClient = hc
+	}
+}
+
+// WithBaseURL points the client at a different API host, such as a local test server.
+func WithBaseURL(baseUrl string) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.apiBaseUrl = baseUrl
+	}
+}
+
+// WithTimeout bounds each individual request. A zero timeout leaves requests bounded only by their context.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.timeout = timeout
+	}
+}
+
+// WithRateLimiter makes the client share the given limiter. Pass the same limiter to every client using an API
+// token. A nil limiter disables client-side throttling.
+func WithRateLimiter(l *RateLimiter) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.rateLimiter = l
+	}
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy.
+func WithRetryPolicy(p RetryPolicy) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.retryPolicy = p
+	}
+}
+
+// WithNonceSource signs a PrivateClient's requests with nonces from src instead of the token's shared source.
+func WithNonceSource(src NonceSource) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.nonceSource = src
+	}
+}
+
+// WithLogOutput sends the client's progress and retry messages to w instead of stdout. Use ioutil.Discard to silence
+// them.
+func WithLogOutput(w io.Writer) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.logOutput = w
+	}
+}
+
+func newClientConfig(opts []ClientOption) clientConfig {
+	cfg := clientConfig{
+		httpClient:  http.DefaultClient,
+		apiBaseUrl:  qryptosApiBaseUrl,
+		timeout:     defaultRequestTimeout,
+		rateLimiter: DefaultRateLimiter,
+		retryPolicy: DefaultRetryPolicy,
+		logOutput:   os.Stdout,
+		stats:       &clientStats{},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg
+}
+
+// apiRequest describes a call to the exchange. The http.Request is rebuilt from it on every attempt so that each
+// attempt is signed afresh.
+type apiRequest struct {
+	method string
+	path   string
+	query  url.Values
+	body   []byte
+
+	// idempotent marks requests that are safe to retry. GETs are always treated as idempotent.
+	idempotent bool
+}
+
+func (r *apiRequest) retryable() bool {
+	return r.idempotent || r.method == http.MethodGet
+}
+
+type apiResponse struct {
+	statusCode int
+	body       []byte
+}
+
+func (cfg *clientConfig) buildRequest(ctx context.Context, r *apiRequest) (*http.Request, error) {
+	endpoint := cfg.apiBaseUrl + r.path
+	if len(r.query) > 0 {
+		endpoint += "?" + r.query.Encode()
+	}
+
+	var body io.Reader
+	if r.body != nil {
+		body = bytes.NewReader(r.body)
+	}
+
+	req, err := http.NewRequest(r.method, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return req.WithContext(ctx), nil
+}
+
+// Stats reports request, retry and throttle counts since the client was created.
+func (cfg *clientConfig) Stats() ClientStats {
+	if cfg.stats == nil {
+		return ClientStats{}
+	}
+	return cfg.stats.snapshot()
+}
+
+// send performs a request, waiting on the rate limiter before each attempt and retrying idempotent requests
+// according to the retry policy. The sign func, if given, is applied to each attempt before it is sent. A signed
+// request rejected for its nonce is retried once straight away, whatever its method, since the exchange didn't act on
+// it. Any non-2xx response is returned as an *APIError.
+func (cfg *clientConfig) send(ctx context.Context, r *apiRequest, sign func(*http.Request) error) (*apiResponse, error) {
+	stats := cfg.stats
+	if stats == nil {
+		stats = &clientStats{}
+	}
+
+	nonceRetried := false
+	for attempt := 1; ; attempt++ {
+		if cfg.rateLimiter != nil {
+			throttled, err := cfg.rateLimiter.Wait(ctx)
+			if throttled {
+				atomic.AddUint64(&stats.throttled, 1)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		atomic.AddUint64(&stats.requests, 1)
+		res, retryable, err := cfg.sendOnce(ctx, r, sign)
+		if err == nil {
+			return res, nil
+		}
+		if sign != nil && !nonceRetried && IsInvalidNonce(err) && ctx.Err() == nil {
+			fmt.Fprintf(cfg.logOutput, "[send] Retrying %s %s with a new nonce: %s\n", r.method, r.path, err.Error())
+			atomic.AddUint64(&stats.retries, 1)
+			nonceRetried = true
+			attempt--
+			continue
+		}
+		if !retryable || !r.retryable() || attempt >= cfg.retryPolicy.MaxAttempts || ctx.Err() != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(cfg.logOutput, "[send] Retrying %s %s after error: %s\n", r.method, r.path, err.Error())
+		atomic.AddUint64(&stats.retries, 1)
+
+		timer := time.NewTimer(cfg.retryPolicy.backoff(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		}
+	}
+}
+
+// sendOnce performs a single attempt. The returned bool reports whether the failure is worth retrying.
+func (cfg *clientConfig) sendOnce(ctx context.Context, r *apiRequest, sign func(*http.Request) error) (*apiResponse, bool, error) {
+	if cfg.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.timeout)
+		defer cancel()
+	}
+
+	req, err := cfg.buildRequest(ctx, r)
+	if err != nil {
+		return nil, false, err
+	}
+
+	req.Header.Set("X-Quoine-API-Version", "2")
+	if sign != nil {
+		if err := sign(req); err != nil {
+			return nil, false, err
+		}
+	}
+
+	hc := cfg.httpClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+
+	res, err := hc.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, true, err
+	}
+
+	apiRes := &apiResponse{
+		statusCode: res.StatusCode,
+		body:       body,
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, isRetryableStatus(res.StatusCode), newAPIError(r, apiRes)
+	}
+
+	return apiRes, false, nil
+}
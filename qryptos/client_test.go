@@ -0,0 +1,170 @@
+package qryptos
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// Tests make far more requests than the exchange's limit allows; limiter behaviour is tested explicitly below
+	DefaultRateLimiter = nil
+
+	os.Exit(m.Run())
+}
+
+var fastRetries = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Millisecond,
+	MaxDelay:    5 * time.Millisecond,
+}
+
+func TestPrivateClient_FetchAccountBalances_Retry(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write([]byte(`[{"currency": "BTC", "balance": "0.04925688"}]`))
+	}))
+	defer ts.Close()
+
+	client := NewPrivateClient("123456", "secret", WithBaseURL(ts.URL), WithRetryPolicy(fastRetries))
+
+	balances, err := client.FetchAccountBalances()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if len(balances) != 1 || balances[0].Balance != Amount(4925688) {
+		t.Errorf("Unexpected balances: %+v", balances)
+	}
+
+	stats := client.Stats()
+	if stats.Requests != 3 || stats.Retries != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestPrivateClient_CreateLimitOrder_NoRetry(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	client := NewPrivateClient("123456", "secret", WithBaseURL(ts.URL), WithRetryPolicy(fastRetries))
+
+	if _, err := client.CreateLimitOrder(4, OrderSideBuy, Amount(100000000), Amount(4754)); err == nil {
+		t.Fatal("Expected an error.")
+	}
+	if actual := atomic.LoadInt32(&calls); actual != 1 {
+		t.Errorf("Expected exactly one attempt; Actual: %d.", actual)
+	}
+}
+
+func TestPrivateClient_CancelOrder_RetryRateLimited(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	client := NewPrivateClient("123456", "secret", WithBaseURL(ts.URL), WithRetryPolicy(fastRetries))
+
+	if err := client.CancelOrder(983487134); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if stats := client.Stats(); stats.Retries != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	limiter := NewRateLimiter(100, 2)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if throttled, err := limiter.Wait(ctx); throttled || err != nil {
+			t.Fatalf("Expected burst request %d to pass immediately. Throttled: %t; Error: %v.", i, throttled, err)
+		}
+	}
+
+	start := time.Now()
+	throttled, err := limiter.Wait(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if !throttled {
+		t.Error("Expected the third request to be throttled.")
+	}
+	if elapsed := time.Since(start); elapsed < 5*time.Millisecond {
+		t.Errorf("Expected to wait for a token. Waited: %s.", elapsed)
+	}
+}
+
+func TestRateLimiter_Wait_Cancelled(t *testing.T) {
+	limiter := NewRateLimiter(0.001, 1)
+	limiter.Wait(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := limiter.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded; Actual: %v.", err)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for retry, max := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 5: time.Second} {
+		delay := policy.backoff(retry)
+		if delay < max/2 || delay > max {
+			t.Errorf("Unexpected backoff for retry %d: %s", retry, delay)
+		}
+	}
+}
+
+func TestPrivateClient_CreateLimitOrder_RetryInvalidNonce(t *testing.T) {
+	var calls, rejections int32 = 0, 1
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= atomic.LoadInt32(&rejections) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code": "invalid_nonce", "message": "Nonce is too small"}`))
+			return
+		}
+		w.Write([]byte(`{"id": 2157479}`))
+	}))
+	defer ts.Close()
+
+	client := NewPrivateClient("123456", "secret", WithBaseURL(ts.URL), WithRetryPolicy(NoRetries))
+
+	orderId, err := client.CreateLimitOrder(4, OrderSideBuy, Amount(100000000), Amount(4754))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if orderId != 2157479 {
+		t.Errorf("Unexpected order ID. Expected: 2157479; Actual: %d.", orderId)
+	}
+
+	// A second rejection in a row is returned rather than retried again
+	atomic.StoreInt32(&calls, 0)
+	atomic.StoreInt32(&rejections, 2)
+	if _, err := client.CreateLimitOrder(4, OrderSideBuy, Amount(100000000), Amount(4754)); !IsInvalidNonce(err) {
+		t.Errorf("Expected an invalid nonce error; Actual: %v.", err)
+	}
+	if actual := atomic.LoadInt32(&calls); actual != 2 {
+		t.Errorf("Unexpected attempts. Expected: 2; Actual: %d.", actual)
+	}
+}
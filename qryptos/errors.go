@@ -0,0 +1,129 @@
+package qryptos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	errorCodeInsufficientFunds = "not_enough_free_balance"
+	errorCodeInvalidNonce      = "invalid_nonce"
+)
+
+// APIError is returned for any non-2xx response from the exchange.
+type APIError struct {
+	Method     string
+	Endpoint   string
+	StatusCode int
+
+	// Code and Message are populated from the exchange's error body when it provides them.
+	Code    string
+	Message string
+
+	// Errors holds field-level validation errors, such as {"quantity": ["less_than_order_size"]}.
+	Errors map[string][]string
+
+	Body []byte
+}
+
+func (e *APIError) Error() string {
+	detail := e.Message
+	if detail == "" && len(e.Errors) > 0 {
+		fields := make([]string, 0, len(e.Errors))
+		for field, msgs := range e.Errors {
+			fields = append(fields, fmt.Sprintf("%s: %s", field, strings.Join(msgs, ", ")))
+		}
+		sort.Strings(fields)
+		detail = strings.Join(fields, "; ")
+	}
+	if detail == "" {
+		detail = http.StatusText(e.StatusCode)
+	}
+
+	return fmt.Sprintf("qryptos: %s %s: status %d: %s", e.Method, e.Endpoint, e.StatusCode, detail)
+}
+
+// hasCode reports whether code appears as the error code, the message or any field-level error.
+func (e *APIError) hasCode(code string) bool {
+	if e.Code == code || strings.Contains(strings.ToLower(e.Message), code) {
+		return true
+	}
+	for _, msgs := range e.Errors {
+		for _, msg := range msgs {
+			if msg == code {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+type errorResponse struct {
+	Code    string          `json:"code"`
+	Message string          `json:"message"`
+	Errors  json.RawMessage `json:"errors"`
+}
+
+func newAPIError(r *apiRequest, res *apiResponse) *APIError {
+	apiErr := &APIError{
+		Method:     r.method,
+		Endpoint:   r.path,
+		StatusCode: res.statusCode,
+		Body:       res.body,
+	}
+
+	var parsed errorResponse
+	if err := json.Unmarshal(res.body, &parsed); err != nil {
+		return apiErr
+	}
+	apiErr.Code = parsed.Code
+	apiErr.Message = parsed.Message
+
+	// The exchange sends field errors either as a map of lists or, occasionally, as a bare list
+	var fieldErrors map[string][]string
+	var listErrors []string
+	if err := json.Unmarshal(parsed.Errors, &fieldErrors); err == nil {
+		apiErr.Errors = fieldErrors
+	} else if err := json.Unmarshal(parsed.Errors, &listErrors); err == nil && len(listErrors) > 0 {
+		apiErr.Errors = map[string][]string{"base": listErrors}
+	}
+
+	return apiErr
+}
+
+// asAPIError finds the *APIError in err's chain, so callers that wrap errors get the same answers from the Is*
+// predicates as those that don't.
+func asAPIError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	ok := errors.As(err, &apiErr)
+	return apiErr, ok
+}
+
+// IsRateLimited reports whether the exchange rejected the request for exceeding its rate limit.
+func IsRateLimited(err error) bool {
+	apiErr, ok := asAPIError(err)
+	return ok && apiErr.StatusCode == http.StatusTooManyRequests
+}
+
+// IsInsufficientFunds reports whether an order was rejected because the account lacks free balance.
+func IsInsufficientFunds(err error) bool {
+	apiErr, ok := asAPIError(err)
+	return ok && (apiErr.hasCode(errorCodeInsufficientFunds) || apiErr.hasCode("insufficient"))
+}
+
+// IsOrderNotFound reports whether the order referenced by the request does not exist.
+func IsOrderNotFound(err error) bool {
+	apiErr, ok := asAPIError(err)
+	return ok && apiErr.StatusCode == http.StatusNotFound && strings.HasPrefix(apiErr.Endpoint, endpointOrders)
+}
+
+// IsInvalidNonce reports whether the request signature was rejected because of its nonce.
+func IsInvalidNonce(err error) bool {
+	apiErr, ok := asAPIError(err)
+	return ok && apiErr.StatusCode == http.StatusUnauthorized && (apiErr.hasCode(errorCodeInvalidNonce) || apiErr.hasCode("nonce"))
+}
@@ -0,0 +1,74 @@
+package qryptos
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIError_Predicates(t *testing.T) {
+	cases := []struct {
+		name          string
+		method        string
+		path          string
+		status        int
+		body          string
+		predicate     func(error) bool
+		predicateName string
+	}{
+		{"rate limited", http.MethodGet, "/orders", 429, ``, IsRateLimited, "IsRateLimited"},
+		{"insufficient funds", http.MethodPost, "/orders", 422, `{"errors":{"user":["not_enough_free_balance"]}}`, IsInsufficientFunds, "IsInsufficientFunds"},
+		{"order not found", http.MethodPut, "/orders/123/cancel", 404, `{"message":"Order not found"}`, IsOrderNotFound, "IsOrderNotFound"},
+		{"invalid nonce", http.MethodGet, "/accounts/balance", 401, `{"message":"invalid_nonce"}`, IsInvalidNonce, "IsInvalidNonce"},
+	}
+
+	for _, c := range cases {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(c.status)
+			w.Write([]byte(c.body))
+		}))
+
+		client := NewPrivateClient("123456", "secret", WithBaseURL(ts.URL), WithRetryPolicy(NoRetries))
+		_, err := client.send(context.Background(), &apiRequest{method: c.method, path: c.path}, client.signRequest)
+		ts.Close()
+
+		apiErr, ok := err.(*APIError)
+		if !ok {
+			t.Errorf("[%s] Expected *APIError; Actual: %v.", c.name, err)
+			continue
+		}
+		if apiErr.StatusCode != c.status || apiErr.Method != c.method || apiErr.Endpoint != c.path {
+			t.Errorf("[%s] Unexpected error details: %+v", c.name, apiErr)
+		}
+		if !c.predicate(err) {
+			t.Errorf("[%s] Expected %s to be true for: %s", c.name, c.predicateName, err.Error())
+		}
+		if wrapped := fmt.Errorf("fetching orders: %w", err); !c.predicate(wrapped) {
+			t.Errorf("[%s] Expected %s to be true for: %s", c.name, c.predicateName, wrapped.Error())
+		}
+	}
+}
+
+func TestPrivateClient_FetchOrders_ErrorStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message":"API token is invalid"}`))
+	}))
+	defer ts.Close()
+
+	client := NewPrivateClient("123456", "secret", WithBaseURL(ts.URL))
+	_, err := client.FetchOrders()
+
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		t.Fatalf("Expected *APIError; Actual: %v.", err)
+	}
+	if expected := "API token is invalid"; apiErr.Message != expected {
+		t.Errorf("Unexpected message. Expected: %s; Actual: %s.", expected, apiErr.Message)
+	}
+	if IsInvalidNonce(err) {
+		t.Error("Did not expect an invalid nonce error.")
+	}
+}
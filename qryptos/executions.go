@@ -0,0 +1,237 @@
+package qryptos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	endpointMyExecutions = "/executions/me"
+
+	defaultExecutionsPageSize = 100
+)
+
+var ErrProductRequired = errors.New("product ID is required")
+
+// Execution is one of our own fills, as reported by the account execution history.
+type Execution struct {
+	ID        int
+	OrderID   int
+	ProductID int
+	Side      string
+	Price     Amount
+	Quantity  Amount
+	Fee       Amount
+	Taker     bool
+	CreatedAt time.Time
+}
+
+// ExecutionsQuery filters FetchMyExecutions. ProductID is required by the exchange; the time range is applied by
+// the client.
+type ExecutionsQuery struct {
+	ProductID int
+	Since     time.Time
+	Until     time.Time
+	PageSize  int
+}
+
+func (q *ExecutionsQuery) pageSize() int {
+	if q.PageSize <= 0 {
+		return defaultExecutionsPageSize
+	}
+	return q.PageSize
+}
+
+func (q *ExecutionsQuery) values(page int) url.Values {
+	v := url.Values{}
+	v.Set("product_id", strconv.Itoa(q.ProductID))
+	v.Set("limit", strconv.Itoa(q.pageSize()))
+	v.Set("page", strconv.Itoa(page))
+
+	return v
+}
+
+func (q *ExecutionsQuery) matches(e *Execution) bool {
+	if !q.Since.IsZero() && e.CreatedAt.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !e.CreatedAt.Before(q.Until) {
+		return false
+	}
+
+	return true
+}
+
+type myExecutionsResponse struct {
+	Models      []*myExecutionResponse `json:"models"`
+	CurrentPage int                    `json:"current_page"`
+	TotalPages  int                    `json:"total_pages"`
+}
+
+type myExecutionResponse struct {
+	ID        int    `json:"id"`
+	OrderID   int    `json:"order_id"`
+	ProductID int    `json:"product_id"`
+	Quantity  Amount `json:"quantity"`
+	Price     Amount `json:"price"`
+	Fee       Amount `json:"fee"`
+	TakerSide string `json:"taker_side"`
+	MySide    string `json:"my_side"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// ExecutionsPage is a single page of executions, already filtered by the query's time range.
+type ExecutionsPage struct {
+	Executions  []*Execution
+	CurrentPage int
+	TotalPages  int
+
+	exhausted bool
+}
+
+// HasMore reports whether another page may contain matching executions.
+func (p *ExecutionsPage) HasMore() bool {
+	return !p.exhausted && p.CurrentPage < p.TotalPages
+}
+
+func (p *ExecutionsPage) number() int {
+	return p.CurrentPage
+}
+
+func (p *ExecutionsPage) size() int {
+	return len(p.Executions)
+}
+
+func (c *PrivateClient) FetchMyExecutionsPage(q *ExecutionsQuery, page int) (*ExecutionsPage, error) {
+	return c.FetchMyExecutionsPageContext(context.Background(), q, page)
+}
+
+// FetchMyExecutionsPageContext fetches one page of the account's executions, starting from page 1.
+func (c *PrivateClient) FetchMyExecutionsPageContext(ctx context.Context, q *ExecutionsQuery, page int) (*ExecutionsPage, error) {
+	if q.ProductID == 0 {
+		return nil, ErrProductRequired
+	}
+
+	res, err := c.send(ctx, &apiRequest{
+		method: http.MethodGet,
+		path:   endpointMyExecutions,
+		query:  q.values(page),
+	}, c.signRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsedResponse myExecutionsResponse
+	if err := json.Unmarshal(res.body, &parsedResponse); err != nil {
+		return nil, err
+	}
+
+	out := &ExecutionsPage{
+		Executions:  make([]*Execution, 0, len(parsedResponse.Models)),
+		CurrentPage: parsedResponse.CurrentPage,
+		TotalPages:  parsedResponse.TotalPages,
+	}
+	if out.CurrentPage == 0 {
+		out.CurrentPage = page
+	}
+	if out.TotalPages == 0 {
+		out.TotalPages = page
+		if len(parsedResponse.Models) >= q.pageSize() {
+			out.TotalPages = page + 1
+		}
+	}
+	if len(parsedResponse.Models) == 0 {
+		out.exhausted = true
+	}
+
+	for _, model := range parsedResponse.Models {
+		execution := parseMyExecution(model, q.ProductID)
+
+		// Executions are listed newest first, so nothing after this one can fall inside the range
+		if pastStart(execution.CreatedAt, q.Since) {
+			out.exhausted = true
+		}
+
+		if q.matches(execution) {
+			out.Executions = append(out.Executions, execution)
+		}
+	}
+
+	return out, nil
+}
+
+// parseMyExecution falls back to productId when the exchange leaves it out of the model.
+func parseMyExecution(model *myExecutionResponse, productId int) *Execution {
+	if model.ProductID != 0 {
+		productId = model.ProductID
+	}
+
+	return &Execution{
+		ID:        model.ID,
+		OrderID:   model.OrderID,
+		ProductID: productId,
+		Side:      model.MySide,
+		Price:     model.Price,
+		Quantity:  model.Quantity,
+		Fee:       model.Fee,
+		Taker:     model.MySide != "" && model.MySide == model.TakerSide,
+		CreatedAt: parseTimestamp(model.CreatedAt),
+	}
+}
+
+// ExecutionIterator walks every page of the account's executions matching a query. It is used the same way as
+// OrderIterator.
+type ExecutionIterator struct {
+	pageIterator
+
+	current *Execution
+}
+
+func (c *PrivateClient) IterateMyExecutions(ctx context.Context, q *ExecutionsQuery) *ExecutionIterator {
+	return &ExecutionIterator{
+		pageIterator: pageIterator{
+			fetch: func(page int) (resultPage, error) {
+				return c.FetchMyExecutionsPageContext(ctx, q, page)
+			},
+		},
+	}
+}
+
+func (it *ExecutionIterator) Next() bool {
+	i, ok := it.advance()
+	if !ok {
+		return false
+	}
+
+	it.current = it.page.(*ExecutionsPage).Executions[i]
+
+	return true
+}
+
+func (it *ExecutionIterator) Execution() *Execution {
+	return it.current
+}
+
+// FetchMyExecutions collects every execution matching the query across all pages.
+func (c *PrivateClient) FetchMyExecutions(q *ExecutionsQuery) ([]*Execution, error) {
+	return c.FetchMyExecutionsContext(context.Background(), q)
+}
+
+func (c *PrivateClient) FetchMyExecutionsContext(ctx context.Context, q *ExecutionsQuery) ([]*Execution, error) {
+	var out []*Execution
+
+	it := c.IterateMyExecutions(ctx, q)
+	for it.Next() {
+		out = append(out, it.Execution())
+	}
+	if err := it.Err(); err != nil {
+		return []*Execution{}, err
+	}
+
+	return out, nil
+}
@@ -0,0 +1,95 @@
+package qryptos
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPrivateClient_FetchMyExecutions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if urlPath := r.URL.Path; urlPath != "/executions/me" {
+			t.Errorf("Unexpected request path: %s", urlPath)
+		}
+		if productId := r.URL.Query().Get("product_id"); productId != "56" {
+			t.Errorf("Unexpected product filter: %s", productId)
+		}
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		fmt.Fprintf(w, `
+{
+	"models": [
+		{"id": %d, "order_id": 983487134, "quantity": "13.5", "price": "0.00010366", "fee": "0.00000021", "taker_side": "buy", "my_side": "sell", "created_at": %d},
+		{"id": %d, "order_id": 148797141, "quantity": "2.0", "price": "0.00004754", "taker_side": "buy", "my_side": "buy", "created_at": %d}
+	],
+	"current_page": %d,
+	"total_pages": 2
+}`, page*10+1, 1516007675-page*100, page*10+2, 1516007675-page*100-50, page)
+	}))
+	defer ts.Close()
+
+	client := NewPrivateClient("123456", "secret", WithBaseURL(ts.URL))
+
+	executions, err := client.FetchMyExecutions(&ExecutionsQuery{
+		ProductID: 56,
+		Since:     time.Unix(1516007675-220, 0),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	if len(executions) != 3 {
+		t.Fatalf("Unexpected execution count. Expected: 3; Actual: %d.", len(executions))
+	}
+
+	first := executions[0]
+	if first.ID != 11 || first.OrderID != 983487134 || first.ProductID != 56 {
+		t.Errorf("Unexpected execution: %+v", first)
+	}
+	if first.Side != OrderSideSell || first.Taker {
+		t.Errorf("Expected a maker sell. Side: %s; Taker: %t.", first.Side, first.Taker)
+	}
+	if expected := Amount(21); first.Fee != expected {
+		t.Errorf("Unexpected fee. Expected: %d; Actual: %d.", expected, first.Fee)
+	}
+	if !executions[1].Taker {
+		t.Error("Expected a taker execution.")
+	}
+}
+
+func TestPrivateClient_FetchMyExecutions_MissingTimestamp(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		createdAt := 0
+		if r.URL.Query().Get("page") == "2" {
+			createdAt = 1516007675
+		}
+		fmt.Fprintf(w, `{"models": [{"id": 1, "quantity": "1.0", "price": "0.0001", "created_at": %d}], "current_page": %s, "total_pages": 2}`,
+			createdAt, r.URL.Query().Get("page"))
+	}))
+	defer ts.Close()
+
+	client := NewPrivateClient("123456", "secret", WithBaseURL(ts.URL))
+
+	// An execution without a timestamp mustn't stop the walk before the one on the next page
+	executions, err := client.FetchMyExecutions(&ExecutionsQuery{
+		ProductID: 56,
+		Since:     time.Unix(1516007675-100, 0),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if len(executions) != 1 {
+		t.Fatalf("Unexpected execution count. Expected: 1; Actual: %d.", len(executions))
+	}
+}
+
+func TestPrivateClient_FetchMyExecutions_ProductRequired(t *testing.T) {
+	client := NewPrivateClient("123456", "secret", WithBaseURL("http://127.0.0.1:0"))
+
+	if _, err := client.FetchMyExecutions(&ExecutionsQuery{}); err != ErrProductRequired {
+		t.Errorf("Expected ErrProductRequired; Actual: %v.", err)
+	}
+}
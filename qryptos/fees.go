@@ -0,0 +1,85 @@
+package qryptos
+
+import "errors"
+
+var ErrFeeTooHigh = errors.New("fee rate leaves nothing of the trade")
+
+// Fees are charged in the quoted currency as a fraction of the traded value, so Amount(100000) is 0.1%. Orders that
+// rest on the book pay the maker rate and orders that take liquidity pay the taker rate.
+type Fees struct {
+	Maker Amount
+	Taker Amount
+}
+
+// Fees returns the product's fee rates.
+func (p *ProductDetails) Fees() Fees {
+	return Fees{Maker: p.MakerFee, Taker: p.TakerFee}
+}
+
+// Rate returns the taker rate for taker trades and the maker rate otherwise.
+func (f Fees) Rate(taker bool) Amount {
+	if taker {
+		return f.Taker
+	}
+	return f.Maker
+}
+
+// Fee is the fee on a trade of quantity at price, in the quoted currency. It rounds up, as the exchange does.
+func (f Fees) Fee(quantity, price Amount, taker bool) (Amount, error) {
+	value, err := quantity.Multiply(price, RoundCeil)
+	if err != nil {
+		return AmountZero, err
+	}
+
+	return value.Multiply(f.Rate(taker), RoundCeil)
+}
+
+// BuyCost is what buying quantity at price costs, fee included.
+func (f Fees) BuyCost(quantity, price Amount, taker bool) (Amount, error) {
+	value, err := quantity.Multiply(price, RoundCeil)
+	if err != nil {
+		return AmountZero, err
+	}
+	fee, err := value.Multiply(f.Rate(taker), RoundCeil)
+	if err != nil {
+		return AmountZero, err
+	}
+
+	return value + fee, nil
+}
+
+// SellProceeds is what selling quantity at price returns, after the fee.
+func (f Fees) SellProceeds(quantity, price Amount, taker bool) (Amount, error) {
+	value, err := quantity.Multiply(price, RoundFloor)
+	if err != nil {
+		return AmountZero, err
+	}
+	fee, err := value.Multiply(f.Rate(taker), RoundCeil)
+	if err != nil {
+		return AmountZero, err
+	}
+
+	return value - fee, nil
+}
+
+// MinimumSellPrice is the lowest price at which something bought at buyPrice can be sold for at least margin more
+// than it cost, once the fees on both trades are paid. margin is a fraction like the fee rates, so Amount(500000)
+// asks for 0.5% on top of breaking even. The result is rounded up; quantize it to the product's tick with
+// QuantizePrice, which rounds sells up as well.
+func (f Fees) MinimumSellPrice(buyPrice, margin Amount, buyTaker, sellTaker bool) (Amount, error) {
+	keep := AmountRatio - f.Rate(sellTaker)
+	if keep <= AmountZero {
+		return AmountZero, ErrFeeTooHigh
+	}
+
+	cost, err := buyPrice.Multiply(AmountRatio+f.Rate(buyTaker), RoundCeil)
+	if err != nil {
+		return AmountZero, err
+	}
+	target, err := cost.Multiply(AmountRatio+margin, RoundCeil)
+	if err != nil {
+		return AmountZero, err
+	}
+
+	return target.Divide(keep, RoundCeil)
+}
@@ -0,0 +1,63 @@
+package qryptos
+
+import "testing"
+
+func TestFees_Fee(t *testing.T) {
+	fees := Fees{Maker: Amount(100000), Taker: Amount(200000)}
+
+	// 10 VZT at 0.0001 BTC is 0.001 BTC
+	quantity, price := Amount(1000000000), Amount(10000)
+	if fee, err := fees.Fee(quantity, price, false); err != nil || fee != Amount(100) {
+		t.Errorf("Unexpected maker fee. Expected: %d; Actual: %d; Error: %v.", 100, fee, err)
+	}
+	if fee, err := fees.Fee(quantity, price, true); err != nil || fee != Amount(200) {
+		t.Errorf("Unexpected taker fee. Expected: %d; Actual: %d; Error: %v.", 200, fee, err)
+	}
+	if cost, err := fees.BuyCost(quantity, price, false); err != nil || cost != Amount(100100) {
+		t.Errorf("Unexpected buy cost. Expected: %d; Actual: %d; Error: %v.", 100100, cost, err)
+	}
+	if proceeds, err := fees.SellProceeds(quantity, price, true); err != nil || proceeds != Amount(99800) {
+		t.Errorf("Unexpected sell proceeds. Expected: %d; Actual: %d; Error: %v.", 99800, proceeds, err)
+	}
+}
+
+func TestFees_MinimumSellPrice(t *testing.T) {
+	testCases := []struct {
+		fees     Fees
+		margin   Amount
+		expected Amount
+	}{
+		// No fees and no margin is breaking even
+		{Fees{}, AmountZero, Amount(10000000)},
+		// 0.1% each way: 0.1 * 1.001 / 0.999
+		{Fees{Maker: Amount(100000)}, AmountZero, Amount(10020021)},
+		// Plus a 0.5% margin
+		{Fees{Maker: Amount(100000)}, Amount(500000), Amount(10070121)},
+		// A maker rebate lowers the bar
+		{Fees{Maker: Amount(-25000)}, AmountZero, Amount(9995002)},
+	}
+
+	for _, tc := range testCases {
+		actual, err := tc.fees.MinimumSellPrice(Amount(10000000), tc.margin, false, false)
+		if err != nil {
+			t.Errorf("Unexpected error for %+v: %s", tc.fees, err.Error())
+			continue
+		}
+		if actual != tc.expected {
+			t.Errorf("Unexpected minimum sell price for %+v. Expected: %s; Actual: %s.", tc.fees, tc.expected, actual)
+		}
+
+		// Selling there must recover the cost plus the margin
+		fees := tc.fees
+		cost, _ := fees.BuyCost(AmountRatio, Amount(10000000), false)
+		proceeds, _ := fees.SellProceeds(AmountRatio, actual, false)
+		target, _ := cost.Multiply(AmountRatio+tc.margin, RoundCeil)
+		if proceeds < target {
+			t.Errorf("Selling at %s doesn't cover %s for %+v.", actual, target, tc.fees)
+		}
+	}
+
+	if _, err := (Fees{Maker: AmountRatio}).MinimumSellPrice(Amount(10000000), AmountZero, false, false); err != ErrFeeTooHigh {
+		t.Errorf("Unexpected error. Expected: %v; Actual: %v.", ErrFeeTooHigh, err)
+	}
+}
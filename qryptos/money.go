@@ -0,0 +1,91 @@
+package qryptos
+
+import "fmt"
+
+// Money is an Amount tagged with the currency it is denominated in.
+type Money struct {
+	Amount   Amount
+	Currency string
+}
+
+// Price is the number of units of Quote currency paid for one unit of Base currency.
+type Price struct {
+	Amount Amount
+	Base   string
+	Quote  string
+}
+
+// CurrencyMismatchError is returned when an operation is attempted on values of different currencies.
+type CurrencyMismatchError struct {
+	Op       string
+	Expected string
+	Actual   string
+}
+
+func (e *CurrencyMismatchError) Error() string {
+	return fmt.Sprintf("currency mismatch in %s: expected %s; got %s", e.Op, e.Expected, e.Actual)
+}
+
+func NewMoney(amount Amount, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, &CurrencyMismatchError{Op: "Add", Expected: m.Currency, Actual: o.Currency}
+	}
+
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}, nil
+}
+
+func (m Money) Sub(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, &CurrencyMismatchError{Op: "Sub", Expected: m.Currency, Actual: o.Currency}
+	}
+
+	return Money{Amount: m.Amount - o.Amount, Currency: m.Currency}, nil
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == AmountZero
+}
+
+func (m Money) String() string {
+	return fmt.Sprintf("%s %s", m.Amount, m.Currency)
+}
+
+func NewPrice(amount Amount, base, quote string) Price {
+	return Price{Amount: amount, Base: base, Quote: quote}
+}
+
+// Convert values an amount of the base currency in the quote currency.
+func (p Price) Convert(base Money, mode RoundingMode) (Money, error) {
+	if base.Currency != p.Base {
+		return Money{}, &CurrencyMismatchError{Op: "Convert", Expected: p.Base, Actual: base.Currency}
+	}
+
+	amt, err := base.Amount.Multiply(p.Amount, mode)
+	if err != nil {
+		return Money{}, err
+	}
+
+	return Money{Amount: amt, Currency: p.Quote}, nil
+}
+
+// ConvertToBase computes how much of the base currency an amount of the quote currency buys.
+func (p Price) ConvertToBase(quote Money, mode RoundingMode) (Money, error) {
+	if quote.Currency != p.Quote {
+		return Money{}, &CurrencyMismatchError{Op: "ConvertToBase", Expected: p.Quote, Actual: quote.Currency}
+	}
+
+	amt, err := quote.Amount.Divide(p.Amount, mode)
+	if err != nil {
+		return Money{}, err
+	}
+
+	return Money{Amount: amt, Currency: p.Base}, nil
+}
+
+func (p Price) String() string {
+	return fmt.Sprintf("%s %s/%s", p.Amount, p.Quote, p.Base)
+}
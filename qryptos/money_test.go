@@ -0,0 +1,54 @@
+package qryptos
+
+import "testing"
+
+func TestMoney_Add(t *testing.T) {
+	sum, err := NewMoney(Amount(150000000), "BTC").Add(NewMoney(Amount(50000000), "BTC"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	expected := NewMoney(Amount(200000000), "BTC")
+	if sum != expected {
+		t.Errorf("Unexpected sum. Expected: %s; Actual: %s.", expected, sum)
+	}
+}
+
+func TestMoney_Sub_CurrencyMismatch(t *testing.T) {
+	_, err := NewMoney(Amount(150000000), "BTC").Sub(NewMoney(Amount(50000000), "ETH"))
+	if _, ok := err.(*CurrencyMismatchError); !ok {
+		t.Errorf("Expected CurrencyMismatchError; Actual: %v.", err)
+	}
+}
+
+func TestPrice_Convert(t *testing.T) {
+	price := NewPrice(Amount(4754), "VZT", "BTC")
+
+	value, err := price.Convert(NewMoney(Amount(23180680000), "VZT"), RoundFloor)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	expected := NewMoney(Amount(1102009), "BTC")
+	if value != expected {
+		t.Errorf("Unexpected value. Expected: %s; Actual: %s.", expected, value)
+	}
+
+	if _, err := price.Convert(NewMoney(Amount(100000000), "BTC"), RoundFloor); err == nil {
+		t.Error("Expected an error converting quote currency as base.")
+	}
+}
+
+func TestPrice_ConvertToBase(t *testing.T) {
+	price := NewPrice(Amount(5000000), "ETH", "BTC")
+
+	qty, err := price.ConvertToBase(NewMoney(Amount(1000000), "BTC"), RoundFloor)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	expected := NewMoney(Amount(20000000), "ETH")
+	if qty != expected {
+		t.Errorf("Unexpected quantity. Expected: %s; Actual: %s.", expected, qty)
+	}
+}
@@ -0,0 +1,33 @@
+package qryptos
+
+import "github.com/tobyjsullivan/shifty/qryptos/auth"
+
+// The nonce sources live in package auth alongside the signer that uses them. They are kept here too so that
+// callers of the clients don't need to import auth to configure nonces.
+
+// NonceSource issues the nonces that sign private requests. See auth.NonceSource.
+type NonceSource = auth.NonceSource
+
+// ClockNonceSource issues millisecond timestamps. See auth.ClockNonceSource.
+type ClockNonceSource = auth.ClockNonceSource
+
+// FileNonceSource records every nonce it issues in a file. See auth.FileNonceSource.
+type FileNonceSource = auth.FileNonceSource
+
+func NewClockNonceSource() *ClockNonceSource {
+	return auth.NewClockNonceSource()
+}
+
+func NewFileNonceSource(path string) (*FileNonceSource, error) {
+	return auth.NewFileNonceSource(path)
+}
+
+// SharedNonceSource returns the source used by every client signing with tokenId that wasn't given its own.
+func SharedNonceSource(tokenId string) NonceSource {
+	return auth.SharedNonceSource(tokenId)
+}
+
+// SetSharedNonceSource replaces the shared source for tokenId, for example with a FileNonceSource at startup.
+func SetSharedNonceSource(tokenId string, src NonceSource) {
+	auth.SetSharedNonceSource(tokenId, src)
+}
@@ -0,0 +1,134 @@
+package qryptos
+
+import "fmt"
+
+const (
+	OrderTypeLimit        = "limit"
+	OrderTypeMarket       = "market"
+	OrderTypeStop         = "stop"
+	OrderTypeStopLimit    = "stop_limit"
+	OrderTypeTrailingStop = "trailing_stop"
+
+	TrailingStopTypeFiat       = "fiat"
+	TrailingStopTypePercentage = "percentage"
+)
+
+// OrderRequest describes a new order for CreateOrder. Which fields are required depends on Type:
+//
+//	limit          Price; PostOnly is optional
+//	market         no prices
+//	stop           StopPrice
+//	stop_limit     StopPrice and Price
+//	trailing_stop  TrailingStopType and TrailingStopValue
+type OrderRequest struct {
+	ProductID int
+	Type      string
+	Side      string
+	Quantity  Amount
+
+	Price     Amount
+	StopPrice Amount
+
+	// PostOnly limit orders are rejected by the exchange rather than filled if they would cross the book.
+	PostOnly bool
+
+	// TrailingStopValue is an amount of the quoted currency for fiat trailing stops, or a percentage of the
+	// market price for percentage trailing stops.
+	TrailingStopType  string
+	TrailingStopValue Amount
+}
+
+// OrderRequestError describes why an OrderRequest was rejected before being sent.
+type OrderRequestError struct {
+	Field  string
+	Reason string
+}
+
+func (e *OrderRequestError) Error() string {
+	return fmt.Sprintf("invalid order request: %s %s", e.Field, e.Reason)
+}
+
+func invalidOrder(field, reason string) error {
+	return &OrderRequestError{Field: field, Reason: reason}
+}
+
+// Validate checks that the request is internally consistent for its order type.
+func (r *OrderRequest) Validate() error {
+	if r.ProductID <= 0 {
+		return invalidOrder("ProductID", "is required")
+	}
+	if r.Side != OrderSideBuy && r.Side != OrderSideSell {
+		return invalidOrder("Side", fmt.Sprintf("must be %q or %q", OrderSideBuy, OrderSideSell))
+	}
+	if r.Quantity <= AmountZero {
+		return invalidOrder("Quantity", "must be positive")
+	}
+	if r.PostOnly && r.Type != OrderTypeLimit {
+		return invalidOrder("PostOnly", "is only supported for limit orders")
+	}
+	if r.Type != OrderTypeTrailingStop && (r.TrailingStopType != "" || r.TrailingStopValue != AmountZero) {
+		return invalidOrder("TrailingStopType", "is only supported for trailing stop orders")
+	}
+
+	switch r.Type {
+	case OrderTypeLimit:
+		if r.Price <= AmountZero {
+			return invalidOrder("Price", "must be positive for limit orders")
+		}
+		if r.StopPrice != AmountZero {
+			return invalidOrder("StopPrice", "is not supported for limit orders")
+		}
+	case OrderTypeMarket:
+		if r.Price != AmountZero || r.StopPrice != AmountZero {
+			return invalidOrder("Price", "is not supported for market orders")
+		}
+	case OrderTypeStop:
+		if r.StopPrice <= AmountZero {
+			return invalidOrder("StopPrice", "must be positive for stop orders")
+		}
+		if r.Price != AmountZero {
+			return invalidOrder("Price", "is not supported for stop orders; use a stop limit order")
+		}
+	case OrderTypeStopLimit:
+		if r.StopPrice <= AmountZero {
+			return invalidOrder("StopPrice", "must be positive for stop limit orders")
+		}
+		if r.Price <= AmountZero {
+			return invalidOrder("Price", "must be positive for stop limit orders")
+		}
+	case OrderTypeTrailingStop:
+		if r.Price != AmountZero || r.StopPrice != AmountZero {
+			return invalidOrder("Price", "is not supported for trailing stop orders")
+		}
+		if r.TrailingStopValue <= AmountZero {
+			return invalidOrder("TrailingStopValue", "must be positive")
+		}
+		switch r.TrailingStopType {
+		case TrailingStopTypeFiat:
+		case TrailingStopTypePercentage:
+			if r.TrailingStopValue >= Amount(100*AmountRatio) {
+				return invalidOrder("TrailingStopValue", "must be less than 100 percent")
+			}
+		default:
+			return invalidOrder("TrailingStopType", fmt.Sprintf("must be %q or %q", TrailingStopTypeFiat, TrailingStopTypePercentage))
+		}
+	default:
+		return invalidOrder("Type", fmt.Sprintf("%q is not a supported order type", r.Type))
+	}
+
+	return nil
+}
+
+func (r *OrderRequest) model() *fmtCreateOrderModel {
+	return &fmtCreateOrderModel{
+		OrderType:         r.Type,
+		ProductID:         r.ProductID,
+		Side:              r.Side,
+		Quantity:          r.Quantity,
+		Price:             r.Price,
+		StopPrice:         r.StopPrice,
+		PostOnly:          r.PostOnly,
+		TrailingStopType:  r.TrailingStopType,
+		TrailingStopValue: r.TrailingStopValue,
+	}
+}
@@ -0,0 +1,106 @@
+package qryptos
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOrderRequest_Validate(t *testing.T) {
+	base := OrderRequest{ProductID: 4, Side: OrderSideSell, Quantity: Amount(100000000)}
+
+	withType := func(modify func(r *OrderRequest)) *OrderRequest {
+		r := base
+		modify(&r)
+		return &r
+	}
+
+	valid := []*OrderRequest{
+		withType(func(r *OrderRequest) { r.Type = OrderTypeLimit; r.Price = Amount(4754) }),
+		withType(func(r *OrderRequest) { r.Type = OrderTypeLimit; r.Price = Amount(4754); r.PostOnly = true }),
+		withType(func(r *OrderRequest) { r.Type = OrderTypeMarket }),
+		withType(func(r *OrderRequest) { r.Type = OrderTypeStop; r.StopPrice = Amount(4000) }),
+		withType(func(r *OrderRequest) { r.Type = OrderTypeStopLimit; r.StopPrice = Amount(4000); r.Price = Amount(3990) }),
+		withType(func(r *OrderRequest) {
+			r.Type = OrderTypeTrailingStop
+			r.TrailingStopType = TrailingStopTypePercentage
+			r.TrailingStopValue = Amount(200000000)
+		}),
+	}
+	for _, r := range valid {
+		if err := r.Validate(); err != nil {
+			t.Errorf("Unexpected error for %s order: %s", r.Type, err.Error())
+		}
+	}
+
+	invalid := map[string]*OrderRequest{
+		"Price":             withType(func(r *OrderRequest) { r.Type = OrderTypeLimit }),
+		"PostOnly":          withType(func(r *OrderRequest) { r.Type = OrderTypeMarket; r.PostOnly = true }),
+		"StopPrice":         withType(func(r *OrderRequest) { r.Type = OrderTypeStop }),
+		"TrailingStopType":  withType(func(r *OrderRequest) { r.Type = OrderTypeTrailingStop; r.TrailingStopValue = Amount(100) }),
+		"TrailingStopValue": withType(func(r *OrderRequest) { r.Type = OrderTypeTrailingStop; r.TrailingStopType = TrailingStopTypeFiat }),
+		"Type":              withType(func(r *OrderRequest) { r.Type = "iceberg" }),
+		"Side":              withType(func(r *OrderRequest) { r.Type = OrderTypeMarket; r.Side = "hold" }),
+	}
+	for field, r := range invalid {
+		err := r.Validate()
+		reqErr, ok := err.(*OrderRequestError)
+		if !ok {
+			t.Errorf("Expected *OrderRequestError for %s; Actual: %v.", field, err)
+			continue
+		}
+		if reqErr.Field != field {
+			t.Errorf("Unexpected field. Expected: %s; Actual: %s.", field, reqErr.Field)
+		}
+	}
+}
+
+func TestPrivateClient_CreateOrder_Market(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqBody struct {
+			Order map[string]interface{} `json:"order"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			t.Fatalf("Error parsing request body: %s", err.Error())
+		}
+		if orderType := reqBody.Order["order_type"]; orderType != OrderTypeMarket {
+			t.Errorf("Unexpected order type: %v", orderType)
+		}
+		if price, ok := reqBody.Order["price"]; ok {
+			t.Errorf("Did not expect a price on a market order: %v", price)
+		}
+
+		w.Write([]byte(`{"id": 148797142}`))
+	}))
+	defer ts.Close()
+
+	client := NewPrivateClient("123456", "secret", WithBaseURL(ts.URL))
+
+	orderId, err := client.CreateOrder(&OrderRequest{
+		ProductID: 4,
+		Type:      OrderTypeMarket,
+		Side:      OrderSideSell,
+		Quantity:  Amount(23180680000),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if expected := 148797142; orderId != expected {
+		t.Errorf("Unexpected ID. Expected: %d; Actual: %d.", expected, orderId)
+	}
+}
+
+func TestPrivateClient_CreateOrder_InvalidNotSent(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Did not expect an invalid order to be sent.")
+	}))
+	defer ts.Close()
+
+	client := NewPrivateClient("123456", "secret", WithBaseURL(ts.URL))
+
+	_, err := client.CreateOrder(&OrderRequest{ProductID: 4, Type: OrderTypeLimit, Side: OrderSideBuy, Quantity: Amount(1)})
+	if _, ok := err.(*OrderRequestError); !ok {
+		t.Errorf("Expected *OrderRequestError; Actual: %v.", err)
+	}
+}
@@ -0,0 +1,180 @@
+package qryptos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+)
+
+// The exchange returns 20 levels per side unless the full book is requested
+const defaultOrderBookDepth = 20
+
+var ErrInsufficientDepth = errors.New("not enough liquidity in the order book")
+
+// PriceLevel is the total quantity resting at a single price.
+type PriceLevel struct {
+	Price    Amount
+	Quantity Amount
+}
+
+// UnmarshalJSON reads the exchange's ["price", "quantity"] pairs.
+func (l *PriceLevel) UnmarshalJSON(data []byte) error {
+	var pair []Amount
+	if err := json.Unmarshal(data, &pair); err != nil {
+		return err
+	}
+	if len(pair) != 2 {
+		return fmt.Errorf("unexpected price level: %s", data)
+	}
+
+	l.Price = pair[0]
+	l.Quantity = pair[1]
+
+	return nil
+}
+
+// OrderBook is a snapshot of resting orders. Bids are sorted best (highest) first and asks best (lowest) first.
+type OrderBook struct {
+	ProductID int
+	Bids      []PriceLevel
+	Asks      []PriceLevel
+}
+
+type orderBookResponse struct {
+	BuyPriceLevels  []PriceLevel `json:"buy_price_levels"`
+	SellPriceLevels []PriceLevel `json:"sell_price_levels"`
+}
+
+func (c *PublicClient) FetchOrderBook(productId, depth int) (*OrderBook, error) {
+	return c.FetchOrderBookContext(context.Background(), productId, depth)
+}
+
+// FetchOrderBookContext fetches up to depth levels on each side of the book. A depth of 0 fetches the full book.
+func (c *PublicClient) FetchOrderBookContext(ctx context.Context, productId, depth int) (*OrderBook, error) {
+	q := url.Values{}
+	if depth <= 0 || depth > defaultOrderBookDepth {
+		q.Set("full", "1")
+	}
+
+	res, err := c.send(ctx, &apiRequest{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("%s/%d/price_levels", productsEndpoint, productId),
+		query:  q,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsedResponse orderBookResponse
+	if err := json.Unmarshal(res.body, &parsedResponse); err != nil {
+		return nil, err
+	}
+
+	book := &OrderBook{
+		ProductID: productId,
+		Bids:      parsedResponse.BuyPriceLevels,
+		Asks:      parsedResponse.SellPriceLevels,
+	}
+	sort.SliceStable(book.Bids, func(i, j int) bool { return book.Bids[i].Price > book.Bids[j].Price })
+	sort.SliceStable(book.Asks, func(i, j int) bool { return book.Asks[i].Price < book.Asks[j].Price })
+
+	if depth > 0 {
+		if len(book.Bids) > depth {
+			book.Bids = book.Bids[:depth]
+		}
+		if len(book.Asks) > depth {
+			book.Asks = book.Asks[:depth]
+		}
+	}
+
+	return book, nil
+}
+
+// Levels returns the side of the book that orders on the given side rest on: bids for buys and asks for sells.
+func (b *OrderBook) Levels(side string) []PriceLevel {
+	if side == OrderSideBuy {
+		return b.Bids
+	}
+	return b.Asks
+}
+
+// betterOrEqual reports whether price is at least as aggressive as limit for resting orders on side.
+func betterOrEqual(side string, price, limit Amount) bool {
+	if side == OrderSideBuy {
+		return price >= limit
+	}
+	return price <= limit
+}
+
+// CumulativeDepth returns the side's levels with each quantity replaced by the total quantity at that price and
+// every better price.
+func (b *OrderBook) CumulativeDepth(side string) []PriceLevel {
+	levels := b.Levels(side)
+	out := make([]PriceLevel, len(levels))
+
+	var total Amount
+	for i, level := range levels {
+		total += level.Quantity
+		out[i] = PriceLevel{Price: level.Price, Quantity: total}
+	}
+
+	return out
+}
+
+// DepthWithin is the total quantity resting on side at limit or better.
+func (b *OrderBook) DepthWithin(side string, limit Amount) Amount {
+	var total Amount
+	for _, level := range b.Levels(side) {
+		if !betterOrEqual(side, level.Price, limit) {
+			break
+		}
+		total += level.Quantity
+	}
+
+	return total
+}
+
+// QueueAhead is the quantity a new order on side at price would have to wait behind: everything resting at a
+// better price plus everything already at the same price.
+func (b *OrderBook) QueueAhead(side string, price Amount) Amount {
+	return b.DepthWithin(side, price)
+}
+
+// VolumeWeightedPrice is the average price paid to immediately fill size with a taker order on side. A buy
+// consumes the asks and a sell consumes the bids.
+func (b *OrderBook) VolumeWeightedPrice(side string, size Amount) (Amount, error) {
+	if size <= AmountZero {
+		return AmountZero, ErrInsufficientDepth
+	}
+
+	levels := b.Asks
+	if side == OrderSideSell {
+		levels = b.Bids
+	}
+
+	remaining := size
+	var notional Amount
+	for _, level := range levels {
+		fill := level.Quantity
+		if fill > remaining {
+			fill = remaining
+		}
+
+		cost, err := fill.Multiply(level.Price, RoundHalfEven)
+		if err != nil {
+			return AmountZero, err
+		}
+		notional += cost
+		remaining -= fill
+
+		if remaining == AmountZero {
+			return notional.Divide(size, RoundHalfEven)
+		}
+	}
+
+	return AmountZero, ErrInsufficientDepth
+}
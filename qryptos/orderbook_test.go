@@ -0,0 +1,88 @@
+package qryptos
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func testOrderBook(t *testing.T) *OrderBook {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if urlPath := r.URL.Path; urlPath != "/products/56/price_levels" {
+			t.Errorf("Unexpected request path: %s", urlPath)
+		}
+		if full := r.URL.Query().Get("full"); full != "" {
+			t.Errorf("Did not expect the full book: %s", full)
+		}
+
+		w.Write([]byte(`
+{
+	"buy_price_levels": [["0.00010100", "50.0"], ["0.00010201", "100.0"], ["0.00010000", "200.0"]],
+	"sell_price_levels": [["0.00010366", "10.0"], ["0.00010400", "30.0"], ["0.00010500", "60.0"]]
+}`))
+	}))
+	defer ts.Close()
+
+	client := NewPublicClient(WithBaseURL(ts.URL))
+
+	book, err := client.FetchOrderBook(56, 10)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	return book
+}
+
+func TestPublicClient_FetchOrderBook(t *testing.T) {
+	book := testOrderBook(t)
+
+	expectedBids := []Amount{Amount(10201), Amount(10100), Amount(10000)}
+	for i, price := range expectedBids {
+		if book.Bids[i].Price != price {
+			t.Errorf("Unexpected bid at %d. Expected: %s; Actual: %s.", i, price, book.Bids[i].Price)
+		}
+	}
+	if best := book.Asks[0]; best.Price != Amount(10366) || best.Quantity != Amount(1000000000) {
+		t.Errorf("Unexpected best ask: %+v", best)
+	}
+}
+
+func TestOrderBook_CumulativeDepth(t *testing.T) {
+	book := testOrderBook(t)
+
+	depth := book.CumulativeDepth(OrderSideSell)
+	expected := []Amount{Amount(1000000000), Amount(4000000000), Amount(10000000000)}
+	for i, qty := range expected {
+		if depth[i].Quantity != qty {
+			t.Errorf("Unexpected depth at %d. Expected: %s; Actual: %s.", i, qty, depth[i].Quantity)
+		}
+	}
+}
+
+func TestOrderBook_QueueAhead(t *testing.T) {
+	book := testOrderBook(t)
+
+	if actual := book.QueueAhead(OrderSideBuy, Amount(10100)); actual != Amount(15000000000) {
+		t.Errorf("Unexpected queue ahead. Expected: %s; Actual: %s.", Amount(15000000000), actual)
+	}
+	if actual := book.QueueAhead(OrderSideBuy, Amount(10300)); actual != AmountZero {
+		t.Errorf("Expected nothing ahead of a new best bid. Actual: %s.", actual)
+	}
+}
+
+func TestOrderBook_VolumeWeightedPrice(t *testing.T) {
+	book := testOrderBook(t)
+
+	// 10 @ 0.00010366 + 30 @ 0.00010400 = 0.0041566 for 40
+	price, err := book.VolumeWeightedPrice(OrderSideBuy, Amount(4000000000))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if expected := Amount(10392); price != expected {
+		t.Errorf("Unexpected price. Expected: %s; Actual: %s.", expected, price)
+	}
+
+	if _, err := book.VolumeWeightedPrice(OrderSideSell, Amount(100000000000)); err != ErrInsufficientDepth {
+		t.Errorf("Expected ErrInsufficientDepth; Actual: %v.", err)
+	}
+}
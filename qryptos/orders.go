@@ -0,0 +1,208 @@
+package qryptos
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const defaultOrdersPageSize = 100
+
+// OrdersQuery filters the orders returned by FetchOrdersPage, IterateOrders and FetchAllOrders. Zero values
+// match everything. Status, ProductID and FundingCurrency are filtered by the exchange; Side and the time range are
+// applied by the client.
+type OrdersQuery struct {
+	Status          string
+	ProductID       int
+	FundingCurrency string
+	Side            string
+	CreatedAfter    time.Time
+	CreatedBefore   time.Time
+	PageSize        int
+}
+
+func (q *OrdersQuery) pageSize() int {
+	if q.PageSize <= 0 {
+		return defaultOrdersPageSize
+	}
+	return q.PageSize
+}
+
+func (q *OrdersQuery) values(page int) url.Values {
+	v := url.Values{}
+	v.Set("with_details", "1")
+	v.Set("page", strconv.Itoa(page))
+
+	v.Set("limit", strconv.Itoa(q.pageSize()))
+
+	if q.Status != "" {
+		v.Set("status", q.Status)
+	}
+	if q.ProductID != 0 {
+		v.Set("product_id", strconv.Itoa(q.ProductID))
+	}
+	if q.FundingCurrency != "" {
+		v.Set("funding_currency", q.FundingCurrency)
+	}
+
+	return v
+}
+
+func (q *OrdersQuery) matches(o *OrderDetails) bool {
+	if q.Side != "" && o.Side != q.Side {
+		return false
+	}
+	if !q.CreatedAfter.IsZero() && o.CreatedAt.Before(q.CreatedAfter) {
+		return false
+	}
+	if !q.CreatedBefore.IsZero() && !o.CreatedAt.Before(q.CreatedBefore) {
+		return false
+	}
+
+	return true
+}
+
+// OrdersPage is a single page of results. Orders are already filtered by the query's client-side filters.
+type OrdersPage struct {
+	Orders      []*OrderDetails
+	CurrentPage int
+	TotalPages  int
+
+	// exhausted is set when no later page can contain matching orders.
+	exhausted bool
+}
+
+// HasMore reports whether another page may contain matching orders.
+func (p *OrdersPage) HasMore() bool {
+	return !p.exhausted && p.CurrentPage < p.TotalPages
+}
+
+func (p *OrdersPage) number() int {
+	return p.CurrentPage
+}
+
+func (p *OrdersPage) size() int {
+	return len(p.Orders)
+}
+
+func (c *PrivateClient) FetchOrdersPage(q *OrdersQuery, page int) (*OrdersPage, error) {
+	return c.FetchOrdersPageContext(context.Background(), q, page)
+}
+
+// FetchOrdersPageContext fetches one page of orders, starting from page 1.
+func (c *PrivateClient) FetchOrdersPageContext(ctx context.Context, q *OrdersQuery, page int) (*OrdersPage, error) {
+	res, err := c.send(ctx, &apiRequest{
+		method: http.MethodGet,
+		path:   endpointOrders,
+		query:  q.values(page),
+	}, c.signRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsedResponse ordersResponse
+	if err := json.Unmarshal(res.body, &parsedResponse); err != nil {
+		return nil, err
+	}
+
+	out := &OrdersPage{
+		Orders:      make([]*OrderDetails, 0, len(parsedResponse.Models)),
+		CurrentPage: parsedResponse.CurrentPage,
+		TotalPages:  parsedResponse.TotalPages,
+	}
+	if out.CurrentPage == 0 {
+		out.CurrentPage = page
+	}
+	if out.TotalPages == 0 {
+		// Without pagination metadata, a full page is the only hint that another one follows
+		out.TotalPages = page
+		if len(parsedResponse.Models) >= q.pageSize() {
+			out.TotalPages = page + 1
+		}
+	}
+	if len(parsedResponse.Models) == 0 {
+		out.exhausted = true
+	}
+
+	for _, model := range parsedResponse.Models {
+		order, err := parseOrderDetails(model)
+		if err != nil {
+			return nil, err
+		}
+
+		// The exchange lists orders newest first, so once we pass CreatedAfter nothing further can match
+		if pastStart(order.CreatedAt, q.CreatedAfter) {
+			out.exhausted = true
+		}
+
+		if q.matches(order) {
+			out.Orders = append(out.Orders, order)
+		}
+	}
+
+	return out, nil
+}
+
+// OrderIterator walks every page of orders matching a query:
+//
+//	it := client.IterateOrders(ctx, &OrdersQuery{Status: OrderStatusLive})
+//	for it.Next() {
+//		order := it.Order()
+//	}
+//	if err := it.Err(); err != nil {
+//		// handle err
+//	}
+type OrderIterator struct {
+	pageIterator
+
+	current *OrderDetails
+}
+
+func (c *PrivateClient) IterateOrders(ctx context.Context, q *OrdersQuery) *OrderIterator {
+	return &OrderIterator{
+		pageIterator: pageIterator{
+			fetch: func(page int) (resultPage, error) {
+				return c.FetchOrdersPageContext(ctx, q, page)
+			},
+		},
+	}
+}
+
+// Next advances to the next order, fetching further pages as needed. It returns false when there are no more
+// orders or an error occurred.
+func (it *OrderIterator) Next() bool {
+	i, ok := it.advance()
+	if !ok {
+		return false
+	}
+
+	it.current = it.page.(*OrdersPage).Orders[i]
+
+	return true
+}
+
+func (it *OrderIterator) Order() *OrderDetails {
+	return it.current
+}
+
+// FetchAllOrders collects every order matching the query across all pages.
+func (c *PrivateClient) FetchAllOrders(q *OrdersQuery) ([]*OrderDetails, error) {
+	return c.FetchAllOrdersContext(context.Background(), q)
+}
+
+func (c *PrivateClient) FetchAllOrdersContext(ctx context.Context, q *OrdersQuery) ([]*OrderDetails, error) {
+	var out []*OrderDetails
+
+	it := c.IterateOrders(ctx, q)
+	for it.Next() {
+		out = append(out, it.Order())
+	}
+	if err := it.Err(); err != nil {
+		return []*OrderDetails{}, err
+	}
+
+	return out, nil
+}
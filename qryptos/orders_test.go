@@ -0,0 +1,100 @@
+package qryptos
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPrivateClient_FetchAllOrders(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if status := q.Get("status"); status != OrderStatusLive {
+			t.Errorf("Unexpected status filter: %s", status)
+		}
+		if productId := q.Get("product_id"); productId != "56" {
+			t.Errorf("Unexpected product filter: %s", productId)
+		}
+
+		page, _ := strconv.Atoi(q.Get("page"))
+		side := OrderSideBuy
+		if page == 2 {
+			side = OrderSideSell
+		}
+
+		fmt.Fprintf(w, `
+{
+	"models": [
+		{"id": %d, "side": "%s", "status": "live", "product_id": 56, "quantity": "1.0", "filled_quantity": "0.0", "price": 0.0001, "currency_pair_code": "VZTBTC", "funding_currency": "BTC", "created_at": 1516007675},
+		{"id": %d, "side": "%s", "status": "live", "product_id": 56, "quantity": "2.0", "filled_quantity": "0.0", "price": 0.0001, "currency_pair_code": "VZTBTC", "funding_currency": "BTC", "created_at": 1516007675}
+	],
+	"current_page": %d,
+	"total_pages": 3
+}`, page*10+1, side, page*10+2, side, page)
+	}))
+	defer ts.Close()
+
+	client := NewPrivateClient("123456", "secret", WithBaseURL(ts.URL))
+
+	orders, err := client.FetchAllOrders(&OrdersQuery{
+		Status:    OrderStatusLive,
+		ProductID: 56,
+		Side:      OrderSideBuy,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	expectedIds := []int{11, 12, 31, 32}
+	if len(orders) != len(expectedIds) {
+		t.Fatalf("Unexpected order count. Expected: %d; Actual: %d.", len(expectedIds), len(orders))
+	}
+	for i, id := range expectedIds {
+		if orders[i].ID != id {
+			t.Errorf("Unexpected order ID at %d. Expected: %d; Actual: %d.", i, id, orders[i].ID)
+		}
+	}
+}
+
+func TestPrivateClient_IterateOrders_CreatedAfter(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		createdAt := 1516007675 - page*1000
+
+		fmt.Fprintf(w, `
+{
+	"models": [
+		{"id": %d, "side": "buy", "status": "filled", "quantity": "1.0", "filled_quantity": "1.0", "price": 0.0001, "created_at": %d}
+	],
+	"current_page": %d,
+	"total_pages": 10
+}`, page, createdAt, page)
+	}))
+	defer ts.Close()
+
+	client := NewPrivateClient("123456", "secret", WithBaseURL(ts.URL))
+
+	it := client.IterateOrders(context.Background(), &OrdersQuery{
+		CreatedAfter: time.Unix(1516007675-2500, 0),
+	})
+	var count int
+	for it.Next() {
+		count++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	if count != 2 {
+		t.Errorf("Unexpected order count. Expected: 2; Actual: %d.", count)
+	}
+	if requests != 3 {
+		t.Errorf("Expected iteration to stop after the first page older than CreatedAfter. Requests: %d.", requests)
+	}
+}
@@ -0,0 +1,58 @@
+package qryptos
+
+import "time"
+
+// resultPage is one page of a paginated listing such as OrdersPage or ExecutionsPage.
+type resultPage interface {
+	HasMore() bool
+	number() int
+	size() int
+}
+
+// pageIterator walks the items of a paginated listing, fetching further pages as needed. The typed iterators embed
+// it and look the current item up in their own page type.
+type pageIterator struct {
+	fetch func(page int) (resultPage, error)
+
+	page  resultPage
+	index int
+	err   error
+}
+
+// advance moves to the next item and returns its index in the current page. It returns false when there are no
+// more items or an error occurred.
+func (it *pageIterator) advance() (int, bool) {
+	if it.err != nil {
+		return 0, false
+	}
+
+	for it.page == nil || it.index >= it.page.size() {
+		nextPage := 1
+		if it.page != nil {
+			if !it.page.HasMore() {
+				return 0, false
+			}
+			nextPage = it.page.number() + 1
+		}
+
+		it.page, it.err = it.fetch(nextPage)
+		if it.err != nil {
+			return 0, false
+		}
+		it.index = 0
+	}
+
+	it.index++
+
+	return it.index - 1, true
+}
+
+func (it *pageIterator) Err() error {
+	return it.err
+}
+
+// pastStart reports whether an item created at createdAt, in a listing sorted newest first, shows that nothing later
+// in the listing can be from start onwards. Items without a timestamp tell us nothing.
+func pastStart(createdAt, start time.Time) bool {
+	return !start.IsZero() && !createdAt.IsZero() && createdAt.Before(start)
+}
@@ -1,14 +1,11 @@
 package qryptos
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"github.com/dgrijalva/jwt-go"
+	"github.com/tobyjsullivan/shifty/qryptos/auth"
 	"net/http"
-	"net/url"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -26,28 +23,38 @@ const (
 )
 
 type PrivateClient struct {
-	tokenId    string
-	secretKey  string
-	apiBaseUrl string
+	clientConfig
+	signer *auth.Signer
 }
 
-func NewPrivateClient(apiTokenID, apiSecretKey string) *PrivateClient {
+func NewPrivateClient(apiTokenID, apiSecretKey string, opts ...ClientOption) *PrivateClient {
+	return NewPrivateClientWithCredentials(auth.Static(apiTokenID, apiSecretKey), opts...)
+}
+
+// NewPrivateClientWithCredentials looks up credentials from creds each time it signs a request.
+func NewPrivateClientWithCredentials(creds auth.CredentialSource, opts ...ClientOption) *PrivateClient {
+	cfg := newClientConfig(opts)
+
 	return &PrivateClient{
-		tokenId:    apiTokenID,
-		secretKey:  apiSecretKey,
-		apiBaseUrl: qryptosApiBaseUrl,
+		clientConfig: cfg,
+		signer:       auth.NewSigner(creds, cfg.nonceSource),
 	}
 }
 
 type OrderDetails struct {
 	ID               int
+	ProductID        int
 	Side             string
 	Status           string
 	CurrencyPairCode string
+	BaseCurrency     string
+	QuoteCurrency    string
 	Price            Amount
 	Quantity         Amount
 	FilledQuantity   Amount
 	Executions       []*ExecutionDetails
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
 }
 
 type ExecutionDetails struct {
@@ -61,126 +68,79 @@ type AccountBalance struct {
 	Balance Amount
 }
 
-func (c *PrivateClient) generateJWT(uri *url.URL) (string, error) {
-	nonce := time.Now().UnixNano() / 1000000
-	path := uri.Path
-
-	if uri.RawQuery != "" {
-		path += "?" + uri.RawQuery
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"path":     path,
-		"nonce":    strconv.FormatInt(nonce, 10),
-		"token_id": c.tokenId,
-	})
-
-	tokenString, err := token.SignedString([]byte(c.secretKey))
-
-	return tokenString, err
-}
-
 func (c *PrivateClient) signRequest(req *http.Request) error {
-	token, err := c.generateJWT(req.URL)
-	if err != nil {
+	if err := c.signer.SignRequest(req); err != nil {
 		return err
 	}
 
-	req.Header.Set("X-Quoine-Auth", token)
-	req.Header.Set("X-Quoine-API-Version", "2")
 	req.Header.Set("Content-Type", "application/json")
 
 	return nil
 }
 
 type ordersResponse struct {
-	Models []*orderResponse `json:"models"`
+	Models      []*orderResponse `json:"models"`
+	CurrentPage int              `json:"current_page"`
+	TotalPages  int              `json:"total_pages"`
 }
 
 type orderResponse struct {
 	ID               int                  `json:"id"`
+	ProductID        int                  `json:"product_id"`
 	Side             string               `json:"side"`
 	Status           string               `json:"status"`
 	CurrencyPairCode string               `json:"currency_pair_code"`
-	Price            float64              `json:"price"`
-	Quantity         string               `json:"quantity"`
-	FilledQuantity   string               `json:"filled_quantity"`
+	FundingCurrency  string               `json:"funding_currency"`
+	Price            Amount               `json:"price"`
+	Quantity         Amount               `json:"quantity"`
+	FilledQuantity   Amount               `json:"filled_quantity"`
 	Executions       []*executionResponse `json:"executions"`
+	CreatedAt        int64                `json:"created_at"`
+	UpdatedAt        int64                `json:"updated_at"`
 }
 
 type executionResponse struct {
 	ID        int    `json:"id"`
-	Quantity  string `json:"quantity"`
-	Price     string `json:"price"`
+	Quantity  Amount `json:"quantity"`
+	Price     Amount `json:"price"`
 	TakerSide string `json:"taker_side"`
 	MySide    string `json:"my_side"`
 }
 
 type accountBalanceResponse struct {
 	Currency string `json:"currency"`
-	Balance string `json:"balance"`
+	Balance  Amount `json:"balance"`
 }
 
+// FetchOrders returns the most recent page of orders. Use FetchAllOrders to see every order matching a query.
 func (c *PrivateClient) FetchOrders() ([]*OrderDetails, error) {
-	endpoint := c.apiBaseUrl + endpointOrders
-	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
-	if err != nil {
-		return []*OrderDetails{}, err
-	}
-
-	q := req.URL.Query()
-	q.Set("limit", "100")
-	q.Set("with_details", "1")
-	req.URL.RawQuery = q.Encode()
-
-	err = c.signRequest(req)
-	if err != nil {
-		return []*OrderDetails{}, err
-	}
+	return c.FetchOrdersContext(context.Background())
+}
 
-	res, err := http.DefaultClient.Do(req)
+func (c *PrivateClient) FetchOrdersContext(ctx context.Context) ([]*OrderDetails, error) {
+	page, err := c.FetchOrdersPageContext(ctx, &OrdersQuery{}, 1)
 	if err != nil {
 		return []*OrderDetails{}, err
 	}
 
-	var parsedResponse ordersResponse
-	if err := json.NewDecoder(res.Body).Decode(&parsedResponse); err != nil {
-		return []*OrderDetails{}, err
-	}
-
-	out := make([]*OrderDetails, len(parsedResponse.Models))
-	for i, model := range parsedResponse.Models {
-		out[i], err = parseOrderDetails(model)
-		if err != nil {
-			return []*OrderDetails{}, err
-		}
-	}
-
-	return out, nil
+	return page.Orders, nil
 }
 
 func (c *PrivateClient) FetchOrder(orderId int) (*OrderDetails, error) {
-	endpoint := fmt.Sprintf("%s%s/%d", c.apiBaseUrl, endpointOrders, orderId)
-	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	q := req.URL.Query()
-	req.URL.RawQuery = q.Encode()
-
-	err = c.signRequest(req)
-	if err != nil {
-		return nil, err
-	}
+	return c.FetchOrderContext(context.Background(), orderId)
+}
 
-	res, err := http.DefaultClient.Do(req)
+func (c *PrivateClient) FetchOrderContext(ctx context.Context, orderId int) (*OrderDetails, error) {
+	res, err := c.send(ctx, &apiRequest{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("%s/%d", endpointOrders, orderId),
+	}, c.signRequest)
 	if err != nil {
 		return nil, err
 	}
 
 	var parsedResponse orderResponse
-	if err := json.NewDecoder(res.Body).Decode(&parsedResponse); err != nil {
+	if err := json.Unmarshal(res.body, &parsedResponse); err != nil {
 		return nil, err
 	}
 
@@ -188,80 +148,75 @@ func (c *PrivateClient) FetchOrder(orderId int) (*OrderDetails, error) {
 }
 
 func (c *PrivateClient) CreateLimitOrder(productId int, side string, quantity, price Amount) (int, error) {
-	fmt.Println("[CreateLimitOrder] Creating order...")
-
-	qtyString := fmt.Sprintf("%.08f", quantity.ToDecimal())
-	priceString := fmt.Sprintf("%.08f", price.ToDecimal())
-
-	payload := &fmtCreateOrder{
-		Order: &fmtCreateOrderModel{
-			OrderType: "limit",
-			ProductID: productId,
-			Side:      side,
-			Quantity:  qtyString,
-			Price:     priceString,
-		},
-	}
+	return c.CreateLimitOrderContext(context.Background(), productId, side, quantity, price)
+}
 
-	bodyString, err := json.Marshal(payload)
-	if err != nil {
-		return 0, err
-	}
+func (c *PrivateClient) CreateLimitOrderContext(ctx context.Context, productId int, side string, quantity, price Amount) (int, error) {
+	return c.CreateOrderContext(ctx, &OrderRequest{
+		ProductID: productId,
+		Type:      OrderTypeLimit,
+		Side:      side,
+		Quantity:  quantity,
+		Price:     price,
+	})
+}
 
-	fmt.Printf("[CreateLimitOrder] Body: %s\n", bodyString)
+func (c *PrivateClient) CreateOrder(order *OrderRequest) (int, error) {
+	return c.CreateOrderContext(context.Background(), order)
+}
 
-	endpoint := c.apiBaseUrl + endpointOrders
-	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(string(bodyString)))
-	if err != nil {
+// CreateOrderContext validates the request and submits it. Invalid requests are rejected with an
+// *OrderRequestError before anything is sent.
+func (c *PrivateClient) CreateOrderContext(ctx context.Context, order *OrderRequest) (int, error) {
+	fmt.Fprintln(c.logOutput, "[CreateOrder] Creating order...")
+
+	if err := order.Validate(); err != nil {
 		return 0, err
 	}
 
-	err = c.signRequest(req)
+	bodyString, err := json.Marshal(&fmtCreateOrder{
+		Order: order.model(),
+	})
 	if err != nil {
 		return 0, err
 	}
 
-	fmt.Printf("[CreateLimitOrder] URL: %s\n", req.URL.String())
+	fmt.Fprintf(c.logOutput, "[CreateOrder] Body: %s\n", bodyString)
 
-	//var res *http.Response
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.send(ctx, &apiRequest{
+		method: http.MethodPost,
+		path:   endpointOrders,
+		body:   bodyString,
+	}, c.signRequest)
 	if err != nil {
 		return 0, err
 	}
 
-	if res.StatusCode != 200 {
-		var buf bytes.Buffer
-		buf.ReadFrom(res.Body)
-
-		fmt.Printf("[CreateLimitOrder] Error: %s\n", buf)
-
-		return 0, errors.New(fmt.Sprintf("unexpected status: %d", res.StatusCode))
-	}
-
 	var parsedRes struct {
 		ID int `json:"id"`
 	}
 
-	err = json.NewDecoder(res.Body).Decode(&parsedRes)
+	err = json.Unmarshal(res.body, &parsedRes)
 	if err != nil {
 		return 0, err
 	}
 
-	fmt.Printf("[CreateLimitOrder] Created successfully: %d\n", parsedRes.ID)
+	fmt.Fprintf(c.logOutput, "[CreateOrder] Created successfully: %d\n", parsedRes.ID)
 
 	return parsedRes.ID, nil
 }
 
 func (c *PrivateClient) EditOrder(orderId int, quantity, price Amount) error {
-	fmt.Println("[EditOrder]", "Updating order:", orderId)
+	return c.EditOrderContext(context.Background(), orderId, quantity, price)
+}
 
-	qtyString := fmt.Sprintf("%.08f", quantity.ToDecimal())
-	priceString := fmt.Sprintf("%.08f", price.ToDecimal())
+func (c *PrivateClient) EditOrderContext(ctx context.Context, orderId int, quantity, price Amount) error {
+	fmt.Fprintln(c.logOutput, "[EditOrder]", "Updating order:", orderId)
 
 	payload := &fmtEditOrder{
 		Order: &fmtEditOrderModel{
-			Quantity: qtyString,
-			Price:    priceString,
+			Quantity: quantity,
+			Price:    price,
 		},
 	}
 
@@ -270,101 +225,62 @@ func (c *PrivateClient) EditOrder(orderId int, quantity, price Amount) error {
 		return err
 	}
 
-	endpoint := fmt.Sprintf("%s%s/%d", c.apiBaseUrl, endpointOrders, orderId)
-	req, err := http.NewRequest(http.MethodPut, endpoint, strings.NewReader(string(bodyString)))
+	res, err := c.send(ctx, &apiRequest{
+		method: http.MethodPut,
+		path:   fmt.Sprintf("%s/%d", endpointOrders, orderId),
+		body:   bodyString,
+	}, c.signRequest)
 	if err != nil {
 		return err
 	}
 
-	err = c.signRequest(req)
-	if err != nil {
-		return err
-	}
-
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
-	}
-
-	if res.StatusCode != 200 {
-		var buf bytes.Buffer
-		buf.ReadFrom(res.Body)
-
-		fmt.Printf("[EditOrder] Error: %s\n", buf)
-
-		return errors.New(fmt.Sprintf("unexpected status: %d", res.StatusCode))
-	}
-
-	fmt.Printf("[EditOrder] Status Code: %d\n", res.StatusCode)
+	fmt.Fprintf(c.logOutput, "[EditOrder] Status Code: %d\n", res.statusCode)
 
 	return nil
 }
 
 func (c *PrivateClient) CancelOrder(orderId int) error {
-	fmt.Println("[CancelOrder] Cancelling order:", orderId)
-
-	endpoint := fmt.Sprintf("%s%s/%d/cancel", c.apiBaseUrl, endpointOrders, orderId)
-	req, err := http.NewRequest(http.MethodPut, endpoint, nil)
-	if err != nil {
-		return err
-	}
-
-	err = c.signRequest(req)
-	if err != nil {
-		return err
-	}
-
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
-	}
-
-	if res.StatusCode != 200 {
-		var buf bytes.Buffer
-		buf.ReadFrom(res.Body)
+	return c.CancelOrderContext(context.Background(), orderId)
+}
 
-		fmt.Printf("[CancelOrder] Error: %s\n", buf)
+func (c *PrivateClient) CancelOrderContext(ctx context.Context, orderId int) error {
+	fmt.Fprintln(c.logOutput, "[CancelOrder] Cancelling order:", orderId)
 
-		return errors.New(fmt.Sprintf("unexpected status: %d", res.StatusCode))
-	}
+	_, err := c.send(ctx, &apiRequest{
+		method: http.MethodPut,
+		path:   fmt.Sprintf("%s/%d/cancel", endpointOrders, orderId),
+		// Cancelling an already-cancelled order is harmless, so cancels can be retried safely
+		idempotent: true,
+	}, c.signRequest)
 
-	return nil
+	return err
 }
 
 func (c *PrivateClient) FetchAccountBalances() ([]*AccountBalance, error) {
-	fmt.Println("[FetchCryptoAccounts] Fetching accounts...")
-
-	endpoint := c.apiBaseUrl + endpointAccountBalances
-	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
-	if err != nil {
-		return []*AccountBalance{}, err
-	}
+	return c.FetchAccountBalancesContext(context.Background())
+}
 
-	err = c.signRequest(req)
-	if err != nil {
-		return []*AccountBalance{}, err
-	}
+func (c *PrivateClient) FetchAccountBalancesContext(ctx context.Context) ([]*AccountBalance, error) {
+	fmt.Fprintln(c.logOutput, "[FetchCryptoAccounts] Fetching accounts...")
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.send(ctx, &apiRequest{
+		method: http.MethodGet,
+		path:   endpointAccountBalances,
+	}, c.signRequest)
 	if err != nil {
 		return []*AccountBalance{}, err
 	}
 
 	var parsedResponse []*accountBalanceResponse
-	if err := json.NewDecoder(res.Body).Decode(&parsedResponse); err != nil {
+	if err := json.Unmarshal(res.body, &parsedResponse); err != nil {
 		return []*AccountBalance{}, err
 	}
 
 	out := make([]*AccountBalance, len(parsedResponse))
 	for i, acctInfo := range parsedResponse {
-		balance, err := amountFromString(acctInfo.Balance)
-		if err != nil {
-			return []*AccountBalance{}, err
-		}
-
 		out[i] = &AccountBalance{
 			Currency: acctInfo.Currency,
-			Balance:  balance,
+			Balance:  acctInfo.Balance,
 		}
 	}
 
@@ -375,16 +291,38 @@ func (o *OrderDetails) CanEdit() bool {
 	return o.Status == OrderStatusLive && o.FilledQuantity == 0.0
 }
 
+// Size is the order quantity in the base currency.
+func (o *OrderDetails) Size() Money {
+	return NewMoney(o.Quantity, o.BaseCurrency)
+}
+
+// Filled is the filled quantity in the base currency.
+func (o *OrderDetails) Filled() Money {
+	return NewMoney(o.FilledQuantity, o.BaseCurrency)
+}
+
+func (o *OrderDetails) LimitPrice() Price {
+	return NewPrice(o.Price, o.BaseCurrency, o.QuoteCurrency)
+}
+
+func (b *AccountBalance) Money() Money {
+	return NewMoney(b.Balance, b.Currency)
+}
+
 type fmtCreateOrder struct {
 	Order *fmtCreateOrderModel `json:"order"`
 }
 
 type fmtCreateOrderModel struct {
-	OrderType string `json:"order_type"`
-	ProductID int    `json:"product_id"`
-	Side      string `json:"side"`
-	Quantity  string `json:"quantity"`
-	Price     string `json:"price"`
+	OrderType         string `json:"order_type"`
+	ProductID         int    `json:"product_id"`
+	Side              string `json:"side"`
+	Quantity          Amount `json:"quantity"`
+	Price             Amount `json:"price,omitempty"`
+	StopPrice         Amount `json:"stop_price,omitempty"`
+	PostOnly          bool   `json:"post_only,omitempty"`
+	TrailingStopType  string `json:"trailing_stop_type,omitempty"`
+	TrailingStopValue Amount `json:"trailing_stop_value,omitempty"`
 }
 
 type fmtEditOrder struct {
@@ -392,29 +330,18 @@ type fmtEditOrder struct {
 }
 
 type fmtEditOrderModel struct {
-	Quantity string `json:"quantity"`
-	Price    string `json:"price"`
+	Quantity Amount `json:"quantity"`
+	Price    Amount `json:"price"`
 }
 
 func parseExecutions(input []*executionResponse) ([]*ExecutionDetails, error) {
 	var out []*ExecutionDetails
 
 	for _, resp := range input {
-
-		quantity, err := amountFromString(resp.Quantity)
-		if err != nil {
-			return []*ExecutionDetails{}, err
-		}
-
-		price, err := amountFromString(resp.Price)
-		if err != nil {
-			return []*ExecutionDetails{}, err
-		}
-
 		out = append(out, &ExecutionDetails{
 			ID:       resp.ID,
-			Quantity: quantity,
-			Price:    price,
+			Quantity: resp.Quantity,
+			Price:    resp.Price,
 		})
 	}
 
@@ -427,38 +354,26 @@ func parseOrderDetails(input *orderResponse) (*OrderDetails, error) {
 		return nil, err
 	}
 
-	quantity, err := amountFromString(input.Quantity)
-	if err != nil {
-		return nil, err
-	}
-
-	filledQty, err := amountFromString(input.FilledQuantity)
-	if err != nil {
-		return nil, err
-	}
-
-	var price Amount
-	price.FromDecimal(input.Price)
-
 	return &OrderDetails{
 		ID:               input.ID,
+		ProductID:        input.ProductID,
 		Side:             input.Side,
 		Status:           input.Status,
 		CurrencyPairCode: input.CurrencyPairCode,
-		Price:            price,
-		Quantity:         quantity,
-		FilledQuantity:   filledQty,
+		BaseCurrency:     strings.TrimSuffix(input.CurrencyPairCode, input.FundingCurrency),
+		QuoteCurrency:    input.FundingCurrency,
+		Price:            input.Price,
+		Quantity:         input.Quantity,
+		FilledQuantity:   input.FilledQuantity,
 		Executions:       executions,
+		CreatedAt:        parseTimestamp(input.CreatedAt),
+		UpdatedAt:        parseTimestamp(input.UpdatedAt),
 	}, nil
 }
 
-func amountFromString(s string) (Amount, error) {
-	var amt Amount
-	fAmount, err := strconv.ParseFloat(s, 64)
-	if err != nil {
-		return amt, err
+func parseTimestamp(unix int64) time.Time {
+	if unix == 0 {
+		return time.Time{}
 	}
-	amt.FromDecimal(fAmount)
-
-	return amt, nil
+	return time.Unix(unix, 0)
 }
@@ -1,6 +1,9 @@
 package qryptos
 
 import (
+	"bytes"
+	"encoding/json"
+	"strings"
 	"testing"
 	"net/http/httptest"
 	"net/http"
@@ -52,11 +55,7 @@ func TestPrivateClient_FetchOrder(t *testing.T) {
 	}))
 	defer ts.Close()
 
-	client := &PrivateClient{
-		tokenId: "123456",
-		secretKey: secretKey,
-		apiBaseUrl: ts.URL,
-	}
+	client := NewPrivateClient("123456", secretKey, WithBaseURL(ts.URL))
 
 	testOrderId := 983487134
 	order, err := client.FetchOrder(testOrderId)
@@ -73,6 +72,11 @@ func TestPrivateClient_FetchOrder(t *testing.T) {
 	if actualPrice := order.Price; expectedPrice != actualPrice {
 		t.Errorf("Unexpected price. Expected: %d; Actual: %d.", expectedPrice, actualPrice)
 	}
+
+	expectedSize := NewMoney(Amount(10586320000), "VZT")
+	if actualSize := order.Size(); expectedSize != actualSize {
+		t.Errorf("Unexpected size. Expected: %s; Actual: %s.", expectedSize, actualSize)
+	}
 }
 
 func TestPrivateClient_CreateLimitOrder(t *testing.T) {
@@ -97,6 +101,22 @@ func TestPrivateClient_CreateLimitOrder(t *testing.T) {
 			t.Errorf("Invalid auth header: %s", authHeader)
 		}
 
+		var reqBody struct {
+			Order struct {
+				Quantity string `json:"quantity"`
+				Price    string `json:"price"`
+			} `json:"order"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+			t.Fatalf("Error parsing request body: %s", err.Error())
+		}
+		if qty := reqBody.Order.Quantity; qty != "231.80680000" {
+			t.Errorf("Unexpected quantity: %s", qty)
+		}
+		if price := reqBody.Order.Price; price != "0.00004754" {
+			t.Errorf("Unexpected price: %s", price)
+		}
+
 		respBody := `
 {
 	"id": 148797141,
@@ -120,11 +140,8 @@ func TestPrivateClient_CreateLimitOrder(t *testing.T) {
 	}))
 	defer ts.Close()
 
-	client := &PrivateClient{
-		tokenId: "123456",
-		secretKey: secretKey,
-		apiBaseUrl: ts.URL,
-	}
+	var log bytes.Buffer
+	client := NewPrivateClient("123456", secretKey, WithBaseURL(ts.URL), WithLogOutput(&log))
 
 	orderId, err := client.CreateLimitOrder(4, OrderSideBuy, Amount(23180680000), Amount(4754))
 	if err != nil {
@@ -135,4 +152,7 @@ func TestPrivateClient_CreateLimitOrder(t *testing.T) {
 	if orderId != expectedId {
 		t.Errorf("Unexpected ID. Expected: %d; Actual: %d.", expectedId, orderId)
 	}
+	if !strings.Contains(log.String(), "[CreateOrder] Created successfully: 148797141") {
+		t.Errorf("Unexpected log output: %q", log.String())
+	}
 }
\ No newline at end of file
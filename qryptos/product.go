@@ -0,0 +1,113 @@
+package qryptos
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrQuantityBelowMinimum = errors.New("quantity is below the product minimum")
+	ErrQuantityStep         = errors.New("quantity is not a multiple of the product quantity step")
+	ErrPriceTick            = errors.New("price is not a multiple of the product price tick")
+	ErrNonPositivePrice     = errors.New("price must be positive")
+)
+
+// OrderConstraintError reports an order that the exchange would reject for the given product.
+type OrderConstraintError struct {
+	CurrencyPairCode string
+	Quantity         Amount
+	Price            Amount
+	Err              error
+}
+
+func (e *OrderConstraintError) Error() string {
+	return fmt.Sprintf("%s order (quantity: %s; price: %s): %s", e.CurrencyPairCode, e.Quantity, e.Price, e.Err.Error())
+}
+
+// Ask is the current market ask as a price in the quoted currency.
+func (p *ProductDetails) Ask() Price {
+	return NewPrice(p.MarketAsk, p.BaseCurrency, p.QuotedCurrency)
+}
+
+// Bid is the current market bid as a price in the quoted currency.
+func (p *ProductDetails) Bid() Price {
+	return NewPrice(p.MarketBid, p.BaseCurrency, p.QuotedCurrency)
+}
+
+// Tick is the smallest price increment. Products without a known tick size move in minimal units.
+func (p *ProductDetails) Tick() Amount {
+	if p.PriceTick <= AmountZero {
+		return MinimalUnit
+	}
+	return p.PriceTick
+}
+
+// Step is the smallest quantity increment. Products without a known step move in minimal units.
+func (p *ProductDetails) Step() Amount {
+	if p.QuantityStep <= AmountZero {
+		return MinimalUnit
+	}
+	return p.QuantityStep
+}
+
+// QuantizePrice snaps a price onto the product's tick grid. Buy prices round down and sell prices round up so that
+// quantizing never makes an order more aggressive than intended.
+func (p *ProductDetails) QuantizePrice(price Amount, side string) Amount {
+	tick := p.Tick()
+	rem := price % tick
+	if rem == 0 {
+		return price
+	}
+	if rem < 0 {
+		rem += tick
+	}
+
+	floor := price - rem
+	if side == OrderSideSell {
+		return floor + tick
+	}
+	return floor
+}
+
+// QuantizeQuantity rounds a quantity down to the product's quantity step.
+func (p *ProductDetails) QuantizeQuantity(quantity Amount) Amount {
+	step := p.Step()
+	if quantity <= AmountZero {
+		return AmountZero
+	}
+
+	return quantity - quantity%step
+}
+
+// MinimumOrderQuantity is the smallest quantity the exchange accepts for this product.
+func (p *ProductDetails) MinimumOrderQuantity() Amount {
+	if p.MinimumQuantity <= AmountZero {
+		return MinimumOrderQuantity(p.BaseCurrency)
+	}
+	return p.MinimumQuantity
+}
+
+// CheckOrder reports whether an order with the given quantity and price satisfies the product's constraints.
+func (p *ProductDetails) CheckOrder(quantity, price Amount) error {
+	var err error
+	switch {
+	case price <= AmountZero:
+		err = ErrNonPositivePrice
+	case price%p.Tick() != 0:
+		err = ErrPriceTick
+	case quantity < p.MinimumOrderQuantity():
+		err = ErrQuantityBelowMinimum
+	case quantity%p.Step() != 0:
+		err = ErrQuantityStep
+	}
+	if err != nil {
+		return &OrderConstraintError{
+			CurrencyPairCode: p.CurrencyPairCode,
+			Quantity:         quantity,
+			Price:            price,
+			Err:              err,
+		}
+	}
+
+	return nil
+}
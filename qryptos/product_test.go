@@ -0,0 +1,74 @@
+package qryptos
+
+import "testing"
+
+func TestProductDetails_QuantizePrice(t *testing.T) {
+	product := &ProductDetails{PriceTick: Amount(500)}
+
+	if actual := product.QuantizePrice(Amount(10366), OrderSideBuy); actual != Amount(10000) {
+		t.Errorf("Unexpected buy price. Expected: %d; Actual: %d.", 10000, actual)
+	}
+	if actual := product.QuantizePrice(Amount(10366), OrderSideSell); actual != Amount(10500) {
+		t.Errorf("Unexpected sell price. Expected: %d; Actual: %d.", 10500, actual)
+	}
+	if actual := product.QuantizePrice(Amount(10500), OrderSideSell); actual != Amount(10500) {
+		t.Errorf("Unexpected sell price. Expected: %d; Actual: %d.", 10500, actual)
+	}
+}
+
+func TestProductDetails_QuantizeQuantity(t *testing.T) {
+	product := &ProductDetails{QuantityStep: Amount(1000000)}
+
+	if actual := product.QuantizeQuantity(Amount(23180680000)); actual != Amount(23180000000) {
+		t.Errorf("Unexpected quantity. Expected: %d; Actual: %d.", 23180000000, actual)
+	}
+}
+
+func TestProductDetails_TickAndStep(t *testing.T) {
+	product := &ProductDetails{}
+	if product.Tick() != MinimalUnit || product.Step() != MinimalUnit {
+		t.Errorf("Unexpected defaults. Tick: %d; Step: %d.", product.Tick(), product.Step())
+	}
+
+	product = &ProductDetails{PriceTick: Amount(500), QuantityStep: Amount(1000000)}
+	if product.Tick() != Amount(500) || product.Step() != Amount(1000000) {
+		t.Errorf("Unexpected increments. Tick: %d; Step: %d.", product.Tick(), product.Step())
+	}
+}
+
+func TestProductDetails_CheckOrder(t *testing.T) {
+	product := &ProductDetails{
+		CurrencyPairCode: "ETHBTC",
+		BaseCurrency:     "ETH",
+		PriceTick:        Amount(100),
+		QuantityStep:     Amount(100000),
+		MinimumQuantity:  Amount(1000000),
+	}
+
+	cases := []struct {
+		quantity Amount
+		price    Amount
+		expected error
+	}{
+		{Amount(2000000), Amount(5000000), nil},
+		{Amount(2000000), Amount(5000050), ErrPriceTick},
+		{Amount(2000000), AmountZero, ErrNonPositivePrice},
+		{Amount(900000), Amount(5000000), ErrQuantityBelowMinimum},
+		{Amount(2050000), Amount(5000000), ErrQuantityStep},
+	}
+
+	for _, c := range cases {
+		err := product.CheckOrder(c.quantity, c.price)
+		if c.expected == nil {
+			if err != nil {
+				t.Errorf("Unexpected error: %s", err.Error())
+			}
+			continue
+		}
+
+		constraintErr, ok := err.(*OrderConstraintError)
+		if !ok || constraintErr.Err != c.expected {
+			t.Errorf("Unexpected error. Expected: %v; Actual: %v.", c.expected, err)
+		}
+	}
+}
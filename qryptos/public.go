@@ -1,6 +1,7 @@
 package qryptos
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strconv"
@@ -38,6 +39,7 @@ var (
 )
 
 type PublicClient struct {
+	clientConfig
 }
 
 type ProductDetails struct {
@@ -50,81 +52,137 @@ type ProductDetails struct {
 	MarketBid        Amount
 	Volume24Hour     Amount
 	Disabled         bool
+	PriceTick        Amount
+	QuantityStep     Amount
+	MinimumQuantity  Amount
+
+	// MakerFee and TakerFee are fractions of the traded value, so Amount(100000) is 0.1%
+	MakerFee Amount
+	TakerFee Amount
 }
 
 func DefaultClient() *PublicClient {
-	return &PublicClient{}
+	return NewPublicClient()
 }
 
-func (c *PublicClient) FetchProducts() ([]*ProductDetails, error) {
-	endpoint := qryptosApiBaseUrl + productsEndpoint
-	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
-	if err != nil {
-		return []*ProductDetails{}, err
+func NewPublicClient(opts ...ClientOption) *PublicClient {
+	return &PublicClient{
+		clientConfig: newClientConfig(opts),
 	}
-	req.Header.Set("X-Quoine-API-Version", "2")
-	res, err := http.DefaultClient.Do(req)
+}
+
+func (c *PublicClient) FetchProducts() ([]*ProductDetails, error) {
+	return c.FetchProductsContext(context.Background())
+}
+
+func (c *PublicClient) FetchProductsContext(ctx context.Context) ([]*ProductDetails, error) {
+	res, err := c.send(ctx, &apiRequest{
+		method: http.MethodGet,
+		path:   productsEndpoint,
+	}, nil)
 	if err != nil {
 		return []*ProductDetails{}, err
 	}
 
 	var parsedResponse []*productsResponse
-	if err := json.NewDecoder(res.Body).Decode(&parsedResponse); err != nil {
+	if err := json.Unmarshal(res.body, &parsedResponse); err != nil {
 		return []*ProductDetails{}, err
 	}
 
 	out := make([]*ProductDetails, 0, len(parsedResponse))
 	for _, respDetail := range parsedResponse {
-		prodId, err := strconv.Atoi(respDetail.ID)
+		details, err := parseProductDetails(respDetail)
 		if err != nil {
-			fmt.Println("[FetchProducts] Error parsing product ID:", err.Error())
 			return []*ProductDetails{}, err
 		}
-
-		if respDetail.MarketAsk == "" {
+		if details == nil {
 			continue
 		}
-		var marketAsk Amount
-		fMarketAsk, err := strconv.ParseFloat(respDetail.MarketAsk, 64)
-		if err != nil {
-			fmt.Println("[FetchProducts] Error parsing MarketAsk:", err.Error())
-			return []*ProductDetails{}, err
-		}
-		marketAsk.FromDecimal(fMarketAsk)
 
-		if respDetail.MarketBid == "" {
-			continue
-		}
-		var marketBid Amount
-		fMarketBid, err := strconv.ParseFloat(respDetail.MarketBid, 64)
-		if err != nil {
-			fmt.Println("[FetchProducts] Error parsing MarketBid:", err.Error())
-			return []*ProductDetails{}, err
-		}
-		marketBid.FromDecimal(fMarketBid)
+		out = append(out, details)
+	}
 
-		var vol24Hr Amount
-		fVol24Hr, err := strconv.ParseFloat(respDetail.Volume24Hr, 64)
-		if err != nil {
-			fmt.Println("[FetchProducts] Error parsing Volume24Hr:", err.Error())
-			return []*ProductDetails{}, err
-		}
-		vol24Hr.FromDecimal(fVol24Hr)
+	return out, nil
+}
 
-		out = append(out, &ProductDetails{
-			ProductID:        prodId,
-			Currency:         respDetail.Currency,
-			BaseCurrency:     respDetail.BaseCurrency,
-			QuotedCurrency:   respDetail.QuotedCurrency,
-			CurrencyPairCode: respDetail.CurrencyPairCode,
-			MarketAsk:        marketAsk,
-			MarketBid:        marketBid,
-			Volume24Hour:     vol24Hr,
-			Disabled:         respDetail.Disabled,
-		})
+// parseProductDetails returns nil for products without a market, which can't be traded.
+func parseProductDetails(respDetail *productsResponse) (*ProductDetails, error) {
+	prodId, err := strconv.Atoi(respDetail.ID)
+	if err != nil {
+		fmt.Println("[parseProductDetails] Error parsing product ID:", err.Error())
+		return nil, err
 	}
 
-	return out, nil
+	if respDetail.MarketAsk == "" {
+		return nil, nil
+	}
+	marketAsk, err := parseExchangeAmount(respDetail.MarketAsk)
+	if err != nil {
+		fmt.Println("[parseProductDetails] Error parsing MarketAsk:", err.Error())
+		return nil, err
+	}
+
+	if respDetail.MarketBid == "" {
+		return nil, nil
+	}
+	marketBid, err := parseExchangeAmount(respDetail.MarketBid)
+	if err != nil {
+		fmt.Println("[parseProductDetails] Error parsing MarketBid:", err.Error())
+		return nil, err
+	}
+
+	vol24Hr, err := parseExchangeAmount(respDetail.Volume24Hr)
+	if err != nil {
+		fmt.Println("[parseProductDetails] Error parsing Volume24Hr:", err.Error())
+		return nil, err
+	}
+
+	priceTick, err := parseOptionalAmount(respDetail.TickSize, MinimalUnit)
+	if err != nil {
+		fmt.Println("[parseProductDetails] Error parsing TickSize:", err.Error())
+		return nil, err
+	}
+
+	qtyStep, err := parseOptionalAmount(respDetail.QuantityStep, MinimalUnit)
+	if err != nil {
+		fmt.Println("[parseProductDetails] Error parsing QuantityStep:", err.Error())
+		return nil, err
+	}
+
+	minQty, err := parseOptionalAmount(respDetail.MinimumQuantity, MinimumOrderQuantity(respDetail.BaseCurrency))
+	if err != nil {
+		fmt.Println("[parseProductDetails] Error parsing MinimumQuantity:", err.Error())
+		return nil, err
+	}
+
+	makerFee, err := parseFeeRate(respDetail.MakerFee)
+	if err != nil {
+		fmt.Println("[parseProductDetails] Error parsing MakerFee:", err.Error())
+		return nil, err
+	}
+
+	takerFee, err := parseFeeRate(respDetail.TakerFee)
+	if err != nil {
+		fmt.Println("[parseProductDetails] Error parsing TakerFee:", err.Error())
+		return nil, err
+	}
+
+	return &ProductDetails{
+		ProductID:        prodId,
+		Currency:         respDetail.Currency,
+		BaseCurrency:     respDetail.BaseCurrency,
+		QuotedCurrency:   respDetail.QuotedCurrency,
+		CurrencyPairCode: respDetail.CurrencyPairCode,
+		MarketAsk:        marketAsk,
+		MarketBid:        marketBid,
+		Volume24Hour:     vol24Hr,
+		Disabled:         respDetail.Disabled,
+		PriceTick:        priceTick,
+		QuantityStep:     qtyStep,
+		MinimumQuantity:  minQty,
+		MakerFee:         makerFee,
+		TakerFee:         takerFee,
+	}, nil
 }
 
 func MinimumOrderQuantity(currency string) Amount {
@@ -146,4 +204,35 @@ type productsResponse struct {
 	MarketBid        string `json:"market_bid"`
 	Volume24Hr       string `json:"volume_24h"`
 	Disabled         bool   `json:"disabled"`
+	TickSize         string `json:"tick_size"`
+	QuantityStep     string `json:"quantity_step"`
+	MinimumQuantity  string `json:"minimum_order_quantity"`
+	MakerFee         string `json:"maker_fee"`
+	TakerFee         string `json:"taker_fee"`
+}
+
+// parseFeeRate treats a missing fee as no fee. Unlike other amounts, fees may be negative: a maker rebate.
+func parseFeeRate(s string) (Amount, error) {
+	if s == "" {
+		return AmountZero, nil
+	}
+
+	return parseExchangeAmount(s)
+}
+
+// parseOptionalAmount falls back to def for fields the exchange leaves blank or zero.
+func parseOptionalAmount(s string, def Amount) (Amount, error) {
+	if s == "" {
+		return def, nil
+	}
+
+	amt, err := parseExchangeAmount(s)
+	if err != nil {
+		return AmountZero, err
+	}
+	if amt <= AmountZero {
+		return def, nil
+	}
+
+	return amt, nil
 }
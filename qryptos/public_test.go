@@ -0,0 +1,126 @@
+package qryptos
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPublicClient_FetchProducts(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if urlPath := r.URL.Path; urlPath != "/products" {
+			t.Errorf("Unexpected request path: %s", urlPath)
+		}
+
+		if version := r.Header.Get("X-Quoine-API-Version"); version != "2" {
+			t.Errorf("Unexpected API version: %s", version)
+		}
+
+		respBody := `
+[
+	{
+		"id": "56",
+		"product_type": "CurrencyPair",
+		"code": "CASH",
+		"market_ask": "0.00010366",
+		"market_bid": "0.00010201",
+		"currency": "BTC",
+		"currency_pair_code": "VZTBTC",
+		"base_currency": "VZT",
+		"quoted_currency": "BTC",
+		"volume_24h": "105373.4500000000",
+		"disabled": false,
+		"maker_fee": "-0.00025",
+		"taker_fee": "0.001"
+	}
+]`
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(respBody))
+	}))
+	defer ts.Close()
+
+	client := NewPublicClient(WithBaseURL(ts.URL))
+
+	products, err := client.FetchProducts()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	if len(products) != 1 {
+		t.Fatalf("Unexpected product count: %d", len(products))
+	}
+
+	product := products[0]
+	if expected := 56; product.ProductID != expected {
+		t.Errorf("Unexpected ID. Expected: %d; Actual: %d.", expected, product.ProductID)
+	}
+	if expected := Amount(10366); product.MarketAsk != expected {
+		t.Errorf("Unexpected market ask. Expected: %d; Actual: %d.", expected, product.MarketAsk)
+	}
+	if expected := Amount(10537345000000); product.Volume24Hour != expected {
+		t.Errorf("Unexpected volume. Expected: %d; Actual: %d.", expected, product.Volume24Hour)
+	}
+	if expected := Amount(-25000); product.MakerFee != expected {
+		t.Errorf("Unexpected maker fee. Expected: %d; Actual: %d.", expected, product.MakerFee)
+	}
+	if expected := Amount(100000); product.TakerFee != expected {
+		t.Errorf("Unexpected taker fee. Expected: %d; Actual: %d.", expected, product.TakerFee)
+	}
+}
+
+func TestPublicClient_FetchProducts_ExtraPrecision(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(`[{"id": "56", "market_ask": "0.00010366", "market_bid": "0.000102014", "volume_24h": "105373.450000005"}]`))
+	}))
+	defer ts.Close()
+
+	products, err := NewPublicClient(WithBaseURL(ts.URL)).FetchProducts()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if len(products) != 1 {
+		t.Fatalf("Unexpected product count: %d", len(products))
+	}
+	if expected := Amount(10201); products[0].MarketBid != expected {
+		t.Errorf("Unexpected market bid. Expected: %d; Actual: %d.", expected, products[0].MarketBid)
+	}
+	if expected := Amount(10537345000001); products[0].Volume24Hour != expected {
+		t.Errorf("Unexpected volume. Expected: %d; Actual: %d.", expected, products[0].Volume24Hour)
+	}
+}
+
+func TestPublicClient_FetchProductsContext_Cancelled(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	client := NewPublicClient(WithBaseURL(ts.URL))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.FetchProductsContext(ctx); err == nil {
+		t.Error("Expected an error from a cancelled request.")
+	}
+}
+
+func TestPublicClient_FetchProducts_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	client := NewPublicClient(WithBaseURL(ts.URL), WithTimeout(50*time.Millisecond), WithRetryPolicy(NoRetries))
+
+	if _, err := client.FetchProducts(); err == nil {
+		t.Error("Expected an error from a request exceeding the timeout.")
+	}
+}
@@ -0,0 +1,20 @@
+package qryptostest
+
+import "github.com/tobyjsullivan/shifty/qryptos"
+
+// VZTBTC returns a fresh copy of the product most tests trade: VZT quoted in BTC at 0.000100/0.000104, with a one
+// satoshi tick, a step of 0.01 VZT and a minimum of 1 VZT.
+func VZTBTC() *qryptos.ProductDetails {
+	return &qryptos.ProductDetails{
+		ProductID:        56,
+		Currency:         "BTC",
+		BaseCurrency:     "VZT",
+		QuotedCurrency:   "BTC",
+		CurrencyPairCode: "VZTBTC",
+		MarketAsk:        qryptos.Amount(10400),
+		MarketBid:        qryptos.Amount(10000),
+		PriceTick:        qryptos.Amount(1),
+		QuantityStep:     qryptos.Amount(1000000),
+		MinimumQuantity:  qryptos.Amount(100000000),
+	}
+}
@@ -0,0 +1,680 @@
+// Package qryptostest runs a stateful, in-process fake of the exchange's REST API for integration tests. Orders are
+// kept by a paper exchange, so they rest, fill against quotes and settle balances as they would on the real venue.
+package qryptostest
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tobyjsullivan/shifty/exchange/paper"
+	"github.com/tobyjsullivan/shifty/qryptos"
+	"github.com/tobyjsullivan/shifty/qryptos/auth"
+)
+
+const (
+	DefaultTokenID = "123456"
+	DefaultSecret  = "secret"
+
+	defaultPageSize = 20
+)
+
+// Fault makes matching requests fail or slow down. Method and Path (a prefix) match everything when empty.
+type Fault struct {
+	Method string
+	Path   string
+
+	// Status is the response status. A zero Status only adds Delay before the request is handled normally.
+	Status int
+	Body   string
+	Delay  time.Duration
+
+	// Times limits the fault to the next Times matching requests. Zero applies it to every matching request.
+	Times int
+}
+
+func (f *Fault) matches(r *http.Request) bool {
+	return (f.Method == "" || f.Method == r.Method) && strings.HasPrefix(r.URL.Path, f.Path)
+}
+
+// RecordedRequest is a request the server has received, before any fault was applied.
+type RecordedRequest struct {
+	Method string
+	Path   string
+	Query  string
+	Body   string
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+func WithCredentials(tokenId, secret string) Option {
+	return func(s *Server) {
+		s.tokenId = tokenId
+		s.secret = secret
+	}
+}
+
+// WithStrictNonces rejects any signed request whose nonce is not greater than the last one seen, as the exchange
+// does.
+func WithStrictNonces() Option {
+	return func(s *Server) {
+		s.strictNonces = true
+	}
+}
+
+// WithPaperOptions configures the underlying paper exchange, for example to set fees.
+func WithPaperOptions(opts ...paper.Option) Option {
+	return func(s *Server) {
+		s.paperOpts = append(s.paperOpts, opts...)
+	}
+}
+
+// Server is a fake of the REST API. Point clients at it with qryptos.WithBaseURL(server.URL).
+type Server struct {
+	*httptest.Server
+
+	tokenId      string
+	secret       string
+	strictNonces bool
+	paperOpts    []paper.Option
+	exchange     *paper.Exchange
+
+	mu        sync.Mutex
+	faults    []*Fault
+	requests  []RecordedRequest
+	lastNonce int64
+}
+
+// NewServer starts a server trading the given products, with an account holding the given balances. Call Close
+// when done.
+func NewServer(products []*qryptos.ProductDetails, balances map[string]qryptos.Amount, opts ...Option) *Server {
+	s := &Server{
+		tokenId: DefaultTokenID,
+		secret:  DefaultSecret,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.exchange = paper.New(products, balances, s.paperOpts...)
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+// Exchange is the simulated venue behind the server. Apply quotes and trades to it to fill resting orders.
+func (s *Server) Exchange() *paper.Exchange {
+	return s.exchange
+}
+
+// Inject adds a fault. Faults are checked in the order they were added.
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = append(s.faults, &f)
+}
+
+// RateLimit makes the next n requests fail with 429 Too Many Requests.
+func (s *Server) RateLimit(n int) {
+	s.Inject(Fault{
+		Status: http.StatusTooManyRequests,
+		Body:   `{"message":"Too many requests"}`,
+		Times:  n,
+	})
+}
+
+// SetLatency delays every request by d. It replaces any latency set before.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, f := range s.faults {
+		if f.Status == 0 && f.Method == "" && f.Path == "" && f.Times == 0 {
+			s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			break
+		}
+	}
+	s.faults = append(s.faults, &Fault{Delay: d})
+}
+
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = nil
+}
+
+// Requests returns every request received so far.
+func (s *Server) Requests() []RecordedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]RecordedRequest, len(s.requests))
+	copy(out, s.requests)
+
+	return out
+}
+
+// takeFault records the request and returns the faults that apply to it, using up limited faults.
+func (s *Server) takeFault(r *http.Request, body []byte) (time.Duration, *Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, RecordedRequest{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.RawQuery,
+		Body:   string(body),
+	})
+
+	var delay time.Duration
+	var failure *Fault
+	remaining := s.faults[:0]
+	for _, f := range s.faults {
+		used := false
+		if f.matches(r) && (f.Status == 0 || failure == nil) {
+			delay += f.Delay
+			if f.Status != 0 {
+				failure = f
+			}
+			used = true
+		}
+
+		if used && f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				continue
+			}
+		}
+		remaining = append(remaining, f)
+	}
+	s.faults = remaining
+
+	return delay, failure
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeMessage(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
+
+// writeError renders errors from the paper exchange the way the real API would.
+func writeError(w http.ResponseWriter, err error) {
+	switch e := err.(type) {
+	case *qryptos.APIError:
+		writeJSON(w, e.StatusCode, map[string]interface{}{
+			"code":    e.Code,
+			"message": e.Message,
+			"errors":  e.Errors,
+		})
+	case *qryptos.OrderRequestError:
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"errors": map[string][]string{e.Field: {e.Reason}},
+		})
+	default:
+		writeMessage(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeMessage(w, http.StatusBadRequest, "Unreadable body")
+		return
+	}
+
+	delay, failure := s.takeFault(r, body)
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if failure != nil {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(failure.Status)
+		w.Write([]byte(failure.Body))
+		return
+	}
+
+	if r.Header.Get(auth.HeaderAPIVersion) != auth.APIVersion {
+		writeMessage(w, http.StatusBadRequest, "Unsupported API version")
+		return
+	}
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/products":
+		s.handleProducts(w, r)
+		return
+	case r.Method == http.MethodGet && len(segments) == 3 && segments[0] == "products" && segments[2] == "price_levels":
+		s.handlePriceLevels(w, r, segments[1])
+		return
+	case r.Method == http.MethodGet && r.URL.Path == "/executions":
+		s.handleTrades(w, r)
+		return
+	}
+
+	if !s.authenticate(w, r) {
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/accounts/balance" && r.Method == http.MethodGet:
+		s.handleBalances(w, r)
+	case r.URL.Path == "/orders" && r.Method == http.MethodGet:
+		s.handleListOrders(w, r)
+	case r.URL.Path == "/orders" && r.Method == http.MethodPost:
+		s.handleCreateOrder(w, r, body)
+	case len(segments) == 2 && segments[0] == "orders" && r.Method == http.MethodGet:
+		s.handleGetOrder(w, r, segments[1])
+	case len(segments) == 2 && segments[0] == "orders" && r.Method == http.MethodPut:
+		s.handleEditOrder(w, r, segments[1], body)
+	case len(segments) == 3 && segments[0] == "orders" && segments[2] == "cancel" && r.Method == http.MethodPut:
+		s.handleCancelOrder(w, r, segments[1])
+	default:
+		writeMessage(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// authenticate checks the X-Quoine-Auth token: its signature, token ID, path and, when strict, its nonce.
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request) bool {
+	tokenString := r.Header.Get(auth.HeaderAuth)
+	if tokenString == "" {
+		writeMessage(w, http.StatusUnauthorized, "Missing "+auth.HeaderAuth)
+		return false
+	}
+
+	claims, err := auth.Verify(tokenString, s.secret)
+	if err == auth.ErrInvalidNonce {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"code": "invalid_nonce", "message": "Nonce is not a number"})
+		return false
+	}
+	if err != nil {
+		writeMessage(w, http.StatusUnauthorized, "Invalid signature")
+		return false
+	}
+	if claims.TokenID != s.tokenId {
+		writeMessage(w, http.StatusUnauthorized, "Unknown token_id")
+		return false
+	}
+	if claims.Path != r.URL.RequestURI() {
+		writeMessage(w, http.StatusUnauthorized, "Path does not match request")
+		return false
+	}
+	nonce := claims.Nonce
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.strictNonces && nonce <= s.lastNonce {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"code": "invalid_nonce", "message": "Nonce is too small"})
+		return false
+	}
+	if nonce > s.lastNonce {
+		s.lastNonce = nonce
+	}
+
+	return true
+}
+
+type productModel struct {
+	ID                   string         `json:"id"`
+	ProductType          string         `json:"product_type"`
+	Code                 string         `json:"code"`
+	Currency             string         `json:"currency"`
+	CurrencyPairCode     string         `json:"currency_pair_code"`
+	BaseCurrency         string         `json:"base_currency"`
+	QuotedCurrency       string         `json:"quoted_currency"`
+	MarketAsk            qryptos.Amount `json:"market_ask"`
+	MarketBid            qryptos.Amount `json:"market_bid"`
+	Volume24Hr           qryptos.Amount `json:"volume_24h"`
+	Disabled             bool           `json:"disabled"`
+	TickSize             qryptos.Amount `json:"tick_size"`
+	QuantityStep         qryptos.Amount `json:"quantity_step"`
+	MinimumOrderQuantity qryptos.Amount `json:"minimum_order_quantity"`
+	MakerFee             qryptos.Amount `json:"maker_fee"`
+	TakerFee             qryptos.Amount `json:"taker_fee"`
+}
+
+func (s *Server) handleProducts(w http.ResponseWriter, r *http.Request) {
+	products, err := s.exchange.FetchProductsContext(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	out := make([]*productModel, len(products))
+	for i, p := range products {
+		out[i] = &productModel{
+			ID:                   strconv.Itoa(p.ProductID),
+			ProductType:          "CurrencyPair",
+			Code:                 "CASH",
+			Currency:             p.Currency,
+			CurrencyPairCode:     p.CurrencyPairCode,
+			BaseCurrency:         p.BaseCurrency,
+			QuotedCurrency:       p.QuotedCurrency,
+			MarketAsk:            p.MarketAsk,
+			MarketBid:            p.MarketBid,
+			Volume24Hr:           p.Volume24Hour,
+			Disabled:             p.Disabled,
+			TickSize:             p.PriceTick,
+			QuantityStep:         p.QuantityStep,
+			MinimumOrderQuantity: p.MinimumQuantity,
+			MakerFee:             p.MakerFee,
+			TakerFee:             p.TakerFee,
+		}
+	}
+
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handlePriceLevels(w http.ResponseWriter, r *http.Request, id string) {
+	productId, err := strconv.Atoi(id)
+	if err != nil {
+		writeMessage(w, http.StatusNotFound, "Product not found")
+		return
+	}
+
+	depth := 20
+	if r.URL.Query().Get("full") == "1" {
+		depth = 0
+	}
+
+	book, err := s.exchange.FetchOrderBookContext(r.Context(), productId, depth)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	levels := func(in []qryptos.PriceLevel) [][]qryptos.Amount {
+		out := make([][]qryptos.Amount, len(in))
+		for i, level := range in {
+			out[i] = []qryptos.Amount{level.Price, level.Quantity}
+		}
+		return out
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"buy_price_levels":  levels(book.Bids),
+		"sell_price_levels": levels(book.Asks),
+	})
+}
+
+type tradeModel struct {
+	ID        int            `json:"id"`
+	Quantity  qryptos.Amount `json:"quantity"`
+	Price     qryptos.Amount `json:"price"`
+	TakerSide string         `json:"taker_side"`
+	CreatedAt int64          `json:"created_at"`
+}
+
+// handleTrades lists the public trade tape from a timestamp, oldest first, as the exchange does when given one.
+func (s *Server) handleTrades(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	productId, _ := strconv.Atoi(query.Get("product_id"))
+	timestamp, _ := strconv.ParseInt(query.Get("timestamp"), 10, 64)
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultPageSize
+	}
+
+	trades, err := s.exchange.FetchExecutionsContext(r.Context(), productId, time.Unix(timestamp, 0))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if len(trades) > limit {
+		trades = trades[:limit]
+	}
+
+	out := make([]*tradeModel, len(trades))
+	for i, t := range trades {
+		out[i] = &tradeModel{
+			ID:        t.ID,
+			Quantity:  t.Quantity,
+			Price:     t.Price,
+			TakerSide: t.TakerSide,
+			CreatedAt: t.CreatedAt.Unix(),
+		}
+	}
+
+	writeJSON(w, http.StatusOK, out)
+}
+
+type balanceModel struct {
+	Currency string         `json:"currency"`
+	Balance  qryptos.Amount `json:"balance"`
+}
+
+func (s *Server) handleBalances(w http.ResponseWriter, r *http.Request) {
+	balances, err := s.exchange.FetchAccountBalancesContext(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	out := make([]*balanceModel, len(balances))
+	for i, b := range balances {
+		out[i] = &balanceModel{Currency: b.Currency, Balance: b.Balance}
+	}
+
+	writeJSON(w, http.StatusOK, out)
+}
+
+type executionModel struct {
+	ID       int            `json:"id"`
+	Quantity qryptos.Amount `json:"quantity"`
+	Price    qryptos.Amount `json:"price"`
+}
+
+type orderModel struct {
+	ID               int               `json:"id"`
+	OrderType        string            `json:"order_type"`
+	ProductID        int               `json:"product_id"`
+	Side             string            `json:"side"`
+	Status           string            `json:"status"`
+	CurrencyPairCode string            `json:"currency_pair_code"`
+	FundingCurrency  string            `json:"funding_currency"`
+	Price            qryptos.Amount    `json:"price"`
+	Quantity         qryptos.Amount    `json:"quantity"`
+	FilledQuantity   qryptos.Amount    `json:"filled_quantity"`
+	Executions       []*executionModel `json:"executions,omitempty"`
+	CreatedAt        int64             `json:"created_at"`
+	UpdatedAt        int64             `json:"updated_at"`
+}
+
+func newOrderModel(o *qryptos.OrderDetails, withDetails bool) *orderModel {
+	model := &orderModel{
+		ID:               o.ID,
+		OrderType:        qryptos.OrderTypeLimit,
+		ProductID:        o.ProductID,
+		Side:             o.Side,
+		Status:           o.Status,
+		CurrencyPairCode: o.CurrencyPairCode,
+		FundingCurrency:  o.QuoteCurrency,
+		Price:            o.Price,
+		Quantity:         o.Quantity,
+		FilledQuantity:   o.FilledQuantity,
+		CreatedAt:        o.CreatedAt.Unix(),
+		UpdatedAt:        o.UpdatedAt.Unix(),
+	}
+	if withDetails {
+		model.Executions = make([]*executionModel, len(o.Executions))
+		for i, e := range o.Executions {
+			model.Executions[i] = &executionModel{ID: e.ID, Quantity: e.Quantity, Price: e.Price}
+		}
+	}
+
+	return model
+}
+
+func (s *Server) handleListOrders(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	q := &qryptos.OrdersQuery{
+		Status:          params.Get("status"),
+		FundingCurrency: params.Get("funding_currency"),
+	}
+	if productId, err := strconv.Atoi(params.Get("product_id")); err == nil {
+		q.ProductID = productId
+	}
+
+	orders, err := s.exchange.FetchAllOrdersContext(r.Context(), q)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	limit, err := strconv.Atoi(params.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultPageSize
+	}
+	page, err := strconv.Atoi(params.Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	totalPages := (len(orders) + limit - 1) / limit
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
+	models := []*orderModel{}
+	for i := (page - 1) * limit; i < page*limit && i < len(orders); i++ {
+		models = append(models, newOrderModel(orders[i], params.Get("with_details") == "1"))
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"models":       models,
+		"current_page": page,
+		"total_pages":  totalPages,
+	})
+}
+
+func (s *Server) findOrder(ctx context.Context, id string) (*qryptos.OrderDetails, error) {
+	orderId, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, nil
+	}
+
+	orders, err := s.exchange.FetchAllOrdersContext(ctx, &qryptos.OrdersQuery{})
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range orders {
+		if o.ID == orderId {
+			return o, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *Server) writeOrder(w http.ResponseWriter, r *http.Request, id string) {
+	order, err := s.findOrder(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if order == nil {
+		writeMessage(w, http.StatusNotFound, "Order not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newOrderModel(order, true))
+}
+
+func (s *Server) handleGetOrder(w http.ResponseWriter, r *http.Request, id string) {
+	s.writeOrder(w, r, id)
+}
+
+type createOrderBody struct {
+	Order struct {
+		OrderType string         `json:"order_type"`
+		ProductID int            `json:"product_id"`
+		Side      string         `json:"side"`
+		Quantity  qryptos.Amount `json:"quantity"`
+		Price     qryptos.Amount `json:"price"`
+		StopPrice qryptos.Amount `json:"stop_price"`
+		PostOnly  bool           `json:"post_only"`
+	} `json:"order"`
+}
+
+func (s *Server) handleCreateOrder(w http.ResponseWriter, r *http.Request, body []byte) {
+	var parsed createOrderBody
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		writeMessage(w, http.StatusBadRequest, "Malformed order")
+		return
+	}
+
+	orderId, err := s.exchange.CreateOrderContext(r.Context(), &qryptos.OrderRequest{
+		ProductID: parsed.Order.ProductID,
+		Type:      parsed.Order.OrderType,
+		Side:      parsed.Order.Side,
+		Quantity:  parsed.Order.Quantity,
+		Price:     parsed.Order.Price,
+		StopPrice: parsed.Order.StopPrice,
+		PostOnly:  parsed.Order.PostOnly,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.writeOrder(w, r, strconv.Itoa(orderId))
+}
+
+type editOrderBody struct {
+	Order struct {
+		Quantity qryptos.Amount `json:"quantity"`
+		Price    qryptos.Amount `json:"price"`
+	} `json:"order"`
+}
+
+func (s *Server) handleEditOrder(w http.ResponseWriter, r *http.Request, id string, body []byte) {
+	orderId, err := strconv.Atoi(id)
+	if err != nil {
+		writeMessage(w, http.StatusNotFound, "Order not found")
+		return
+	}
+
+	var parsed editOrderBody
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		writeMessage(w, http.StatusBadRequest, "Malformed order")
+		return
+	}
+
+	if err := s.exchange.EditOrderContext(r.Context(), orderId, parsed.Order.Quantity, parsed.Order.Price); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.writeOrder(w, r, id)
+}
+
+func (s *Server) handleCancelOrder(w http.ResponseWriter, r *http.Request, id string) {
+	orderId, err := strconv.Atoi(id)
+	if err != nil {
+		writeMessage(w, http.StatusNotFound, "Order not found")
+		return
+	}
+
+	if err := s.exchange.CancelOrderContext(r.Context(), orderId); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.writeOrder(w, r, id)
+}
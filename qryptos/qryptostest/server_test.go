@@ -0,0 +1,228 @@
+package qryptostest
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/tobyjsullivan/shifty/qryptos"
+)
+
+func newTestServer(opts ...Option) *Server {
+	return NewServer([]*qryptos.ProductDetails{VZTBTC()}, map[string]qryptos.Amount{"BTC": qryptos.Amount(1000000)}, opts...)
+}
+
+func newTestClient(s *Server, opts ...qryptos.ClientOption) *qryptos.PrivateClient {
+	opts = append([]qryptos.ClientOption{
+		qryptos.WithBaseURL(s.URL),
+		qryptos.WithRateLimiter(nil),
+		qryptos.WithRetryPolicy(qryptos.NoRetries),
+	}, opts...)
+
+	return qryptos.NewPrivateClient(DefaultTokenID, DefaultSecret, opts...)
+}
+
+func TestServer_OrderLifecycle(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+	client := newTestClient(s)
+
+	orderId, err := client.CreateLimitOrder(56, qryptos.OrderSideBuy, qryptos.Amount(5000000000), qryptos.Amount(9900))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	if err := client.EditOrder(orderId, qryptos.Amount(6000000000), qryptos.Amount(9800)); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	order, err := client.FetchOrder(orderId)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if order.Status != qryptos.OrderStatusLive || order.Price != qryptos.Amount(9800) || order.Quantity != qryptos.Amount(6000000000) {
+		t.Errorf("Unexpected order after edit: %+v", order)
+	}
+	if order.QuoteCurrency != "BTC" || order.CurrencyPairCode != "VZTBTC" {
+		t.Errorf("Unexpected order currencies: %+v", order)
+	}
+
+	if err := client.CancelOrder(orderId); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	order, err = client.FetchOrder(orderId)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if order.Status != qryptos.OrderStatusCancelled {
+		t.Errorf("Unexpected status. Expected: %s; Actual: %s.", qryptos.OrderStatusCancelled, order.Status)
+	}
+
+	if err := client.CancelOrder(999); !qryptos.IsOrderNotFound(err) {
+		t.Errorf("Expected an order not found error; Actual: %v.", err)
+	}
+}
+
+func TestServer_FillsAndBalances(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+	client := newTestClient(s)
+
+	orderId, err := client.CreateLimitOrder(56, qryptos.OrderSideBuy, qryptos.Amount(5000000000), qryptos.Amount(10000))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	// Only 0.005 BTC remains free
+	_, err = client.CreateLimitOrder(56, qryptos.OrderSideBuy, qryptos.Amount(6000000000), qryptos.Amount(10000))
+	if !qryptos.IsInsufficientFunds(err) {
+		t.Errorf("Expected an insufficient funds error; Actual: %v.", err)
+	}
+
+	s.Exchange().ApplyTrade(&qryptos.Trade{ProductID: 56, Price: qryptos.Amount(10000), Quantity: qryptos.Amount(2000000000), TakerSide: qryptos.OrderSideSell})
+
+	order, err := client.FetchOrder(orderId)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if expected := qryptos.Amount(2000000000); order.FilledQuantity != expected {
+		t.Errorf("Unexpected filled quantity. Expected: %d; Actual: %d.", expected, order.FilledQuantity)
+	}
+	if len(order.Executions) != 1 {
+		t.Errorf("Unexpected execution count. Expected: 1; Actual: %d.", len(order.Executions))
+	}
+
+	balances, err := client.FetchAccountBalances()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	for _, b := range balances {
+		if b.Currency == "VZT" && b.Balance != qryptos.Amount(2000000000) {
+			t.Errorf("Unexpected VZT balance. Expected: %d; Actual: %d.", qryptos.Amount(2000000000), b.Balance)
+		}
+	}
+}
+
+func TestServer_TradeTape(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+	client := newTestClient(s)
+	public := qryptos.NewPublicClient(qryptos.WithBaseURL(s.URL), qryptos.WithRateLimiter(nil))
+	since := time.Now().Add(-time.Minute)
+
+	if _, err := client.CreateLimitOrder(56, qryptos.OrderSideBuy, qryptos.Amount(1000000000), qryptos.Amount(10400)); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	trades, err := public.FetchExecutions(56, since)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if len(trades) != 1 {
+		t.Fatalf("Unexpected trade count. Expected: 1; Actual: %d.", len(trades))
+	}
+	if trades[0].Price != qryptos.Amount(10400) || trades[0].TakerSide != qryptos.OrderSideBuy {
+		t.Errorf("Unexpected trade: %+v", trades[0])
+	}
+}
+
+func TestServer_Pagination(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+	client := newTestClient(s)
+
+	for i := 0; i < 5; i++ {
+		if _, err := client.CreateLimitOrder(56, qryptos.OrderSideBuy, qryptos.Amount(100000000), qryptos.Amount(9000+i)); err != nil {
+			t.Fatalf("Unexpected error: %s", err.Error())
+		}
+	}
+
+	orders, err := client.FetchAllOrders(&qryptos.OrdersQuery{Status: qryptos.OrderStatusLive, PageSize: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if len(orders) != 5 {
+		t.Fatalf("Unexpected order count. Expected: 5; Actual: %d.", len(orders))
+	}
+	if orders[0].Price != qryptos.Amount(9004) {
+		t.Errorf("Expected newest order first: %+v", orders[0])
+	}
+
+	pages := 0
+	for _, r := range s.Requests() {
+		if r.Method == http.MethodGet && r.Path == "/orders" {
+			pages++
+		}
+	}
+	if pages != 3 {
+		t.Errorf("Unexpected page requests. Expected: 3; Actual: %d.", pages)
+	}
+}
+
+func TestServer_RejectsBadCredentials(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+
+	client := qryptos.NewPrivateClient(DefaultTokenID, "wrong", qryptos.WithBaseURL(s.URL), qryptos.WithRateLimiter(nil), qryptos.WithRetryPolicy(qryptos.NoRetries))
+	_, err := client.FetchAccountBalances()
+	apiErr, ok := err.(*qryptos.APIError)
+	if !ok || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected a 401 error; Actual: %v.", err)
+	}
+}
+
+func TestServer_StrictNonces(t *testing.T) {
+	s := newTestServer(WithStrictNonces())
+	defer s.Close()
+
+	// Separate clients on the same token share a nonce source, so requests within the same millisecond don't collide
+	clients := []*qryptos.PrivateClient{newTestClient(s), newTestClient(s)}
+	for i := 0; i < 50; i++ {
+		if _, err := clients[i%2].FetchAccountBalances(); err != nil {
+			t.Fatalf("Unexpected error: %s", err.Error())
+		}
+	}
+}
+
+func TestServer_RateLimitIsRetried(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+	client := newTestClient(s, qryptos.WithRetryPolicy(qryptos.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}))
+
+	s.RateLimit(2)
+	if _, err := client.FetchAccountBalances(); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if stats := client.Stats(); stats.Retries != 2 {
+		t.Errorf("Unexpected retries. Expected: 2; Actual: %d.", stats.Retries)
+	}
+
+	s.RateLimit(3)
+	if _, err := client.FetchAccountBalances(); !qryptos.IsRateLimited(err) {
+		t.Errorf("Expected a rate limit error; Actual: %v.", err)
+	}
+}
+
+func TestServer_InjectedFailureAndLatency(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+	client := newTestClient(s, qryptos.WithTimeout(50*time.Millisecond))
+
+	s.Inject(Fault{Method: http.MethodPost, Path: "/orders", Status: http.StatusBadGateway, Times: 1})
+	if _, err := client.CreateLimitOrder(56, qryptos.OrderSideBuy, qryptos.Amount(100000000), qryptos.Amount(9000)); err == nil {
+		t.Error("Expected the injected failure.")
+	}
+	if _, err := client.CreateLimitOrder(56, qryptos.OrderSideBuy, qryptos.Amount(100000000), qryptos.Amount(9000)); err != nil {
+		t.Errorf("Unexpected error after the fault was used up: %s", err.Error())
+	}
+
+	s.SetLatency(200 * time.Millisecond)
+	if _, err := client.FetchAccountBalances(); err == nil {
+		t.Error("Expected a timeout.")
+	}
+
+	s.ClearFaults()
+	if _, err := client.FetchAccountBalances(); err != nil {
+		t.Errorf("Unexpected error: %s", err.Error())
+	}
+}
@@ -0,0 +1,82 @@
+package qryptos
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// The exchange allows 300 requests per 5 minutes for each API token
+	defaultRequestsPerSecond = 1.0
+	defaultBurst             = 10
+)
+
+// DefaultRateLimiter is shared by every client that isn't given its own limiter through WithRateLimiter.
+var DefaultRateLimiter = NewRateLimiter(defaultRequestsPerSecond, defaultBurst)
+
+// RateLimiter is a token bucket that is safe to share between clients and goroutines.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter allows requestsPerSecond on average with bursts of up to burst requests.
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		rate:   requestsPerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token and returns how long the caller must wait before using it.
+func (l *RateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// cancelReservation returns a token taken by reserve when the caller gave up waiting for it.
+func (l *RateLimiter) cancelReservation() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.tokens++
+}
+
+// Wait blocks until a request may be made. It reports whether the caller was throttled.
+func (l *RateLimiter) Wait(ctx context.Context) (bool, error) {
+	delay := l.reserve()
+	if delay <= 0 {
+		return false, nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true, nil
+	case <-ctx.Done():
+		l.cancelReservation()
+		return true, ctx.Err()
+	}
+}
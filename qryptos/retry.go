@@ -0,0 +1,70 @@
+package qryptos
+
+import (
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// RetryPolicy controls how idempotent requests (GETs and cancels) are retried after rate limiting, server errors
+// and transport failures. Order creation and edits are never retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first. Values below 2 disable retries.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var (
+	DefaultRetryPolicy = RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    5 * time.Second,
+	}
+
+	NoRetries = RetryPolicy{MaxAttempts: 1}
+)
+
+// backoff returns the delay before the given retry (1 for the first retry) using exponential backoff with jitter.
+// The jitter keeps concurrent goroutines that failed together from retrying in lockstep.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// ClientStats counts how often a client has had to slow down.
+type ClientStats struct {
+	Requests  uint64
+	Retries   uint64
+	Throttled uint64
+}
+
+type clientStats struct {
+	requests  uint64
+	retries   uint64
+	throttled uint64
+}
+
+func (s *clientStats) snapshot() ClientStats {
+	return ClientStats{
+		Requests:  atomic.LoadUint64(&s.requests),
+		Retries:   atomic.LoadUint64(&s.retries),
+		Throttled: atomic.LoadUint64(&s.throttled),
+	}
+}
@@ -0,0 +1,668 @@
+package qryptos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/tobyjsullivan/shifty/qryptos/auth"
+)
+
+const (
+	defaultStreamUrl = "wss://ws.pusherapp.com/app/2ff981bb060680b5ce97?protocol=7&client=shifty&version=1.0"
+
+	defaultReconnectDelay    = time.Second
+	defaultMaxReconnectDelay = 30 * time.Second
+	defaultStreamBuffer      = 256
+
+	// The server tells us its activity timeout on connect. These apply until it does.
+	defaultActivityTimeout = 120 * time.Second
+	pongTimeout            = 30 * time.Second
+	streamDialTimeout      = 30 * time.Second
+
+	streamAuthPath = "/realtime"
+
+	pusherConnectionEstablished = "pusher:connection_established"
+	pusherSubscribe             = "pusher:subscribe"
+	pusherSubscriptionSucceeded = "pusher_internal:subscription_succeeded"
+	pusherPing                  = "pusher:ping"
+	pusherPong                  = "pusher:pong"
+	pusherError                 = "pusher:error"
+
+	quoineAuthRequest = "quoine:auth_request"
+	quoineAuthSuccess = "quoine:auth_success"
+	quoineAuthFailure = "quoine:auth_failure"
+)
+
+var (
+	ErrStreamCredentialsRequired = errors.New("stream credentials are required for account channels")
+	ErrStreamAuthFailed          = errors.New("stream authentication failed")
+	ErrStreamPingTimeout         = errors.New("stream did not answer ping")
+)
+
+// StreamEvent is delivered on Stream.Events. Use a type switch to handle the events you care about.
+type StreamEvent interface {
+	// Channel is the Pusher channel the event arrived on, or "" for connection events.
+	Channel() string
+}
+
+// ConnectedEvent is sent each time the stream (re)connects.
+type ConnectedEvent struct {
+	SocketID string
+}
+
+func (e *ConnectedEvent) Channel() string { return "" }
+
+// DisconnectedEvent is sent when the connection drops. The stream reconnects on its own.
+type DisconnectedEvent struct {
+	Err error
+}
+
+func (e *DisconnectedEvent) Channel() string { return "" }
+
+// GapEvent is sent when a channel is resubscribed after a reconnect. Updates published between Since and Until were
+// missed, so consumers should refresh any state built from the channel through the REST API.
+type GapEvent struct {
+	ChannelName string
+	Since       time.Time
+	Until       time.Time
+}
+
+func (e *GapEvent) Channel() string { return e.ChannelName }
+
+type TickerEvent struct {
+	ChannelName string
+	Product     *ProductDetails
+}
+
+func (e *TickerEvent) Channel() string { return e.ChannelName }
+
+// OrderBookEvent replaces one side of the book. Levels are sorted best first, as in OrderBook.
+type OrderBookEvent struct {
+	ChannelName      string
+	CurrencyPairCode string
+	Side             string
+	Levels           []PriceLevel
+}
+
+func (e *OrderBookEvent) Channel() string { return e.ChannelName }
+
+type OrderEvent struct {
+	ChannelName string
+	Order       *OrderDetails
+}
+
+func (e *OrderEvent) Channel() string { return e.ChannelName }
+
+type ExecutionEvent struct {
+	ChannelName string
+	Execution   *Execution
+}
+
+func (e *ExecutionEvent) Channel() string { return e.ChannelName }
+
+func TickerChannel(productId int, currencyPairCode string) string {
+	return fmt.Sprintf("product_cash_%s_%d", strings.ToLower(currencyPairCode), productId)
+}
+
+// OrderBookChannel carries the buy (bids) or sell (asks) side of a product's book.
+func OrderBookChannel(currencyPairCode, side string) string {
+	return fmt.Sprintf("price_ladders_cash_%s_%s", strings.ToLower(currencyPairCode), side)
+}
+
+func OrdersChannel(fundingCurrency string) string {
+	return fmt.Sprintf("user_account_%s_orders", strings.ToLower(fundingCurrency))
+}
+
+func ExecutionsChannel(currencyPairCode string) string {
+	return fmt.Sprintf("user_executions_cash_%s", strings.ToLower(currencyPairCode))
+}
+
+// StreamOption configures a Stream.
+type StreamOption func(*streamConfig)
+
+type streamConfig struct {
+	url               string
+	reconnectDelay    time.Duration
+	maxReconnectDelay time.Duration
+	signer            *auth.Signer
+	bufferSize        int
+}
+
+// WithStreamURL points the stream at a different websocket endpoint, such as a local test server.
+func WithStreamURL(url string) StreamOption {
+	return func(cfg *streamConfig) {
+		cfg.url = url
+	}
+}
+
+// WithReconnectDelay sets the first delay before reconnecting. It doubles on each failed attempt up to max.
+func WithReconnectDelay(delay, max time.Duration) StreamOption {
+	return func(cfg *streamConfig) {
+		cfg.reconnectDelay = delay
+		cfg.maxReconnectDelay = max
+	}
+}
+
+// WithStreamCredentials authenticates the stream so it can subscribe to account channels.
+func WithStreamCredentials(apiTokenID, apiSecretKey string) StreamOption {
+	return func(cfg *streamConfig) {
+		cfg.signer = auth.NewSigner(auth.Static(apiTokenID, apiSecretKey), nil)
+	}
+}
+
+// WithStreamBuffer sets how many events may be queued before the stream stops reading from the connection.
+func WithStreamBuffer(size int) StreamOption {
+	return func(cfg *streamConfig) {
+		cfg.bufferSize = size
+	}
+}
+
+type subscription struct {
+	channel string
+	private bool
+	parse   func(channel string, data []byte) (StreamEvent, error)
+
+	// confirmed is reset on every new connection. wasConfirmed stays set so a later confirmation is known to be a
+	// resubscription.
+	confirmed    bool
+	wasConfirmed bool
+}
+
+// Stream is a client for the exchange's Pusher websocket feed. Subscribe to channels, then call Run and consume
+// Events until the context is cancelled. Run must only be called once per Stream.
+type Stream struct {
+	cfg    streamConfig
+	events chan StreamEvent
+
+	mu             sync.Mutex
+	subs           map[string]*subscription
+	conn           *streamConn
+	authenticated  bool
+	lastDisconnect time.Time
+}
+
+func NewStream(opts ...StreamOption) *Stream {
+	cfg := streamConfig{
+		url:               defaultStreamUrl,
+		reconnectDelay:    defaultReconnectDelay,
+		maxReconnectDelay: defaultMaxReconnectDelay,
+		bufferSize:        defaultStreamBuffer,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &Stream{
+		cfg:    cfg,
+		events: make(chan StreamEvent, cfg.bufferSize),
+		subs:   make(map[string]*subscription),
+	}
+}
+
+// Events is closed when Run returns. The stream blocks when the buffer is full, so consumers must keep up.
+func (s *Stream) Events() <-chan StreamEvent {
+	return s.events
+}
+
+func (s *Stream) SubscribeTicker(productId int, currencyPairCode string) error {
+	return s.subscribe(&subscription{
+		channel: TickerChannel(productId, currencyPairCode),
+		parse:   parseTickerEvent,
+	})
+}
+
+// SubscribeOrderBook subscribes to both sides of a product's book.
+func (s *Stream) SubscribeOrderBook(currencyPairCode string) error {
+	for _, side := range []string{OrderSideBuy, OrderSideSell} {
+		side := side
+		err := s.subscribe(&subscription{
+			channel: OrderBookChannel(currencyPairCode, side),
+			parse: func(channel string, data []byte) (StreamEvent, error) {
+				return parseOrderBookEvent(channel, currencyPairCode, side, data)
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SubscribeOrders follows updates to the account's orders funded in the given currency.
+func (s *Stream) SubscribeOrders(fundingCurrency string) error {
+	return s.subscribe(&subscription{
+		channel: OrdersChannel(fundingCurrency),
+		private: true,
+		parse:   parseOrderEvent,
+	})
+}
+
+// SubscribeExecutions follows the account's own fills on a product.
+func (s *Stream) SubscribeExecutions(productId int, currencyPairCode string) error {
+	return s.subscribe(&subscription{
+		channel: ExecutionsChannel(currencyPairCode),
+		private: true,
+		parse: func(channel string, data []byte) (StreamEvent, error) {
+			return parseExecutionEvent(channel, productId, data)
+		},
+	})
+}
+
+func (s *Stream) subscribe(sub *subscription) error {
+	if sub.private && s.cfg.signer == nil {
+		return ErrStreamCredentialsRequired
+	}
+
+	s.mu.Lock()
+	if _, ok := s.subs[sub.channel]; ok {
+		s.mu.Unlock()
+		return nil
+	}
+	s.subs[sub.channel] = sub
+	conn := s.conn
+	ready := conn != nil && (!sub.private || s.authenticated)
+	s.mu.Unlock()
+
+	// Anything that can't be sent now is sent when the connection (re)opens
+	if ready {
+		if err := sendPusherMessage(conn, pusherSubscribe, "", map[string]string{"channel": sub.channel}); err != nil {
+			fmt.Println("[Stream] Error subscribing to", sub.channel, "-", err.Error())
+		}
+	}
+
+	return nil
+}
+
+type pusherMessage struct {
+	Event   string          `json:"event"`
+	Channel string          `json:"channel,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// payload unwraps the data field, which Pusher usually sends as a JSON-encoded string.
+func (m *pusherMessage) payload() []byte {
+	var encoded string
+	if err := json.Unmarshal(m.Data, &encoded); err == nil {
+		return []byte(encoded)
+	}
+	return m.Data
+}
+
+// streamConn serialises writes, which websocket.Conn leaves to the caller. Subscriptions are written from the
+// caller's goroutine while Run writes pings and pongs.
+type streamConn struct {
+	*websocket.Conn
+
+	writeMu sync.Mutex
+}
+
+func (c *streamConn) WriteMessage(messageType int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	return c.Conn.WriteMessage(messageType, data)
+}
+
+type messageWriter interface {
+	WriteMessage(messageType int, data []byte) error
+}
+
+func sendPusherMessage(conn messageWriter, event, channel string, data interface{}) error {
+	encodedData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	msg, err := json.Marshal(&pusherMessage{Event: event, Channel: channel, Data: encodedData})
+	if err != nil {
+		return err
+	}
+
+	return conn.WriteMessage(websocket.TextMessage, msg)
+}
+
+// Run connects and delivers events until the context is cancelled, reconnecting with backoff whenever the
+// connection drops. It always returns the context's error.
+func (s *Stream) Run(ctx context.Context) error {
+	defer close(s.events)
+
+	delay := s.cfg.reconnectDelay
+	for {
+		connected, err := s.runConnection(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if connected {
+			delay = s.cfg.reconnectDelay
+		}
+
+		fmt.Println("[Stream] Disconnected:", err.Error())
+		s.emit(ctx, &DisconnectedEvent{Err: err})
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+
+		delay *= 2
+		if delay > s.cfg.maxReconnectDelay {
+			delay = s.cfg.maxReconnectDelay
+		}
+	}
+}
+
+func (s *Stream) emit(ctx context.Context, ev StreamEvent) {
+	select {
+	case s.events <- ev:
+	case <-ctx.Done():
+	}
+}
+
+type connectionEstablished struct {
+	SocketID        string `json:"socket_id"`
+	ActivityTimeout int    `json:"activity_timeout"`
+}
+
+// runConnection handles a single connection until it fails. The bool reports whether the connection was
+// established, so that Run can reset its backoff.
+func (s *Stream) runConnection(ctx context.Context) (bool, error) {
+	dialer := &websocket.Dialer{
+		HandshakeTimeout: streamDialTimeout,
+		NetDial: func(network, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}
+	wsConn, _, err := dialer.Dial(s.cfg.url, nil)
+	if err != nil {
+		return false, err
+	}
+	conn := &streamConn{Conn: wsConn}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+			conn.Close()
+		}
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(streamDialTimeout))
+	established, err := readConnectionEstablished(conn)
+	if err != nil {
+		return false, err
+	}
+
+	activityTimeout := defaultActivityTimeout
+	if established.ActivityTimeout > 0 {
+		activityTimeout = time.Duration(established.ActivityTimeout) * time.Second
+	}
+
+	s.mu.Lock()
+	s.conn = conn
+	s.authenticated = false
+	s.mu.Unlock()
+	defer s.disconnected()
+
+	s.emit(ctx, &ConnectedEvent{SocketID: established.SocketID})
+
+	if s.cfg.signer != nil {
+		token, err := s.cfg.signer.Sign(streamAuthPath)
+		if err != nil {
+			return true, err
+		}
+		err = sendPusherMessage(conn, quoineAuthRequest, "", map[string]interface{}{
+			"path":    streamAuthPath,
+			"headers": map[string]string{"X-Quoine-Auth": token},
+		})
+		if err != nil {
+			return true, err
+		}
+	}
+	if err := s.resubscribe(conn, false); err != nil {
+		return true, err
+	}
+
+	// Read errors can't be recovered from, so pings are sent from keepAlive and the read deadline only catches a
+	// ping that went unanswered
+	activity := make(chan struct{}, 1)
+	go keepAlive(conn, activityTimeout, activity, done)
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(activityTimeout + pongTimeout))
+
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				return true, ErrStreamPingTimeout
+			}
+			return true, err
+		}
+		select {
+		case activity <- struct{}{}:
+		default:
+		}
+
+		var msg pusherMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			fmt.Println("[Stream] Error parsing message:", err.Error())
+			continue
+		}
+
+		if err := s.handleMessage(ctx, conn, &msg); err != nil {
+			return true, err
+		}
+	}
+}
+
+// keepAlive sends a Pusher ping whenever nothing has been received for activityTimeout.
+func keepAlive(conn messageWriter, activityTimeout time.Duration, activity <-chan struct{}, done <-chan struct{}) {
+	timer := time.NewTimer(activityTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-activity:
+			if !timer.Stop() {
+				<-timer.C
+			}
+		case <-timer.C:
+			if err := sendPusherMessage(conn, pusherPing, "", map[string]string{}); err != nil {
+				fmt.Println("[Stream] Error sending ping:", err.Error())
+				return
+			}
+		}
+		timer.Reset(activityTimeout)
+	}
+}
+
+func readConnectionEstablished(conn *streamConn) (*connectionEstablished, error) {
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	var msg pusherMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+	if msg.Event != pusherConnectionEstablished {
+		return nil, fmt.Errorf("unexpected first stream event: %s", msg.Event)
+	}
+
+	var established connectionEstablished
+	if err := json.Unmarshal(msg.payload(), &established); err != nil {
+		return nil, err
+	}
+
+	return &established, nil
+}
+
+func (s *Stream) disconnected() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.conn = nil
+	s.authenticated = false
+	s.lastDisconnect = time.Now()
+	for _, sub := range s.subs {
+		sub.confirmed = false
+	}
+}
+
+// resubscribe sends a subscribe for every channel not yet confirmed on this connection. Account channels are only
+// included once authenticated.
+func (s *Stream) resubscribe(conn *streamConn, authenticated bool) error {
+	s.mu.Lock()
+	var channels []string
+	for channel, sub := range s.subs {
+		if sub.confirmed || sub.private != authenticated {
+			continue
+		}
+		channels = append(channels, channel)
+	}
+	s.mu.Unlock()
+
+	sort.Strings(channels)
+	for _, channel := range channels {
+		if err := sendPusherMessage(conn, pusherSubscribe, "", map[string]string{"channel": channel}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Stream) handleMessage(ctx context.Context, conn *streamConn, msg *pusherMessage) error {
+	switch msg.Event {
+	case pusherPing:
+		return sendPusherMessage(conn, pusherPong, "", map[string]string{})
+	case pusherPong:
+		return nil
+	case pusherError:
+		fmt.Println("[Stream] Server error:", string(msg.payload()))
+		return nil
+	case quoineAuthSuccess:
+		s.mu.Lock()
+		s.authenticated = true
+		s.mu.Unlock()
+		return s.resubscribe(conn, true)
+	case quoineAuthFailure:
+		return ErrStreamAuthFailed
+	case pusherSubscriptionSucceeded:
+		s.confirmSubscription(ctx, msg.Channel)
+		return nil
+	}
+
+	s.mu.Lock()
+	sub, ok := s.subs[msg.Channel]
+	s.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	ev, err := sub.parse(msg.Channel, msg.payload())
+	if err != nil {
+		fmt.Println("[Stream] Error parsing", msg.Event, "on", msg.Channel, "-", err.Error())
+		return nil
+	}
+	if ev != nil {
+		s.emit(ctx, ev)
+	}
+
+	return nil
+}
+
+func (s *Stream) confirmSubscription(ctx context.Context, channel string) {
+	s.mu.Lock()
+	sub, ok := s.subs[channel]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	resubscribed := sub.wasConfirmed
+	sub.confirmed = true
+	sub.wasConfirmed = true
+	since := s.lastDisconnect
+	s.mu.Unlock()
+
+	if resubscribed {
+		s.emit(ctx, &GapEvent{
+			ChannelName: channel,
+			Since:       since,
+			Until:       time.Now(),
+		})
+	}
+}
+
+func parseTickerEvent(channel string, data []byte) (StreamEvent, error) {
+	var resp productsResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+
+	details, err := parseProductDetails(&resp)
+	if err != nil || details == nil {
+		return nil, err
+	}
+
+	return &TickerEvent{ChannelName: channel, Product: details}, nil
+}
+
+func parseOrderBookEvent(channel, currencyPairCode, side string, data []byte) (StreamEvent, error) {
+	var levels []PriceLevel
+	if err := json.Unmarshal(data, &levels); err != nil {
+		return nil, err
+	}
+
+	if side == OrderSideBuy {
+		sort.SliceStable(levels, func(i, j int) bool { return levels[i].Price > levels[j].Price })
+	} else {
+		sort.SliceStable(levels, func(i, j int) bool { return levels[i].Price < levels[j].Price })
+	}
+
+	return &OrderBookEvent{
+		ChannelName:      channel,
+		CurrencyPairCode: currencyPairCode,
+		Side:             side,
+		Levels:           levels,
+	}, nil
+}
+
+func parseOrderEvent(channel string, data []byte) (StreamEvent, error) {
+	var resp orderResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+
+	order, err := parseOrderDetails(&resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OrderEvent{ChannelName: channel, Order: order}, nil
+}
+
+func parseExecutionEvent(channel string, productId int, data []byte) (StreamEvent, error) {
+	var resp myExecutionResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+
+	return &ExecutionEvent{ChannelName: channel, Execution: parseMyExecution(&resp, productId)}, nil
+}
@@ -0,0 +1,301 @@
+package qryptos
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/websocket"
+)
+
+// fakePusher is a stand-in for the exchange's Pusher feed. Each connection is handed to onConnect after the
+// connection_established message has been sent.
+type fakePusher struct {
+	t         *testing.T
+	server    *httptest.Server
+	onConnect func(conn *websocket.Conn, index int)
+
+	mu          sync.Mutex
+	connections int
+}
+
+func newFakePusher(t *testing.T, onConnect func(conn *websocket.Conn, index int)) *fakePusher {
+	p := &fakePusher{t: t, onConnect: onConnect}
+	p.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Unexpected upgrade error: %s", err.Error())
+			return
+		}
+		defer conn.Close()
+
+		p.mu.Lock()
+		index := p.connections
+		p.connections++
+		p.mu.Unlock()
+
+		sendPusherMessage(conn, pusherConnectionEstablished, "", `{"socket_id":"123.456","activity_timeout":120}`)
+		p.onConnect(conn, index)
+	}))
+
+	return p
+}
+
+func (p *fakePusher) url() string {
+	return "ws" + strings.TrimPrefix(p.server.URL, "http")
+}
+
+func (p *fakePusher) Close() {
+	p.server.Close()
+}
+
+// readClientMessage returns the next message from the client, or nil once the connection closes.
+func readClientMessage(t *testing.T, conn *websocket.Conn) *pusherMessage {
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		return nil
+	}
+
+	var msg pusherMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Errorf("Unexpected message from client: %s", data)
+		return nil
+	}
+
+	return &msg
+}
+
+func subscribedChannel(msg *pusherMessage) string {
+	var data struct {
+		Channel string `json:"channel"`
+	}
+	json.Unmarshal(msg.Data, &data)
+	return data.Channel
+}
+
+func nextEvent(t *testing.T, events <-chan StreamEvent) StreamEvent {
+	select {
+	case ev, ok := <-events:
+		if !ok {
+			t.Fatal("Event channel closed unexpectedly.")
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a stream event.")
+	}
+	return nil
+}
+
+func TestStream_TickerAndOrderBook(t *testing.T) {
+	pusher := newFakePusher(t, func(conn *websocket.Conn, index int) {
+		for msg := readClientMessage(t, conn); msg != nil; msg = readClientMessage(t, conn) {
+			if msg.Event != pusherSubscribe {
+				continue
+			}
+
+			channel := subscribedChannel(msg)
+			sendPusherMessage(conn, pusherSubscriptionSucceeded, channel, "{}")
+			switch channel {
+			case "product_cash_vztbtc_56":
+				sendPusherMessage(conn, "updated", channel, `{"id":"56","currency":"BTC","currency_pair_code":"VZTBTC","base_currency":"VZT","quoted_currency":"BTC","market_ask":"0.00010366","market_bid":"0.00010201","volume_24h":"105373.45"}`)
+			case "price_ladders_cash_vztbtc_sell":
+				sendPusherMessage(conn, "updated", channel, `[["0.00010400","12.0"],["0.00010366","3.5"]]`)
+			}
+		}
+	})
+	defer pusher.Close()
+
+	stream := NewStream(WithStreamURL(pusher.url()))
+	stream.SubscribeTicker(56, "VZTBTC")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go stream.Run(ctx)
+
+	if _, ok := nextEvent(t, stream.Events()).(*ConnectedEvent); !ok {
+		t.Fatal("Expected a ConnectedEvent first.")
+	}
+
+	ticker, ok := nextEvent(t, stream.Events()).(*TickerEvent)
+	if !ok {
+		t.Fatal("Expected a TickerEvent.")
+	}
+	if expected := Amount(10366); ticker.Product.MarketAsk != expected {
+		t.Errorf("Unexpected market ask. Expected: %d; Actual: %d.", expected, ticker.Product.MarketAsk)
+	}
+
+	// Subscribing while connected is sent straight away
+	stream.SubscribeOrderBook("VZTBTC")
+
+	var book *OrderBookEvent
+	for book == nil {
+		book, _ = nextEvent(t, stream.Events()).(*OrderBookEvent)
+	}
+	if book.Side != OrderSideSell || len(book.Levels) != 2 {
+		t.Fatalf("Unexpected order book event: %+v", book)
+	}
+	if expected := Amount(10366); book.Levels[0].Price != expected {
+		t.Errorf("Expected best ask first. Expected: %d; Actual: %d.", expected, book.Levels[0].Price)
+	}
+}
+
+func TestStream_ReconnectResubscribes(t *testing.T) {
+	subscribed := make(chan string, 10)
+	pusher := newFakePusher(t, func(conn *websocket.Conn, index int) {
+		for msg := readClientMessage(t, conn); msg != nil; msg = readClientMessage(t, conn) {
+			if msg.Event != pusherSubscribe {
+				continue
+			}
+
+			channel := subscribedChannel(msg)
+			subscribed <- channel
+			sendPusherMessage(conn, pusherSubscriptionSucceeded, channel, "{}")
+
+			// Drop the first connection as soon as the client is subscribed
+			if index == 0 {
+				return
+			}
+		}
+	})
+	defer pusher.Close()
+
+	stream := NewStream(WithStreamURL(pusher.url()), WithReconnectDelay(10*time.Millisecond, 50*time.Millisecond))
+	stream.SubscribeTicker(56, "VZTBTC")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go stream.Run(ctx)
+
+	var gap *GapEvent
+	sawDisconnect := false
+	for gap == nil {
+		switch ev := nextEvent(t, stream.Events()).(type) {
+		case *DisconnectedEvent:
+			sawDisconnect = true
+		case *GapEvent:
+			gap = ev
+		}
+	}
+
+	if !sawDisconnect {
+		t.Error("Expected a DisconnectedEvent before the gap.")
+	}
+	if expected := "product_cash_vztbtc_56"; gap.Channel() != expected {
+		t.Errorf("Unexpected gap channel. Expected: %s; Actual: %s.", expected, gap.Channel())
+	}
+	if gap.Since.IsZero() || gap.Until.Before(gap.Since) {
+		t.Errorf("Unexpected gap range: %s to %s.", gap.Since, gap.Until)
+	}
+	if len(subscribed) != 2 {
+		t.Errorf("Unexpected subscription count. Expected: 2; Actual: %d.", len(subscribed))
+	}
+
+	cancel()
+	for range stream.Events() {
+	}
+}
+
+func TestStream_AccountChannels(t *testing.T) {
+	pusher := newFakePusher(t, func(conn *websocket.Conn, index int) {
+		authenticated := false
+		for msg := readClientMessage(t, conn); msg != nil; msg = readClientMessage(t, conn) {
+			switch msg.Event {
+			case quoineAuthRequest:
+				var req struct {
+					Path    string            `json:"path"`
+					Headers map[string]string `json:"headers"`
+				}
+				json.Unmarshal(msg.Data, &req)
+
+				token, err := jwt.Parse(req.Headers["X-Quoine-Auth"], func(*jwt.Token) (interface{}, error) {
+					return []byte("secret"), nil
+				})
+				if err != nil || !token.Valid || req.Path != "/realtime" {
+					sendPusherMessage(conn, quoineAuthFailure, "", "{}")
+					continue
+				}
+				authenticated = true
+				sendPusherMessage(conn, quoineAuthSuccess, "", "{}")
+			case pusherSubscribe:
+				if !authenticated {
+					t.Error("Account channel subscribed before authenticating.")
+				}
+				channel := subscribedChannel(msg)
+				sendPusherMessage(conn, pusherSubscriptionSucceeded, channel, "{}")
+				sendPusherMessage(conn, "updated", channel, `{"id":2157474,"product_id":56,"side":"sell","status":"filled","currency_pair_code":"VZTBTC","funding_currency":"BTC","price":"0.00010366","quantity":"13.5","filled_quantity":"13.5"}`)
+			}
+		}
+	})
+	defer pusher.Close()
+
+	stream := NewStream(WithStreamURL(pusher.url()))
+	if err := stream.SubscribeOrders("BTC"); err != ErrStreamCredentialsRequired {
+		t.Errorf("Expected ErrStreamCredentialsRequired; Actual: %v.", err)
+	}
+
+	stream = NewStream(WithStreamURL(pusher.url()), WithStreamCredentials("123456", "secret"))
+	if err := stream.SubscribeOrders("BTC"); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go stream.Run(ctx)
+
+	var orderEvent *OrderEvent
+	for orderEvent == nil {
+		orderEvent, _ = nextEvent(t, stream.Events()).(*OrderEvent)
+	}
+
+	order := orderEvent.Order
+	if order.ID != 2157474 || order.Status != OrderStatusFilled || order.BaseCurrency != "VZT" {
+		t.Errorf("Unexpected order: %+v", order)
+	}
+	if expected := "user_account_btc_orders"; orderEvent.Channel() != expected {
+		t.Errorf("Unexpected channel. Expected: %s; Actual: %s.", expected, orderEvent.Channel())
+	}
+}
+
+type recordingWriter struct {
+	messages chan []byte
+}
+
+func (w *recordingWriter) WriteMessage(messageType int, data []byte) error {
+	w.messages <- data
+	return nil
+}
+
+func TestKeepAlive(t *testing.T) {
+	w := &recordingWriter{messages: make(chan []byte, 10)}
+	activity := make(chan struct{}, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go keepAlive(w, 50*time.Millisecond, activity, done)
+
+	// Incoming messages hold the ping off
+	for i := 0; i < 4; i++ {
+		time.Sleep(20 * time.Millisecond)
+		activity <- struct{}{}
+	}
+	select {
+	case data := <-w.messages:
+		t.Errorf("Unexpected message while active: %s", data)
+	default:
+	}
+
+	select {
+	case data := <-w.messages:
+		var msg pusherMessage
+		if err := json.Unmarshal(data, &msg); err != nil || msg.Event != pusherPing {
+			t.Errorf("Expected a ping: %s", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for a ping.")
+	}
+}
@@ -0,0 +1,345 @@
+// Package tracker follows the account's orders over time and reports what happened to them as typed events. It
+// diffs successive order snapshots, or applies stream updates, so strategies can react to fills and cancellations
+// instead of comparing raw OrderDetails themselves.
+package tracker
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/tobyjsullivan/shifty/qryptos"
+)
+
+// Event is something that happened to an order between two observations.
+type Event interface {
+	Order() *qryptos.OrderDetails
+}
+
+// Created is emitted the first time an order is seen.
+type Created struct {
+	Details *qryptos.OrderDetails
+}
+
+// PartiallyFilled is emitted when a live order has executions that weren't seen before.
+type PartiallyFilled struct {
+	Details    *qryptos.OrderDetails
+	Executions []*qryptos.ExecutionDetails
+}
+
+// Filled is emitted once when an order becomes filled. Executions holds the ones not reported by an earlier
+// PartiallyFilled.
+type Filled struct {
+	Details    *qryptos.OrderDetails
+	Executions []*qryptos.ExecutionDetails
+}
+
+// Cancelled is emitted once when an order is cancelled.
+type Cancelled struct {
+	Details *qryptos.OrderDetails
+}
+
+// Edited is emitted when a live order's price or quantity changes.
+type Edited struct {
+	Details  *qryptos.OrderDetails
+	Previous *qryptos.OrderDetails
+}
+
+// Disappeared is emitted when a live order can no longer be found. Details is the last state seen.
+type Disappeared struct {
+	Details *qryptos.OrderDetails
+}
+
+func (e *Created) Order() *qryptos.OrderDetails         { return e.Details }
+func (e *PartiallyFilled) Order() *qryptos.OrderDetails { return e.Details }
+func (e *Filled) Order() *qryptos.OrderDetails          { return e.Details }
+func (e *Cancelled) Order() *qryptos.OrderDetails       { return e.Details }
+func (e *Edited) Order() *qryptos.OrderDetails          { return e.Details }
+func (e *Disappeared) Order() *qryptos.OrderDetails     { return e.Details }
+
+// NewExecutions returns the executions reported by a PartiallyFilled or Filled event, or nil for any other event.
+func NewExecutions(ev Event) []*qryptos.ExecutionDetails {
+	switch e := ev.(type) {
+	case *PartiallyFilled:
+		return e.Executions
+	case *Filled:
+		return e.Executions
+	}
+
+	return nil
+}
+
+// Handler is called with each event, in the order the events were emitted.
+type Handler func(Event)
+
+// OrderLister is the part of a venue Poll needs.
+type OrderLister interface {
+	FetchAllOrdersContext(ctx context.Context, q *qryptos.OrdersQuery) ([]*qryptos.OrderDetails, error)
+}
+
+// OrderFetcher is implemented by venues that can look up a single order. Poll uses it to find out what happened to
+// live orders that dropped out of the listing, so their final executions aren't missed.
+type OrderFetcher interface {
+	FetchOrderContext(ctx context.Context, orderId int) (*qryptos.OrderDetails, error)
+}
+
+// Tracker remembers the last state of every order it has seen. It is safe for concurrent use.
+type Tracker struct {
+	mu         sync.Mutex
+	orders     map[int]*qryptos.OrderDetails
+	executions map[int]bool
+	finished   map[int]bool
+	handlers   []Handler
+}
+
+func New() *Tracker {
+	return &Tracker{
+		orders:     make(map[int]*qryptos.OrderDetails),
+		executions: make(map[int]bool),
+		finished:   make(map[int]bool),
+	}
+}
+
+// Subscribe registers h to be called with every event. Handlers run synchronously on the goroutine that observed
+// the change, after the tracker's state has been updated.
+func (t *Tracker) Subscribe(h Handler) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.handlers = append(t.handlers, h)
+}
+
+// Seed records orders as already known without emitting any events. Use it to start from the account's current
+// state rather than reporting its whole history.
+func (t *Tracker) Seed(orders []*qryptos.OrderDetails) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, o := range orders {
+		t.record(copyOrder(o))
+	}
+}
+
+// Update diffs a complete snapshot against the last known state. Every live order the tracker knows about is
+// expected to be in the snapshot; any that are missing are reported as Disappeared.
+func (t *Tracker) Update(snapshot []*qryptos.OrderDetails) []Event {
+	seen := make(map[int]bool, len(snapshot))
+	for _, o := range snapshot {
+		seen[o.ID] = true
+	}
+
+	t.mu.Lock()
+	var events []Event
+	for _, o := range snapshot {
+		events = append(events, t.diff(o)...)
+	}
+	var gone []int
+	for id := range t.orders {
+		if !seen[id] {
+			gone = append(gone, id)
+		}
+	}
+	sort.Ints(gone)
+	for _, id := range gone {
+		events = append(events, t.forget(t.orders[id])...)
+	}
+	t.mu.Unlock()
+
+	t.publish(events)
+	return events
+}
+
+// Poll lists orders matching q and diffs them like Update. Live orders the tracker knows about that aren't in the
+// listing, for example because they were filled and q only asks for live orders, or because they fell off the
+// fetched pages, are looked up one by one when the venue is an OrderFetcher. They are only reported as Disappeared
+// when they can't be found.
+func (t *Tracker) Poll(ctx context.Context, src OrderLister, q *qryptos.OrdersQuery) ([]Event, error) {
+	listed, err := src.FetchAllOrdersContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int]bool, len(listed))
+	for _, o := range listed {
+		seen[o.ID] = true
+	}
+
+	snapshot := listed
+	for _, id := range t.missing(seen) {
+		fetcher, ok := src.(OrderFetcher)
+		if !ok {
+			break
+		}
+
+		o, err := fetcher.FetchOrderContext(ctx, id)
+		if qryptos.IsOrderNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		snapshot = append(snapshot, o)
+	}
+
+	return t.Update(snapshot), nil
+}
+
+// Apply diffs a single order update, such as one received from a stream. Unlike Update, other orders are left
+// alone.
+func (t *Tracker) Apply(o *qryptos.OrderDetails) []Event {
+	t.mu.Lock()
+	events := t.diff(o)
+	t.mu.Unlock()
+
+	t.publish(events)
+	return events
+}
+
+// ApplyStreamEvent applies order and execution updates from a Stream. Other events are ignored. Executions for
+// orders the tracker hasn't seen yet are ignored too, since there is nothing to attach them to; they are picked up
+// with the order itself.
+func (t *Tracker) ApplyStreamEvent(ev qryptos.StreamEvent) []Event {
+	switch e := ev.(type) {
+	case *qryptos.OrderEvent:
+		return t.Apply(e.Order)
+	case *qryptos.ExecutionEvent:
+		return t.applyExecution(e.Execution)
+	}
+
+	return nil
+}
+
+// Order returns a copy of the last known state of an order, or nil if it has never been seen.
+func (t *Tracker) Order(orderId int) *qryptos.OrderDetails {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	o, ok := t.orders[orderId]
+	if !ok {
+		return nil
+	}
+	return copyOrder(o)
+}
+
+func (t *Tracker) applyExecution(exec *qryptos.Execution) []Event {
+	t.mu.Lock()
+	prev, ok := t.orders[exec.OrderID]
+	if !ok || t.executions[exec.ID] {
+		t.mu.Unlock()
+		return nil
+	}
+
+	o := copyOrder(prev)
+	o.Executions = append(o.Executions, &qryptos.ExecutionDetails{
+		ID:       exec.ID,
+		Quantity: exec.Quantity,
+		Price:    exec.Price,
+	})
+	o.FilledQuantity += exec.Quantity
+	if o.FilledQuantity >= o.Quantity {
+		o.Status = qryptos.OrderStatusFilled
+	}
+	events := t.diff(o)
+	t.mu.Unlock()
+
+	t.publish(events)
+	return events
+}
+
+// missing returns the IDs of live orders that aren't in seen.
+func (t *Tracker) missing(seen map[int]bool) []int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var ids []int
+	for id, o := range t.orders {
+		if !seen[id] && o.Status == qryptos.OrderStatusLive {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// diff compares o with its last known state, records it and returns what changed. The caller must hold t.mu.
+func (t *Tracker) diff(o *qryptos.OrderDetails) []Event {
+	if t.finished[o.ID] {
+		return nil
+	}
+
+	prev, known := t.orders[o.ID]
+	cur := copyOrder(o)
+
+	var events []Event
+	if !known {
+		events = append(events, &Created{Details: cur})
+	} else if prev.Status != qryptos.OrderStatusLive {
+		// Finished orders don't change, but a stale snapshot mustn't bring them back to life either
+		return nil
+	} else if cur.Status == qryptos.OrderStatusLive && (cur.Price != prev.Price || cur.Quantity != prev.Quantity) {
+		events = append(events, &Edited{Details: cur, Previous: prev})
+	}
+
+	var fresh []*qryptos.ExecutionDetails
+	for _, exec := range cur.Executions {
+		if !t.executions[exec.ID] {
+			fresh = append(fresh, exec)
+		}
+	}
+
+	switch {
+	case cur.Status == qryptos.OrderStatusFilled:
+		events = append(events, &Filled{Details: cur, Executions: fresh})
+	case len(fresh) > 0:
+		events = append(events, &PartiallyFilled{Details: cur, Executions: fresh})
+	}
+	if cur.Status == qryptos.OrderStatusCancelled {
+		events = append(events, &Cancelled{Details: cur})
+	}
+
+	t.record(cur)
+	return events
+}
+
+// forget drops an order that is no longer listed. Only live orders are worth reporting; finished ones simply age
+// out of the listing, and only their ID is kept so they aren't reported again if a later snapshot includes them.
+// The caller must hold t.mu.
+func (t *Tracker) forget(o *qryptos.OrderDetails) []Event {
+	delete(t.orders, o.ID)
+
+	if o.Status != qryptos.OrderStatusLive {
+		t.finished[o.ID] = true
+		return nil
+	}
+	return []Event{&Disappeared{Details: o}}
+}
+
+// record stores o as the latest state. The caller must hold t.mu.
+func (t *Tracker) record(o *qryptos.OrderDetails) {
+	t.orders[o.ID] = o
+	for _, exec := range o.Executions {
+		t.executions[exec.ID] = true
+	}
+}
+
+func (t *Tracker) publish(events []Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	handlers := t.handlers
+	t.mu.Unlock()
+
+	for _, ev := range events {
+		for _, h := range handlers {
+			h(ev)
+		}
+	}
+}
+
+func copyOrder(o *qryptos.OrderDetails) *qryptos.OrderDetails {
+	c := *o
+	c.Executions = append([]*qryptos.ExecutionDetails(nil), o.Executions...)
+	return &c
+}
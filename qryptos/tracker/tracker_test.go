@@ -0,0 +1,183 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tobyjsullivan/shifty/exchange/paper"
+	"github.com/tobyjsullivan/shifty/qryptos"
+	"github.com/tobyjsullivan/shifty/qryptos/qryptostest"
+)
+
+func order(id int, status string, quantity, price qryptos.Amount, executions ...*qryptos.ExecutionDetails) *qryptos.OrderDetails {
+	o := &qryptos.OrderDetails{
+		ID:         id,
+		ProductID:  56,
+		Side:       qryptos.OrderSideBuy,
+		Status:     status,
+		Quantity:   quantity,
+		Price:      price,
+		Executions: executions,
+	}
+	for _, exec := range executions {
+		o.FilledQuantity += exec.Quantity
+	}
+	return o
+}
+
+func execution(id int, quantity qryptos.Amount) *qryptos.ExecutionDetails {
+	return &qryptos.ExecutionDetails{ID: id, Quantity: quantity, Price: qryptos.Amount(10000)}
+}
+
+// describe renders events compactly so sequences can be compared.
+func describe(events []Event) []string {
+	out := []string{}
+	for _, ev := range events {
+		name := strings.TrimPrefix(reflect.TypeOf(ev).String(), "*tracker.")
+		desc := fmt.Sprintf("%s %d", name, ev.Order().ID)
+		for _, exec := range NewExecutions(ev) {
+			desc += fmt.Sprintf(" #%d", exec.ID)
+		}
+		out = append(out, desc)
+	}
+	return out
+}
+
+func expectEvents(t *testing.T, step string, events []Event, expected ...string) {
+	if expected == nil {
+		expected = []string{}
+	}
+	if actual := describe(events); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Unexpected events after %s. Expected: %v; Actual: %v.", step, expected, actual)
+	}
+}
+
+func TestTracker_Update(t *testing.T) {
+	tr := New()
+	var handled []Event
+	tr.Subscribe(func(ev Event) { handled = append(handled, ev) })
+
+	events := tr.Update([]*qryptos.OrderDetails{
+		order(1, qryptos.OrderStatusLive, qryptos.Amount(300), qryptos.Amount(10000)),
+		order(2, qryptos.OrderStatusLive, qryptos.Amount(300), qryptos.Amount(10000), execution(20, qryptos.Amount(100))),
+	})
+	expectEvents(t, "first snapshot", events, "Created 1", "Created 2", "PartiallyFilled 2 #20")
+
+	// Nothing changed
+	events = tr.Update([]*qryptos.OrderDetails{
+		order(1, qryptos.OrderStatusLive, qryptos.Amount(300), qryptos.Amount(10000)),
+		order(2, qryptos.OrderStatusLive, qryptos.Amount(300), qryptos.Amount(10000), execution(20, qryptos.Amount(100))),
+	})
+	expectEvents(t, "unchanged snapshot", events)
+
+	events = tr.Update([]*qryptos.OrderDetails{
+		order(1, qryptos.OrderStatusLive, qryptos.Amount(300), qryptos.Amount(10001)),
+		order(2, qryptos.OrderStatusFilled, qryptos.Amount(300), qryptos.Amount(10000),
+			execution(20, qryptos.Amount(100)), execution(21, qryptos.Amount(200))),
+		order(3, qryptos.OrderStatusLive, qryptos.Amount(300), qryptos.Amount(10000)),
+	})
+	expectEvents(t, "edit and fill", events, "Edited 1", "Filled 2 #21", "Created 3")
+	if edited := events[0].(*Edited); edited.Previous.Price != qryptos.Amount(10000) {
+		t.Errorf("Unexpected previous price. Expected: %d; Actual: %d.", 10000, edited.Previous.Price)
+	}
+
+	// Order 2 ages out quietly; order 3 vanishing while live is reported
+	events = tr.Update([]*qryptos.OrderDetails{
+		order(1, qryptos.OrderStatusCancelled, qryptos.Amount(300), qryptos.Amount(10001)),
+	})
+	expectEvents(t, "cancel", events, "Cancelled 1", "Disappeared 3")
+
+	// A finished order turning up again isn't reported twice
+	events = tr.Update([]*qryptos.OrderDetails{
+		order(1, qryptos.OrderStatusCancelled, qryptos.Amount(300), qryptos.Amount(10001)),
+		order(2, qryptos.OrderStatusFilled, qryptos.Amount(300), qryptos.Amount(10000),
+			execution(20, qryptos.Amount(100)), execution(21, qryptos.Amount(200))),
+	})
+	expectEvents(t, "stale snapshot", events)
+
+	if len(handled) != 8 {
+		t.Errorf("Unexpected handled event count. Expected: 8; Actual: %d.", len(handled))
+	}
+}
+
+func TestTracker_Seed(t *testing.T) {
+	tr := New()
+	tr.Seed([]*qryptos.OrderDetails{
+		order(1, qryptos.OrderStatusLive, qryptos.Amount(300), qryptos.Amount(10000), execution(10, qryptos.Amount(100))),
+	})
+
+	events := tr.Update([]*qryptos.OrderDetails{
+		order(1, qryptos.OrderStatusLive, qryptos.Amount(300), qryptos.Amount(10000),
+			execution(10, qryptos.Amount(100)), execution(11, qryptos.Amount(100))),
+	})
+	expectEvents(t, "seeded snapshot", events, "PartiallyFilled 1 #11")
+}
+
+func TestTracker_Poll(t *testing.T) {
+	sim := paper.New([]*qryptos.ProductDetails{qryptostest.VZTBTC()}, map[string]qryptos.Amount{"BTC": qryptos.Amount(1000000)})
+	ctx := context.Background()
+
+	orderId, err := sim.CreateOrderContext(ctx, &qryptos.OrderRequest{
+		ProductID: 56,
+		Type:      qryptos.OrderTypeLimit,
+		Side:      qryptos.OrderSideBuy,
+		Quantity:  qryptos.Amount(500000000),
+		Price:     qryptos.Amount(10100),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	tr := New()
+	q := &qryptos.OrdersQuery{ProductID: 56, Status: qryptos.OrderStatusLive}
+	events, err := tr.Poll(ctx, sim, q)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	expectEvents(t, "first poll", events, fmt.Sprintf("Created %d", orderId))
+
+	// The fill takes the order out of the live listing, but its executions are still picked up
+	sim.ApplyTrade(&qryptos.Trade{ProductID: 56, Price: qryptos.Amount(10000), Quantity: qryptos.Amount(500000000), TakerSide: qryptos.OrderSideSell})
+	events, err = tr.Poll(ctx, sim, q)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if len(events) != 1 {
+		t.Fatalf("Unexpected event count. Expected: 1; Actual: %d.", len(events))
+	}
+	filled, ok := events[0].(*Filled)
+	if !ok {
+		t.Fatalf("Unexpected event: %+v", events[0])
+	}
+	if len(filled.Executions) != 1 || filled.Executions[0].Quantity != qryptos.Amount(500000000) {
+		t.Errorf("Unexpected executions: %+v", filled.Executions)
+	}
+}
+
+func TestTracker_ApplyStreamEvent(t *testing.T) {
+	tr := New()
+
+	// Executions for unknown orders have nothing to attach to
+	events := tr.ApplyStreamEvent(&qryptos.ExecutionEvent{Execution: &qryptos.Execution{ID: 30, OrderID: 1, Quantity: qryptos.Amount(100)}})
+	expectEvents(t, "unknown execution", events)
+
+	events = tr.ApplyStreamEvent(&qryptos.OrderEvent{Order: order(1, qryptos.OrderStatusLive, qryptos.Amount(300), qryptos.Amount(10000))})
+	expectEvents(t, "order update", events, "Created 1")
+
+	events = tr.ApplyStreamEvent(&qryptos.ExecutionEvent{Execution: &qryptos.Execution{ID: 30, OrderID: 1, Quantity: qryptos.Amount(100)}})
+	expectEvents(t, "first execution", events, "PartiallyFilled 1 #30")
+
+	// Duplicate deliveries are ignored
+	events = tr.ApplyStreamEvent(&qryptos.ExecutionEvent{Execution: &qryptos.Execution{ID: 30, OrderID: 1, Quantity: qryptos.Amount(100)}})
+	expectEvents(t, "duplicate execution", events)
+
+	events = tr.ApplyStreamEvent(&qryptos.ExecutionEvent{Execution: &qryptos.Execution{ID: 31, OrderID: 1, Quantity: qryptos.Amount(200)}})
+	expectEvents(t, "last execution", events, "Filled 1 #31")
+
+	if o := tr.Order(1); o.FilledQuantity != qryptos.Amount(300) {
+		t.Errorf("Unexpected filled quantity. Expected: %d; Actual: %d.", 300, o.FilledQuantity)
+	}
+}
@@ -0,0 +1,121 @@
+package qryptos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	endpointExecutions = "/executions"
+
+	// The largest page the exchange will return when listing executions by timestamp
+	maxTradesPageSize = 1000
+)
+
+// ErrTradesPageFull means more trades share one second than fit in a page. The tape can only be paged by timestamp,
+// so the rest of that second can't be reached.
+var ErrTradesPageFull = errors.New("too many trades in one second to page past")
+
+// Trade is a public execution between any two parties on a product.
+type Trade struct {
+	ID        int
+	ProductID int
+	Price     Amount
+	Quantity  Amount
+	TakerSide string
+	CreatedAt time.Time
+}
+
+type tradeResponse struct {
+	ID        int    `json:"id"`
+	Quantity  Amount `json:"quantity"`
+	Price     Amount `json:"price"`
+	TakerSide string `json:"taker_side"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// FetchExecutions returns every public execution on the product since the given time, oldest first.
+func (c *PublicClient) FetchExecutions(productId int, since time.Time) ([]*Trade, error) {
+	return c.FetchExecutionsContext(context.Background(), productId, since)
+}
+
+// FetchExecutionsContext pages through the trade tape by timestamp until it reaches the most recent execution. It
+// returns ErrTradesPageFull rather than skip trades it can't page past.
+func (c *PublicClient) FetchExecutionsContext(ctx context.Context, productId int, since time.Time) ([]*Trade, error) {
+	if productId == 0 {
+		return []*Trade{}, ErrProductRequired
+	}
+
+	var out []*Trade
+	timestamp := since.Unix()
+	lastId := 0
+	for {
+		page, err := c.fetchTradesPage(ctx, productId, timestamp)
+		if err != nil {
+			return []*Trade{}, err
+		}
+
+		// Timestamps only have one second resolution so consecutive pages can overlap
+		added := 0
+		for _, trade := range page {
+			if trade.ID <= lastId || trade.CreatedAt.Before(since) {
+				continue
+			}
+			out = append(out, trade)
+			lastId = trade.ID
+			added++
+		}
+
+		if len(page) < maxTradesPageSize {
+			break
+		}
+		// A full page of trades already seen means the next page would start from the same second again
+		if added == 0 {
+			return []*Trade{}, ErrTradesPageFull
+		}
+		timestamp = page[len(page)-1].CreatedAt.Unix()
+	}
+
+	return out, nil
+}
+
+func (c *PublicClient) fetchTradesPage(ctx context.Context, productId int, timestamp int64) ([]*Trade, error) {
+	query := url.Values{}
+	query.Set("product_id", strconv.Itoa(productId))
+	query.Set("timestamp", strconv.FormatInt(timestamp, 10))
+	query.Set("limit", strconv.Itoa(maxTradesPageSize))
+
+	res, err := c.send(ctx, &apiRequest{
+		method: http.MethodGet,
+		path:   endpointExecutions,
+		query:  query,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Listing by timestamp returns a bare array sorted oldest first
+	var parsedResponse []*tradeResponse
+	if err := json.Unmarshal(res.body, &parsedResponse); err != nil {
+		return nil, err
+	}
+
+	out := make([]*Trade, 0, len(parsedResponse))
+	for _, model := range parsedResponse {
+		out = append(out, &Trade{
+			ID:        model.ID,
+			ProductID: productId,
+			Price:     model.Price,
+			Quantity:  model.Quantity,
+			TakerSide: model.TakerSide,
+			CreatedAt: parseTimestamp(model.CreatedAt),
+		})
+	}
+
+	return out, nil
+}
@@ -0,0 +1,100 @@
+package qryptos
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPublicClient_FetchExecutions(t *testing.T) {
+	const start = 1516007600
+	const tradeCount = 1500
+
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if urlPath := r.URL.Path; urlPath != "/executions" {
+			t.Errorf("Unexpected request path: %s", urlPath)
+		}
+		if productId := r.URL.Query().Get("product_id"); productId != "56" {
+			t.Errorf("Unexpected product filter: %s", productId)
+		}
+		timestamp, _ := strconv.ParseInt(r.URL.Query().Get("timestamp"), 10, 64)
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		// Two trades per second, with the timestamp filter treated as inclusive so that pages overlap
+		models := []map[string]interface{}{}
+		for id := 1; id <= tradeCount && len(models) < limit; id++ {
+			createdAt := int64(start + id/2)
+			if createdAt < timestamp {
+				continue
+			}
+			models = append(models, map[string]interface{}{
+				"id":         id,
+				"quantity":   "1.5",
+				"price":      "0.00010366",
+				"taker_side": "buy",
+				"created_at": createdAt,
+			})
+		}
+		json.NewEncoder(w).Encode(models)
+	}))
+	defer ts.Close()
+
+	client := NewPublicClient(WithBaseURL(ts.URL))
+
+	trades, err := client.FetchExecutions(56, time.Unix(start+10, 0))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	// Trades 1 to 19 happened before the start time
+	if expected := tradeCount - 19; len(trades) != expected {
+		t.Fatalf("Unexpected trade count. Expected: %d; Actual: %d.", expected, len(trades))
+	}
+	for i, trade := range trades {
+		if expected := i + 20; trade.ID != expected {
+			t.Fatalf("Unexpected trade ID at %d. Expected: %d; Actual: %d.", i, expected, trade.ID)
+		}
+	}
+	if expected := 2; requests != expected {
+		t.Errorf("Unexpected request count. Expected: %d; Actual: %d.", expected, requests)
+	}
+
+	first := trades[0]
+	if first.ProductID != 56 || first.TakerSide != OrderSideBuy {
+		t.Errorf("Unexpected trade: %+v", first)
+	}
+	if expected := Amount(150000000); first.Quantity != expected {
+		t.Errorf("Unexpected quantity. Expected: %d; Actual: %d.", expected, first.Quantity)
+	}
+}
+
+func TestPublicClient_FetchExecutions_FullSecond(t *testing.T) {
+	const start = 1516007600
+
+	// More trades in one second than fit in a page
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		models := []map[string]interface{}{}
+		for id := 1; id <= maxTradesPageSize; id++ {
+			models = append(models, map[string]interface{}{
+				"id":         id,
+				"quantity":   "1.5",
+				"price":      "0.00010366",
+				"taker_side": "sell",
+				"created_at": start,
+			})
+		}
+		json.NewEncoder(w).Encode(models)
+	}))
+	defer ts.Close()
+
+	client := NewPublicClient(WithBaseURL(ts.URL))
+
+	if _, err := client.FetchExecutions(56, time.Unix(start, 0)); err != ErrTradesPageFull {
+		t.Errorf("Unexpected error. Expected: %v; Actual: %v.", ErrTradesPageFull, err)
+	}
+}
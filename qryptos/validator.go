@@ -0,0 +1,305 @@
+package qryptos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// The default fat-finger band is 5% of the market price
+const defaultFatFingerBand = Amount(5000000)
+
+var (
+	ErrProductDisabled     = errors.New("product is disabled")
+	ErrNoMarket            = errors.New("product has no market price")
+	ErrPriceCrossesBook    = errors.New("post-only price crosses the book")
+	ErrPriceOutsideBand    = errors.New("price is too far through the market")
+	ErrInsufficientBalance = errors.New("available balance is too low")
+)
+
+// ValidationError reports an order that Validator refused to send. Err is one of the Err* values above, or
+// ErrQuantityBelowMinimum and the other product constraint errors.
+type ValidationError struct {
+	Request *OrderRequest
+	Err     error
+	Detail  string
+}
+
+func (e *ValidationError) Error() string {
+	msg := fmt.Sprintf("order rejected before sending (product: %d; side: %s; quantity: %s; price: %s): %s",
+		e.Request.ProductID, e.Request.Side, e.Request.Quantity, e.Request.Price, e.Err.Error())
+	if e.Detail != "" {
+		msg += " (" + e.Detail + ")"
+	}
+	return msg
+}
+
+func rejectOrder(req *OrderRequest, err error, detail string) error {
+	return &ValidationError{Request: req, Err: err, Detail: detail}
+}
+
+// IsValidationError reports whether err is a *ValidationError for the given reason.
+func IsValidationError(err error, reason error) bool {
+	verr, ok := err.(*ValidationError)
+	return ok && verr.Err == reason
+}
+
+// ValidatorOption configures a Validator.
+type ValidatorOption func(*Validator)
+
+// WithFatFingerBand sets how far beyond the opposite side of the book an order may be priced, as a fraction of
+// the market price: Amount(5000000) lets a buy go up to 5% above the ask. A zero band turns the check off.
+func WithFatFingerBand(band Amount) ValidatorOption {
+	return func(v *Validator) {
+		v.band = band
+	}
+}
+
+// WithoutBalanceCheck skips the available balance check, which costs two extra requests per order.
+func WithoutBalanceCheck() ValidatorOption {
+	return func(v *Validator) {
+		v.checkBalance = false
+	}
+}
+
+// Validator wraps a PrivateClient and checks new orders against the product and the account before sending them,
+// so that orders the exchange would reject, or that are priced by mistake, fail fast with a *ValidationError
+// instead of a failed request. Everything other than creating orders passes straight through to the client.
+type Validator struct {
+	*PrivateClient
+
+	catalog      *Catalog
+	band         Amount
+	checkBalance bool
+}
+
+// NewValidator checks orders sent through client against the products in catalog. Market prices are only as fresh
+// as the catalog, which is one more reason for the fat-finger band to leave some room.
+func NewValidator(client *PrivateClient, catalog *Catalog, opts ...ValidatorOption) *Validator {
+	v := &Validator{
+		PrivateClient: client,
+		catalog:       catalog,
+		band:          defaultFatFingerBand,
+		checkBalance:  true,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v
+}
+
+func (v *Validator) CreateLimitOrder(productId int, side string, quantity, price Amount) (int, error) {
+	return v.CreateLimitOrderContext(context.Background(), productId, side, quantity, price)
+}
+
+func (v *Validator) CreateLimitOrderContext(ctx context.Context, productId int, side string, quantity, price Amount) (int, error) {
+	return v.CreateOrderContext(ctx, &OrderRequest{
+		ProductID: productId,
+		Type:      OrderTypeLimit,
+		Side:      side,
+		Quantity:  quantity,
+		Price:     price,
+	})
+}
+
+func (v *Validator) CreateOrder(order *OrderRequest) (int, error) {
+	return v.CreateOrderContext(context.Background(), order)
+}
+
+// CreateOrderContext sends the order only if Check passes.
+func (v *Validator) CreateOrderContext(ctx context.Context, order *OrderRequest) (int, error) {
+	if err := v.Check(ctx, order); err != nil {
+		return 0, err
+	}
+
+	return v.PrivateClient.CreateOrderContext(ctx, order)
+}
+
+// Check runs every pre-trade check without sending the order. Errors fetching the products, balances or orders are
+// returned as they are; only failed checks are *ValidationErrors.
+func (v *Validator) Check(ctx context.Context, order *OrderRequest) error {
+	if err := order.Validate(); err != nil {
+		return err
+	}
+
+	product, err := v.catalog.Product(ctx, order.ProductID)
+	if err == ErrProductNotFound {
+		return rejectOrder(order, err, "")
+	}
+	if err != nil {
+		return err
+	}
+
+	if product.Disabled {
+		return rejectOrder(order, ErrProductDisabled, product.CurrencyPairCode)
+	}
+	if err := checkConstraints(product, order); err != nil {
+		return err
+	}
+	if err := v.checkPrice(product, order); err != nil {
+		return err
+	}
+	if v.checkBalance {
+		return v.checkFunds(ctx, product, order)
+	}
+
+	return nil
+}
+
+// checkConstraints applies the product's quantity and tick rules. Orders without a limit price only have their
+// quantity checked, and stop prices must be on the tick as well.
+func checkConstraints(product *ProductDetails, order *OrderRequest) error {
+	detail := fmt.Sprintf("minimum: %s; step: %s; tick: %s",
+		product.MinimumOrderQuantity(), product.Step(), product.Tick())
+
+	price := order.Price
+	if price == AmountZero {
+		price = product.Tick()
+	}
+	if err := product.CheckOrder(order.Quantity, price); err != nil {
+		return rejectOrder(order, err.(*OrderConstraintError).Err, detail)
+	}
+	if order.StopPrice%product.Tick() != 0 {
+		return rejectOrder(order, ErrPriceTick, detail)
+	}
+
+	return nil
+}
+
+// bandPrice is the price an order is expected to trade at: the stop price for stop orders, which sit beyond the
+// market until they trigger, and the limit price otherwise.
+func bandPrice(order *OrderRequest) Amount {
+	if order.Type == OrderTypeStop || order.Type == OrderTypeStopLimit {
+		return order.StopPrice
+	}
+	return order.Price
+}
+
+// checkPrice refuses post-only orders that would take and any limit or stop price too far through the opposite
+// quote.
+func (v *Validator) checkPrice(product *ProductDetails, order *OrderRequest) error {
+	price := bandPrice(order)
+	if price == AmountZero {
+		return nil
+	}
+
+	// The quote a buy would take from is the ask; a sell takes from the bid
+	opposite := product.MarketAsk
+	if order.Side == OrderSideSell {
+		opposite = product.MarketBid
+	}
+	if opposite <= AmountZero {
+		return nil
+	}
+
+	crosses := price >= opposite
+	if order.Side == OrderSideSell {
+		crosses = price <= opposite
+	}
+	if order.PostOnly && crosses {
+		return rejectOrder(order, ErrPriceCrossesBook, fmt.Sprintf("market: %s", opposite))
+	}
+
+	if v.band <= AmountZero {
+		return nil
+	}
+	slack, err := opposite.Multiply(v.band, RoundFloor)
+	if err != nil {
+		return err
+	}
+	if order.Side == OrderSideBuy && price > opposite+slack {
+		return rejectOrder(order, ErrPriceOutsideBand, fmt.Sprintf("ask: %s; limit: %s", opposite, opposite+slack))
+	}
+	if order.Side == OrderSideSell && price < opposite-slack {
+		return rejectOrder(order, ErrPriceOutsideBand, fmt.Sprintf("bid: %s; limit: %s", opposite, opposite-slack))
+	}
+
+	return nil
+}
+
+// checkFunds compares what the order needs with the balance that isn't already held by live orders.
+func (v *Validator) checkFunds(ctx context.Context, product *ProductDetails, order *OrderRequest) error {
+	currency, need, err := required(product, order)
+	if err != nil {
+		return err
+	}
+	if need == AmountZero {
+		return nil
+	}
+
+	available, err := v.available(ctx, currency)
+	if err != nil {
+		return err
+	}
+	if need > available {
+		return rejectOrder(order, ErrInsufficientBalance, fmt.Sprintf("%s needed: %s; available: %s", currency, need, available))
+	}
+
+	return nil
+}
+
+// required is the currency and amount an order ties up. Buys without a limit price are valued at their stop price,
+// or at the ask for market orders. Buys allow for the taker fee, since even a limit buy can take if the market
+// moves.
+func required(product *ProductDetails, order *OrderRequest) (string, Amount, error) {
+	if order.Side == OrderSideSell {
+		return product.BaseCurrency, order.Quantity, nil
+	}
+
+	price := order.Price
+	if price == AmountZero {
+		price = order.StopPrice
+	}
+	if price == AmountZero {
+		price = product.MarketAsk
+	}
+	if price <= AmountZero {
+		return "", AmountZero, rejectOrder(order, ErrNoMarket, product.CurrencyPairCode)
+	}
+
+	cost, err := product.Fees().BuyCost(order.Quantity, price, true)
+	return product.QuotedCurrency, cost, err
+}
+
+// available is the balance of currency minus what live orders hold. Live buys hold their taker fee as well, the
+// same allowance required makes for new ones.
+func (v *Validator) available(ctx context.Context, currency string) (Amount, error) {
+	balances, err := v.PrivateClient.FetchAccountBalancesContext(ctx)
+	if err != nil {
+		return AmountZero, err
+	}
+	available := AmountZero
+	for _, b := range balances {
+		if b.Currency == currency {
+			available = b.Balance
+		}
+	}
+
+	live, err := v.PrivateClient.FetchAllOrdersContext(ctx, &OrdersQuery{Status: OrderStatusLive})
+	if err != nil {
+		return AmountZero, err
+	}
+	for _, o := range live {
+		remaining := o.Quantity - o.FilledQuantity
+		switch {
+		case o.Side == OrderSideSell && o.BaseCurrency == currency:
+			available -= remaining
+		case o.Side == OrderSideBuy && o.QuoteCurrency == currency:
+			fees := Fees{}
+			product, err := v.catalog.Product(ctx, o.ProductID)
+			if err == nil {
+				fees = product.Fees()
+			} else if err != ErrProductNotFound {
+				return AmountZero, err
+			}
+			held, err := fees.BuyCost(remaining, o.Price, true)
+			if err != nil {
+				return AmountZero, err
+			}
+			available -= held
+		}
+	}
+
+	return available, nil
+}
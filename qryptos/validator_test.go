@@ -0,0 +1,154 @@
+package qryptos_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/tobyjsullivan/shifty/qryptos"
+	"github.com/tobyjsullivan/shifty/qryptos/qryptostest"
+)
+
+func newValidator() (*qryptos.Validator, *qryptostest.Server) {
+	s := qryptostest.NewServer([]*qryptos.ProductDetails{qryptostest.VZTBTC(), {
+		ProductID:        57,
+		Currency:         "BTC",
+		BaseCurrency:     "ETH",
+		QuotedCurrency:   "BTC",
+		CurrencyPairCode: "ETHBTC",
+		MarketAsk:        qryptos.Amount(5020000),
+		MarketBid:        qryptos.Amount(5000000),
+		Disabled:         true,
+	}, {
+		ProductID:        58,
+		Currency:         "BTC",
+		BaseCurrency:     "XRP",
+		QuotedCurrency:   "BTC",
+		CurrencyPairCode: "XRPBTC",
+		MarketAsk:        qryptos.Amount(8010),
+		MarketBid:        qryptos.Amount(8000),
+		PriceTick:        qryptos.Amount(10),
+	}, {
+		ProductID:        59,
+		Currency:         "BTC",
+		BaseCurrency:     "XMR",
+		QuotedCurrency:   "BTC",
+		CurrencyPairCode: "XMRBTC",
+		MarketAsk:        qryptos.Amount(10400),
+		MarketBid:        qryptos.Amount(10000),
+		TakerFee:         qryptos.Amount(1000000),
+	}}, map[string]qryptos.Amount{"BTC": qryptos.Amount(1000000), "VZT": qryptos.Amount(5000000000)})
+
+	opts := []qryptos.ClientOption{qryptos.WithBaseURL(s.URL), qryptos.WithRateLimiter(nil)}
+	private := qryptos.NewPrivateClient(qryptostest.DefaultTokenID, qryptostest.DefaultSecret, opts...)
+	catalog := qryptos.NewCatalog(qryptos.NewPublicClient(opts...), time.Minute)
+
+	return qryptos.NewValidator(private, catalog), s
+}
+
+func limitOrder(productId int, side string, quantity, price qryptos.Amount) *qryptos.OrderRequest {
+	return &qryptos.OrderRequest{
+		ProductID: productId,
+		Type:      qryptos.OrderTypeLimit,
+		Side:      side,
+		Quantity:  quantity,
+		Price:     price,
+	}
+}
+
+func stopLimit(productId int, side string, stopPrice, price qryptos.Amount) *qryptos.OrderRequest {
+	order := limitOrder(productId, side, qryptos.Amount(1000000000), price)
+	order.Type = qryptos.OrderTypeStopLimit
+	order.StopPrice = stopPrice
+	return order
+}
+
+func TestValidator_Rejections(t *testing.T) {
+	v, s := newValidator()
+	defer s.Close()
+
+	postOnly := limitOrder(56, qryptos.OrderSideBuy, qryptos.Amount(1000000000), qryptos.Amount(10400))
+	postOnly.PostOnly = true
+
+	testCases := []struct {
+		name     string
+		order    *qryptos.OrderRequest
+		expected error
+	}{
+		{"unknown product", limitOrder(99, qryptos.OrderSideBuy, qryptos.Amount(1000000000), qryptos.Amount(10000)), qryptos.ErrProductNotFound},
+		{"disabled product", limitOrder(57, qryptos.OrderSideBuy, qryptos.Amount(1000000), qryptos.Amount(5000000)), qryptos.ErrProductDisabled},
+		{"below minimum", limitOrder(56, qryptos.OrderSideBuy, qryptos.Amount(10000000), qryptos.Amount(10000)), qryptos.ErrQuantityBelowMinimum},
+		{"post-only crossing", postOnly, qryptos.ErrPriceCrossesBook},
+		{"fat-finger buy", limitOrder(56, qryptos.OrderSideBuy, qryptos.Amount(1000000000), qryptos.Amount(11000)), qryptos.ErrPriceOutsideBand},
+		{"fat-finger sell", limitOrder(56, qryptos.OrderSideSell, qryptos.Amount(1000000000), qryptos.Amount(9000)), qryptos.ErrPriceOutsideBand},
+		{"too big to buy", limitOrder(56, qryptos.OrderSideBuy, qryptos.Amount(20000000000), qryptos.Amount(10000)), qryptos.ErrInsufficientBalance},
+		{"too big to sell", limitOrder(56, qryptos.OrderSideSell, qryptos.Amount(6000000000), qryptos.Amount(10500)), qryptos.ErrInsufficientBalance},
+		{"stop off the tick", stopLimit(58, qryptos.OrderSideBuy, qryptos.Amount(8105), qryptos.Amount(8110)), qryptos.ErrPriceTick},
+		{"fat-finger stop", stopLimit(56, qryptos.OrderSideBuy, qryptos.Amount(11000), qryptos.Amount(11100)), qryptos.ErrPriceOutsideBand},
+	}
+
+	for _, tc := range testCases {
+		_, err := v.CreateOrder(tc.order)
+		if !qryptos.IsValidationError(err, tc.expected) {
+			t.Errorf("Unexpected error for %s. Expected: %v; Actual: %v.", tc.name, tc.expected, err)
+		}
+	}
+
+	// Nothing should have reached the exchange
+	for _, req := range s.Requests() {
+		if req.Method == http.MethodPost {
+			t.Errorf("Unexpected request: %+v", req)
+		}
+	}
+}
+
+func TestValidator_ReservedFunds(t *testing.T) {
+	v, s := newValidator()
+	defer s.Close()
+
+	// 50 VZT at 0.0001 holds 0.005 of the 0.01 BTC
+	if _, err := v.CreateLimitOrder(56, qryptos.OrderSideBuy, qryptos.Amount(5000000000), qryptos.Amount(10000)); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	_, err := v.CreateLimitOrder(56, qryptos.OrderSideBuy, qryptos.Amount(6000000000), qryptos.Amount(10000))
+	if !qryptos.IsValidationError(err, qryptos.ErrInsufficientBalance) {
+		t.Errorf("Unexpected error. Expected: %v; Actual: %v.", qryptos.ErrInsufficientBalance, err)
+	}
+
+	if err := v.Check(context.Background(), limitOrder(56, qryptos.OrderSideBuy, qryptos.Amount(4000000000), qryptos.Amount(10000))); err != nil {
+		t.Errorf("Unexpected error for an order within the remaining balance: %s", err.Error())
+	}
+}
+
+func TestValidator_ReservedFundsIncludeFees(t *testing.T) {
+	v, s := newValidator()
+	defer s.Close()
+
+	// 90 XMR at 0.0001 with a 1% fee holds 0.00909 of the 0.01 BTC
+	if _, err := v.CreateLimitOrder(59, qryptos.OrderSideBuy, qryptos.Amount(9000000000), qryptos.Amount(10000)); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	// 9.5 more with their fee need 0.0009595, which only fits if the live buy's fee is left out
+	err := v.Check(context.Background(), limitOrder(59, qryptos.OrderSideBuy, qryptos.Amount(950000000), qryptos.Amount(10000)))
+	if !qryptos.IsValidationError(err, qryptos.ErrInsufficientBalance) {
+		t.Errorf("Unexpected error. Expected: %v; Actual: %v.", qryptos.ErrInsufficientBalance, err)
+	}
+}
+
+func TestValidator_StopOrdersBeyondTheMarket(t *testing.T) {
+	v, s := newValidator()
+	defer s.Close()
+
+	// Stop buys sit above the ask and stop sells below the bid until they trigger
+	for _, order := range []*qryptos.OrderRequest{
+		stopLimit(56, qryptos.OrderSideBuy, qryptos.Amount(10600), qryptos.Amount(10700)),
+		stopLimit(56, qryptos.OrderSideSell, qryptos.Amount(9800), qryptos.Amount(9700)),
+	} {
+		if err := v.Check(context.Background(), order); err != nil {
+			t.Errorf("Unexpected error for %s stop: %s", order.Side, err.Error())
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Package valuation converts between currencies using the quotes of the products that trade them. Currencies
+// without a direct product are converted through intermediate pairs, for example XMR to ETH through BTC.
+package valuation
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/tobyjsullivan/shifty/qryptos"
+)
+
+// MaxHops bounds how many products a conversion may pass through.
+const MaxHops = 3
+
+var ErrNoPath = errors.New("no conversion path between currencies")
+
+// Quote selects which side of each product's book conversions use. The side depends on which way a hop crosses
+// the product: selling the base currency meets the bid and buying it meets the ask.
+type Quote int
+
+const (
+	// Bid values holdings at what the market would pay for them, crossing the spread on every hop.
+	Bid Quote = iota
+	// Ask values holdings at what the market is asking for them, the other side of the spread on every hop.
+	Ask
+	// Mid uses the midpoint of the bid and ask in both directions.
+	Mid
+)
+
+// price is the quote for crossing p. Inverse crossings buy the base currency, so they take the opposite side of
+// the book to forward ones.
+func (q Quote) price(p *qryptos.ProductDetails, inverse bool) qryptos.Amount {
+	switch {
+	case q == Bid && !inverse, q == Ask && inverse:
+		return p.MarketBid
+	case q == Ask && !inverse, q == Bid && inverse:
+		return p.MarketAsk
+	}
+
+	if p.MarketBid <= qryptos.AmountZero || p.MarketAsk <= qryptos.AmountZero {
+		return qryptos.AmountZero
+	}
+	return (p.MarketBid + p.MarketAsk) / 2
+}
+
+// edge crosses product from one of its currencies to the other.
+type edge struct {
+	to      string
+	product *qryptos.ProductDetails
+
+	// inverse is set when crossing from the quoted currency to the base currency
+	inverse bool
+}
+
+// convert moves amount across the edge, rounding down so values are never overstated.
+func (e *edge) convert(amount qryptos.Amount, quote Quote) (qryptos.Amount, bool) {
+	price := quote.price(e.product, e.inverse)
+	if price <= qryptos.AmountZero {
+		return qryptos.AmountZero, false
+	}
+
+	var out qryptos.Amount
+	var err error
+	if e.inverse {
+		out, err = amount.Divide(price, qryptos.RoundFloor)
+	} else {
+		out, err = amount.Multiply(price, qryptos.RoundFloor)
+	}
+	return out, err == nil
+}
+
+// Graph links currencies through the products that trade them. It is a snapshot; build a new one when quotes
+// change.
+type Graph struct {
+	edges map[string][]*edge
+}
+
+// NewGraph builds a graph from products. Disabled products are left out.
+func NewGraph(products []*qryptos.ProductDetails) *Graph {
+	g := &Graph{edges: make(map[string][]*edge)}
+	for _, p := range products {
+		if p.Disabled || p.BaseCurrency == "" || p.QuotedCurrency == "" {
+			continue
+		}
+
+		g.edges[p.BaseCurrency] = append(g.edges[p.BaseCurrency], &edge{to: p.QuotedCurrency, product: p})
+		g.edges[p.QuotedCurrency] = append(g.edges[p.QuotedCurrency], &edge{to: p.BaseCurrency, product: p, inverse: true})
+	}
+
+	return g
+}
+
+// Convert values m in currency to, through whichever path of at most MaxHops products yields the most. It returns
+// ErrNoPath if the currencies aren't connected by products with the chosen quote.
+func (g *Graph) Convert(m qryptos.Money, to string, quote Quote) (qryptos.Money, error) {
+	if m.Currency == to {
+		return m, nil
+	}
+
+	best, ok := g.search(m.Currency, to, m.Amount, quote, map[string]bool{m.Currency: true}, MaxHops)
+	if !ok {
+		return qryptos.Money{}, ErrNoPath
+	}
+
+	return qryptos.NewMoney(best, to), nil
+}
+
+// search returns the most amount can become in currency to, visiting each currency at most once.
+func (g *Graph) search(from, to string, amount qryptos.Amount, quote Quote, visited map[string]bool, hops int) (qryptos.Amount, bool) {
+	var best qryptos.Amount
+	var found bool
+	for _, e := range g.edges[from] {
+		if visited[e.to] {
+			continue
+		}
+
+		out, ok := e.convert(amount, quote)
+		if !ok {
+			continue
+		}
+
+		if e.to == to {
+			if !found || out > best {
+				best, found = out, true
+			}
+			continue
+		}
+		if hops <= 1 {
+			continue
+		}
+
+		visited[e.to] = true
+		out, ok = g.search(e.to, to, out, quote, visited, hops-1)
+		delete(visited, e.to)
+		if ok && (!found || out > best) {
+			best, found = out, true
+		}
+	}
+
+	return best, found
+}
+
+// Rate is the best price of one unit of from in to.
+func (g *Graph) Rate(from, to string, quote Quote) (qryptos.Price, error) {
+	one, err := g.Convert(qryptos.NewMoney(qryptos.AmountRatio, from), to, quote)
+	if err != nil {
+		return qryptos.Price{}, err
+	}
+
+	return qryptos.NewPrice(one.Amount, from, to), nil
+}
+
+// Holding is one balance and its value in the reference currency.
+type Holding struct {
+	Balance qryptos.Money
+	Value   qryptos.Money
+}
+
+// Valuation is the value of a set of balances in a single reference currency.
+type Valuation struct {
+	Total    qryptos.Money
+	Holdings []*Holding
+
+	// Unpriced lists the currencies that couldn't be converted. They are left out of Total.
+	Unpriced []string
+}
+
+// Value converts every non-zero balance to currency and totals them. Balances that can't be converted are listed
+// in Unpriced rather than failing the whole valuation. Holdings are sorted by value, largest first.
+func (g *Graph) Value(balances []*qryptos.AccountBalance, currency string, quote Quote) (*Valuation, error) {
+	v := &Valuation{Total: qryptos.NewMoney(qryptos.AmountZero, currency)}
+	for _, b := range balances {
+		if b.Balance == qryptos.AmountZero {
+			continue
+		}
+
+		value, err := g.Convert(b.Money(), currency, quote)
+		if err == ErrNoPath {
+			v.Unpriced = append(v.Unpriced, b.Currency)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		v.Holdings = append(v.Holdings, &Holding{Balance: b.Money(), Value: value})
+		if v.Total, err = v.Total.Add(value); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(v.Holdings, func(i, j int) bool { return v.Holdings[i].Value.Amount > v.Holdings[j].Value.Amount })
+	sort.Strings(v.Unpriced)
+
+	return v, nil
+}
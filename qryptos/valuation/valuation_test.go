@@ -0,0 +1,110 @@
+package valuation
+
+import (
+	"testing"
+
+	"github.com/tobyjsullivan/shifty/qryptos"
+)
+
+func product(base, quote string, bid, ask qryptos.Amount) *qryptos.ProductDetails {
+	return &qryptos.ProductDetails{
+		BaseCurrency:     base,
+		QuotedCurrency:   quote,
+		CurrencyPairCode: base + quote,
+		MarketBid:        bid,
+		MarketAsk:        ask,
+	}
+}
+
+func testGraph() *Graph {
+	return NewGraph([]*qryptos.ProductDetails{
+		product("ETH", "BTC", qryptos.Amount(5000000), qryptos.Amount(5100000)),
+		product("XMR", "BTC", qryptos.Amount(2000000), qryptos.Amount(2020000)),
+		product("QASH", "ETH", qryptos.Amount(100000), qryptos.Amount(110000)),
+		product("BTC", "USD", qryptos.Amount(1000000000000), qryptos.Amount(1010000000000)),
+		{BaseCurrency: "LTC", QuotedCurrency: "BTC", MarketBid: qryptos.Amount(1500000), MarketAsk: qryptos.Amount(1520000), Disabled: true},
+	})
+}
+
+func TestGraph_Convert(t *testing.T) {
+	g := testGraph()
+
+	testCases := []struct {
+		from     qryptos.Money
+		to       string
+		quote    Quote
+		expected qryptos.Amount
+	}{
+		// Direct, in both directions
+		{qryptos.NewMoney(qryptos.Amount(200000000), "ETH"), "BTC", Bid, qryptos.Amount(10000000)},
+		{qryptos.NewMoney(qryptos.Amount(10000000), "BTC"), "ETH", Bid, qryptos.Amount(196078431)},
+		{qryptos.NewMoney(qryptos.Amount(10000000), "BTC"), "ETH", Ask, qryptos.Amount(200000000)},
+		{qryptos.NewMoney(qryptos.Amount(200000000), "ETH"), "BTC", Mid, qryptos.Amount(10100000)},
+		// Through one and two intermediate currencies
+		{qryptos.NewMoney(qryptos.Amount(100000000), "XMR"), "ETH", Bid, qryptos.Amount(39215686)},
+		{qryptos.NewMoney(qryptos.Amount(10000000000), "QASH"), "USD", Ask, qryptos.Amount(5666100000)},
+		// Same currency
+		{qryptos.NewMoney(qryptos.Amount(123), "BTC"), "BTC", Bid, qryptos.Amount(123)},
+	}
+
+	for _, tc := range testCases {
+		actual, err := g.Convert(tc.from, tc.to, tc.quote)
+		if err != nil {
+			t.Errorf("Unexpected error converting %s to %s: %s", tc.from, tc.to, err.Error())
+			continue
+		}
+		if actual.Currency != tc.to || actual.Amount != tc.expected {
+			t.Errorf("Unexpected conversion of %s. Expected: %s %s; Actual: %s.", tc.from, tc.expected, tc.to, actual)
+		}
+	}
+
+	// Disabled products don't connect anything
+	if _, err := g.Convert(qryptos.NewMoney(qryptos.Amount(100000000), "LTC"), "BTC", Bid); err != ErrNoPath {
+		t.Errorf("Unexpected error. Expected: %v; Actual: %v.", ErrNoPath, err)
+	}
+}
+
+func TestGraph_Convert_BestPath(t *testing.T) {
+	// ETH is worth more through USD than directly, even after crossing the BTC/USD spread
+	g := NewGraph([]*qryptos.ProductDetails{
+		product("ETH", "BTC", qryptos.Amount(5000000), qryptos.Amount(5100000)),
+		product("ETH", "USD", qryptos.Amount(60000000000), qryptos.Amount(61000000000)),
+		product("BTC", "USD", qryptos.Amount(1000000000000), qryptos.Amount(1010000000000)),
+	})
+
+	rate, err := g.Rate("ETH", "BTC", Bid)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if expected := qryptos.Amount(5940594); rate.Amount != expected {
+		t.Errorf("Unexpected rate. Expected: %s; Actual: %s.", expected, rate.Amount)
+	}
+	if rate.Base != "ETH" || rate.Quote != "BTC" {
+		t.Errorf("Unexpected rate currencies: %s", rate)
+	}
+}
+
+func TestGraph_Value(t *testing.T) {
+	g := testGraph()
+
+	v, err := g.Value([]*qryptos.AccountBalance{
+		{Currency: "BTC", Balance: qryptos.Amount(1000000)},
+		{Currency: "ETH", Balance: qryptos.Amount(100000000)},
+		{Currency: "QASH", Balance: qryptos.Amount(1000000000)},
+		{Currency: "XRP", Balance: qryptos.Amount(100000000)},
+		{Currency: "XMR", Balance: qryptos.AmountZero},
+	}, "BTC", Bid)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	if expected := qryptos.NewMoney(qryptos.Amount(6050000), "BTC"); v.Total != expected {
+		t.Errorf("Unexpected total. Expected: %s; Actual: %s.", expected, v.Total)
+	}
+	if len(v.Holdings) != 3 || v.Holdings[0].Balance.Currency != "ETH" {
+		t.Errorf("Unexpected holdings: %+v", v.Holdings)
+	}
+	if len(v.Unpriced) != 1 || v.Unpriced[0] != "XRP" {
+		t.Errorf("Unexpected unpriced currencies: %v", v.Unpriced)
+	}
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tobyjsullivan/shifty/qryptos"
+	"github.com/tobyjsullivan/shifty/qryptos/valuation"
+)
+
+// parseID reads the single positional ID a command takes.
+func parseID(flags *flag.FlagSet, what string) (int, error) {
+	if flags.NArg() != 1 {
+		return 0, fmt.Errorf("expected one %s", what)
+	}
+
+	id, err := strconv.Atoi(flags.Arg(0))
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", what, flags.Arg(0))
+	}
+
+	return id, nil
+}
+
+// amountFlag parses a decimal flag value into an Amount.
+type amountFlag struct {
+	amount qryptos.Amount
+	set    bool
+}
+
+func (f *amountFlag) String() string {
+	return f.amount.String()
+}
+
+func (f *amountFlag) Set(s string) error {
+	amount, err := qryptos.ParseAmount(s)
+	if err != nil {
+		return err
+	}
+	f.amount = amount
+	f.set = true
+
+	return nil
+}
+
+func runProducts(c *cli, args []string) error {
+	flags := c.newFlags("products")
+	quote := flags.String("quote", "", "only products quoted in this currency")
+	all := flags.Bool("all", false, "include disabled products")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	products, err := c.public.FetchProducts()
+	if err != nil {
+		return err
+	}
+
+	out := make([]*qryptos.ProductDetails, 0, len(products))
+	t := newTable("ID", "PAIR", "BID", "ASK", "VOLUME 24H", "TICK", "MIN QUANTITY", "DISABLED")
+	for _, p := range products {
+		if *quote != "" && p.QuotedCurrency != *quote {
+			continue
+		}
+		if p.Disabled && !*all {
+			continue
+		}
+
+		out = append(out, p)
+		t.add(p.ProductID, p.CurrencyPairCode, p.MarketBid, p.MarketAsk, p.Volume24Hour, p.PriceTick, p.MinimumOrderQuantity(), p.Disabled)
+	}
+
+	return c.print(t, out)
+}
+
+func runBook(c *cli, args []string) error {
+	flags := c.newFlags("book")
+	depth := flags.Int("depth", 10, "levels per side, 0 for the full book")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	productId, err := parseID(flags, "product ID")
+	if err != nil {
+		return err
+	}
+
+	book, err := c.public.FetchOrderBook(productId, *depth)
+	if err != nil {
+		return err
+	}
+	if *depth > 0 {
+		if len(book.Bids) > *depth {
+			book.Bids = book.Bids[:*depth]
+		}
+		if len(book.Asks) > *depth {
+			book.Asks = book.Asks[:*depth]
+		}
+	}
+
+	// Asks read top down to the spread, then bids from the spread down
+	t := newTable("SIDE", "PRICE", "QUANTITY")
+	for i := len(book.Asks) - 1; i >= 0; i-- {
+		t.add("ask", book.Asks[i].Price, book.Asks[i].Quantity)
+	}
+	for _, level := range book.Bids {
+		t.add("bid", level.Price, level.Quantity)
+	}
+
+	return c.print(t, book)
+}
+
+func orderTable(orders []*qryptos.OrderDetails) *table {
+	t := newTable("ID", "PAIR", "SIDE", "STATUS", "PRICE", "QUANTITY", "FILLED", "CREATED")
+	for _, o := range orders {
+		t.add(o.ID, o.CurrencyPairCode, o.Side, o.Status, o.Price, o.Quantity, o.FilledQuantity, o.CreatedAt)
+	}
+
+	return t
+}
+
+func runOrders(c *cli, args []string) error {
+	flags := c.newFlags("orders")
+	q := &qryptos.OrdersQuery{}
+	flags.StringVar(&q.Status, "status", "", "live, filled or cancelled")
+	flags.IntVar(&q.ProductID, "product", 0, "product ID")
+	flags.StringVar(&q.FundingCurrency, "funding", "", "funding currency")
+	flags.StringVar(&q.Side, "side", "", "buy or sell")
+	limit := flags.Int("limit", 50, "most orders to list, 0 for all")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	orders := []*qryptos.OrderDetails{}
+	it := c.private.IterateOrders(context.Background(), q)
+	for (*limit <= 0 || len(orders) < *limit) && it.Next() {
+		orders = append(orders, it.Order())
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	return c.print(orderTable(orders), orders)
+}
+
+func runOrder(c *cli, args []string) error {
+	flags := c.newFlags("order")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	orderId, err := parseID(flags, "order ID")
+	if err != nil {
+		return err
+	}
+
+	order, err := c.private.FetchOrder(orderId)
+	if err != nil {
+		return err
+	}
+	if err := c.print(orderTable([]*qryptos.OrderDetails{order}), order); err != nil || c.json {
+		return err
+	}
+
+	if len(order.Executions) == 0 {
+		return nil
+	}
+	fmt.Fprintln(c.out)
+	t := newTable("EXECUTION", "PRICE", "QUANTITY")
+	for _, e := range order.Executions {
+		t.add(e.ID, e.Price, e.Quantity)
+	}
+
+	return c.print(t, nil)
+}
+
+func runCreate(c *cli, args []string) error {
+	flags := c.newFlags("create")
+	order := &qryptos.OrderRequest{}
+	var quantity, price amountFlag
+	flags.IntVar(&order.ProductID, "product", 0, "product ID")
+	flags.StringVar(&order.Side, "side", "", "buy or sell")
+	flags.StringVar(&order.Type, "type", qryptos.OrderTypeLimit, "order type")
+	flags.Var(&quantity, "quantity", "quantity in the base currency")
+	flags.Var(&price, "price", "limit price")
+	flags.BoolVar(&order.PostOnly, "post-only", false, "reject the order if it would take")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	order.Quantity = quantity.amount
+	order.Price = price.amount
+
+	if err := order.Validate(); err != nil {
+		return err
+	}
+
+	action := fmt.Sprintf("Create %s %s of %s on product %d", order.Type, order.Side, order.Quantity, order.ProductID)
+	if price.set {
+		action += " at " + order.Price.String()
+	}
+	if err := c.confirm(action); err != nil {
+		return err
+	}
+
+	orderId, err := c.private.CreateOrder(order)
+	if err != nil {
+		return err
+	}
+
+	return c.print(&table{header: []string{"ID"}, rows: [][]string{{strconv.Itoa(orderId)}}}, map[string]int{"id": orderId})
+}
+
+func runEdit(c *cli, args []string) error {
+	flags := c.newFlags("edit")
+	var quantity, price amountFlag
+	flags.Var(&quantity, "quantity", "new quantity")
+	flags.Var(&price, "price", "new price")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	orderId, err := parseID(flags, "order ID")
+	if err != nil {
+		return err
+	}
+
+	// The exchange needs both fields, so fill in whichever wasn't given from the order as it stands
+	if !quantity.set || !price.set {
+		order, err := c.private.FetchOrder(orderId)
+		if err != nil {
+			return err
+		}
+		if !quantity.set {
+			quantity.amount = order.Quantity
+		}
+		if !price.set {
+			price.amount = order.Price
+		}
+	}
+
+	if err := c.confirm(fmt.Sprintf("Edit order %d to %s at %s", orderId, quantity.amount, price.amount)); err != nil {
+		return err
+	}
+	if err := c.private.EditOrder(orderId, quantity.amount, price.amount); err != nil {
+		return err
+	}
+
+	return runOrder(c, []string{strconv.Itoa(orderId)})
+}
+
+// cancelOrders cancels the orders f matches and reports the outcome of every one.
+func (c *cli) cancelOrders(orders []*qryptos.OrderDetails, f *qryptos.CancelFilter) error {
+	type result struct {
+		ID    int    `json:"id"`
+		Error string `json:"error,omitempty"`
+	}
+
+	report := qryptos.CancelMatching(context.Background(), orders, f, c.private.CancelOrderContext)
+
+	results := make([]*result, len(report.Results))
+	t := newTable("ID", "RESULT")
+	for i, r := range report.Results {
+		results[i] = &result{ID: r.Order.ID}
+		switch {
+		case qryptos.IsOrderNotFound(r.Err):
+			results[i].Error = r.Err.Error()
+			t.add(r.Order.ID, "already gone")
+		case r.Err != nil:
+			results[i].Error = r.Err.Error()
+			t.add(r.Order.ID, r.Err.Error())
+		default:
+			t.add(r.Order.ID, "cancelled")
+		}
+	}
+
+	if err := c.print(t, results); err != nil {
+		return err
+	}
+
+	return report.Err()
+}
+
+func runCancel(c *cli, args []string) error {
+	flags := c.newFlags("cancel")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("expected at least one order ID")
+	}
+
+	orders := make([]*qryptos.OrderDetails, flags.NArg())
+	for i, arg := range flags.Args() {
+		orderId, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("invalid order ID %q", arg)
+		}
+		orders[i] = &qryptos.OrderDetails{ID: orderId}
+	}
+
+	if err := c.confirm(fmt.Sprintf("Cancel %d orders", len(orders))); err != nil {
+		return err
+	}
+
+	return c.cancelOrders(orders, nil)
+}
+
+func runCancelAll(c *cli, args []string) error {
+	flags := c.newFlags("cancel-all")
+	f := &qryptos.CancelFilter{}
+	var above, below amountFlag
+	flags.StringVar(&f.Side, "side", "", "only cancel buy or sell orders")
+	flags.Var(&above, "above", "only cancel orders priced above this")
+	flags.Var(&below, "below", "only cancel orders priced below this")
+	flags.IntVar(&f.Concurrency, "concurrency", 0, "cancellations in flight at once")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	productId, err := parseID(flags, "product ID")
+	if err != nil {
+		return err
+	}
+	f.ProductID = productId
+	f.Match = func(o *qryptos.OrderDetails) bool {
+		return (!above.set || o.Price > above.amount) && (!below.set || o.Price < below.amount)
+	}
+
+	live, err := c.private.FetchAllOrders(&qryptos.OrdersQuery{
+		Status:    qryptos.OrderStatusLive,
+		ProductID: productId,
+		Side:      f.Side,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Only what was shown gets cancelled, even if more orders arrive while we wait for confirmation
+	var orders []*qryptos.OrderDetails
+	for _, o := range live {
+		if f.Match(o) {
+			orders = append(orders, o)
+		}
+	}
+	if len(orders) == 0 {
+		if c.json {
+			return c.print(nil, []struct{}{})
+		}
+		fmt.Fprintln(c.out, "No matching live orders.")
+		return nil
+	}
+
+	// With -json the listing goes with the prompt, so that the results are the only thing on out
+	listing := c.out
+	if c.json {
+		listing = c.prompt
+	}
+	if err := printTable(listing, orderTable(orders)); err != nil {
+		return err
+	}
+	if err := c.confirm(fmt.Sprintf("Cancel these %d orders", len(orders))); err != nil {
+		return err
+	}
+
+	return c.cancelOrders(orders, f)
+}
+
+func runBalances(c *cli, args []string) error {
+	flags := c.newFlags("balances")
+	all := flags.Bool("all", false, "include zero balances")
+	in := flags.String("in", "", "also value each balance in this currency at the market bid")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	balances, err := c.private.FetchAccountBalances()
+	if err != nil {
+		return err
+	}
+
+	if *in != "" {
+		return c.printValuation(balances, strings.ToUpper(*in))
+	}
+
+	out := make([]*qryptos.AccountBalance, 0, len(balances))
+	t := newTable("CURRENCY", "BALANCE")
+	for _, b := range balances {
+		if b.Balance == qryptos.AmountZero && !*all {
+			continue
+		}
+
+		out = append(out, b)
+		t.add(b.Currency, b.Balance)
+	}
+
+	return c.print(t, out)
+}
+
+func (c *cli) printValuation(balances []*qryptos.AccountBalance, currency string) error {
+	products, err := c.public.FetchProducts()
+	if err != nil {
+		return err
+	}
+
+	v, err := valuation.NewGraph(products).Value(balances, currency, valuation.Bid)
+	if err != nil {
+		return err
+	}
+
+	t := newTable("CURRENCY", "BALANCE", "VALUE")
+	for _, h := range v.Holdings {
+		t.add(h.Balance.Currency, h.Balance.Amount, h.Value.Amount)
+	}
+	for _, unpriced := range v.Unpriced {
+		t.add(unpriced, "", "-")
+	}
+	t.add("TOTAL", "", v.Total.Amount)
+
+	return c.print(t, v)
+}
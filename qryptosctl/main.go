@@ -0,0 +1,155 @@
+// Command qryptosctl inspects and manages the account by hand, for when the bots misbehave.
+//
+//	qryptosctl [-json] [-yes] [-base-url URL] COMMAND [ARGS]
+//
+// Credentials are read from QRYPTOS_API_TOKEN_ID and QRYPTOS_API_SECRET_KEY. Commands that change orders ask for
+// confirmation first unless -yes is given.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/tobyjsullivan/shifty/qryptos"
+	"github.com/tobyjsullivan/shifty/qryptos/auth"
+)
+
+var errAborted = errors.New("aborted")
+
+type command struct {
+	usage   string
+	summary string
+	run     func(c *cli, args []string) error
+}
+
+// commands is filled in by init, since the commands themselves look up their usage here.
+var commands map[string]*command
+
+func init() {
+	commands = map[string]*command{
+		"products":   {"products [-quote CURRENCY] [-all]", "List products", runProducts},
+		"book":       {"book [-depth N] PRODUCT_ID", "Show the order book", runBook},
+		"orders":     {"orders [-status STATUS] [-product ID] [-funding CURRENCY] [-side SIDE] [-limit N]", "List orders", runOrders},
+		"order":      {"order ORDER_ID", "Show an order and its executions", runOrder},
+		"create":     {"create -product ID -side SIDE -quantity Q [-price P] [-type TYPE] [-post-only]", "Create an order", runCreate},
+		"edit":       {"edit [-quantity Q] [-price P] ORDER_ID", "Edit a live order", runEdit},
+		"cancel":     {"cancel ORDER_ID...", "Cancel orders", runCancel},
+		"cancel-all": {"cancel-all [-side SIDE] [-above P] [-below P] [-concurrency N] PRODUCT_ID", "Cancel every live order for a product", runCancelAll},
+		"balances":   {"balances [-all]", "Show account balances", runBalances},
+	}
+}
+
+// cli carries the clients and output settings every command shares.
+type cli struct {
+	public  *qryptos.PublicClient
+	private *qryptos.PrivateClient
+	in      *bufio.Reader
+	out     io.Writer
+	prompt  io.Writer
+	json    bool
+	yes     bool
+}
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	if err == flag.ErrHelp {
+		// The usage has already been printed
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// run executes a command line. Results go to out, while usage, flag errors and confirmation prompts go to prompt.
+func run(args []string, in io.Reader, out, prompt io.Writer) error {
+	flags := flag.NewFlagSet("qryptosctl", flag.ContinueOnError)
+	flags.SetOutput(prompt)
+	jsonOutput := flags.Bool("json", false, "print JSON instead of tables")
+	yes := flags.Bool("yes", false, "don't ask before changing orders")
+	baseUrl := flags.String("base-url", "", "API base URL")
+	flags.Usage = func() {
+		fmt.Fprintln(prompt, "usage: qryptosctl [-json] [-yes] [-base-url URL] COMMAND [ARGS]")
+		fmt.Fprintln(prompt)
+
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(prompt, "  %-12s %s\n", name, commands[name].summary)
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return flag.ErrHelp
+	}
+
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		flags.Usage()
+		return fmt.Errorf("unknown command %q", flags.Arg(0))
+	}
+
+	// The clients' progress messages would end up mixed into the results
+	opts := []qryptos.ClientOption{qryptos.WithLogOutput(ioutil.Discard)}
+	if *baseUrl != "" {
+		opts = append(opts, qryptos.WithBaseURL(*baseUrl))
+	}
+
+	c := &cli{
+		public:  qryptos.NewPublicClient(opts...),
+		private: qryptos.NewPrivateClientWithCredentials(auth.DefaultEnv, opts...),
+		in:      bufio.NewReader(in),
+		out:     out,
+		prompt:  prompt,
+		json:    *jsonOutput,
+		yes:     *yes,
+	}
+
+	return cmd.run(c, flags.Args()[1:])
+}
+
+// newFlags returns a flag set for a command whose usage prints the command's synopsis.
+func (c *cli) newFlags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(c.prompt)
+	flags.Usage = func() {
+		fmt.Fprintln(c.prompt, "usage: qryptosctl", commands[name].usage)
+		flags.PrintDefaults()
+	}
+
+	return flags
+}
+
+// confirm asks before a mutating command. Anything but y or yes aborts.
+func (c *cli) confirm(action string) error {
+	if c.yes {
+		return nil
+	}
+
+	fmt.Fprintf(c.prompt, "%s. Proceed? [y/N] ", action)
+	answer, err := c.in.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+
+	return errAborted
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tobyjsullivan/shifty/qryptos"
+	"github.com/tobyjsullivan/shifty/qryptos/qryptostest"
+)
+
+func newTestServer() *qryptostest.Server {
+	return newTestServerWithBalances(map[string]qryptos.Amount{"BTC": qryptos.Amount(1000000)})
+}
+
+func newTestServerWithBalances(balances map[string]qryptos.Amount) *qryptostest.Server {
+	os.Setenv("QRYPTOS_API_TOKEN_ID", qryptostest.DefaultTokenID)
+	os.Setenv("QRYPTOS_API_SECRET_KEY", qryptostest.DefaultSecret)
+
+	return qryptostest.NewServer([]*qryptos.ProductDetails{qryptostest.VZTBTC()}, balances)
+}
+
+func runTest(s *qryptostest.Server, input string, args ...string) (string, error) {
+	out, _, err := runTestWithPrompt(s, input, args...)
+	return out, err
+}
+
+func runTestWithPrompt(s *qryptostest.Server, input string, args ...string) (string, string, error) {
+	var out, prompt bytes.Buffer
+	err := run(append([]string{"-base-url", s.URL}, args...), strings.NewReader(input), &out, &prompt)
+
+	return out.String(), prompt.String(), err
+}
+
+func liveOrders(t *testing.T, s *qryptostest.Server) []*qryptos.OrderDetails {
+	orders, err := s.Exchange().FetchAllOrdersContext(context.Background(), &qryptos.OrdersQuery{Status: qryptos.OrderStatusLive})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	return orders
+}
+
+func TestBalances(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+
+	out, err := runTest(s, "", "balances")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if expected := "CURRENCY  BALANCE\nBTC       0.01000000\n"; out != expected {
+		t.Errorf("Unexpected output. Expected: %q; Actual: %q.", expected, out)
+	}
+}
+
+func TestBalances_Valued(t *testing.T) {
+	s := newTestServerWithBalances(map[string]qryptos.Amount{
+		"BTC": qryptos.Amount(1000000),
+		"VZT": qryptos.Amount(10000000000),
+	})
+	defer s.Close()
+
+	out, err := runTest(s, "", "balances", "-in", "btc")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	expected := "CURRENCY  BALANCE       VALUE\n" +
+		"BTC       0.01000000    0.01000000\n" +
+		"VZT       100.00000000  0.01000000\n" +
+		"TOTAL                   0.02000000\n"
+	if out != expected {
+		t.Errorf("Unexpected output. Expected: %q; Actual: %q.", expected, out)
+	}
+}
+
+func TestCreate_Confirmation(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+
+	args := []string{"create", "-product", "56", "-side", "buy", "-quantity", "10", "-price", "0.00009"}
+	if _, err := runTest(s, "n\n", args...); err != errAborted {
+		t.Errorf("Expected the command to be aborted; Actual: %v.", err)
+	}
+	if orders := liveOrders(t, s); len(orders) != 0 {
+		t.Fatalf("Unexpected order count. Expected: 0; Actual: %d.", len(orders))
+	}
+
+	if _, err := runTest(s, "y\n", args...); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	out, err := runTest(s, "", "-json", "orders", "-status", "live")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	var orders []*qryptos.OrderDetails
+	if err := json.Unmarshal([]byte(out), &orders); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if len(orders) != 1 || orders[0].Price != qryptos.Amount(9000) || orders[0].Quantity != qryptos.Amount(1000000000) {
+		t.Errorf("Unexpected orders: %s", out)
+	}
+}
+
+func TestCancelAll(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+
+	for _, price := range []string{"0.00009", "0.000091"} {
+		if _, err := runTest(s, "", "-yes", "create", "-product", "56", "-side", "buy", "-quantity", "10", "-price", price); err != nil {
+			t.Fatalf("Unexpected error: %s", err.Error())
+		}
+	}
+
+	out, err := runTest(s, "yes\n", "cancel-all", "56")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if strings.Count(out, "cancelled") != 2 {
+		t.Errorf("Expected two cancellations: %s", out)
+	}
+	if orders := liveOrders(t, s); len(orders) != 0 {
+		t.Errorf("Unexpected live order count. Expected: 0; Actual: %d.", len(orders))
+	}
+
+	out, err = runTest(s, "", "-yes", "cancel", "999")
+	if err != nil || !strings.Contains(out, "already gone") {
+		t.Errorf("Expected an unknown order to be reported as gone: %s (%v)", out, err)
+	}
+
+	s.Inject(qryptostest.Fault{Path: "/orders/1000/cancel", Status: 500})
+	if _, err := runTest(s, "", "-yes", "cancel", "1000"); err == nil {
+		t.Error("Expected a failed cancellation to be an error.")
+	}
+}
+
+func TestCancelAll_JSON(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+
+	out, err := runTest(s, "", "-json", "cancel-all", "56")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if expected := "[]\n"; out != expected {
+		t.Errorf("Unexpected output. Expected: %q; Actual: %q.", expected, out)
+	}
+
+	if _, err := runTest(s, "", "-yes", "create", "-product", "56", "-side", "buy", "-quantity", "10", "-price", "0.00009"); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	orderId := liveOrders(t, s)[0].ID
+
+	// The orders are listed before the prompt, and declining leaves nothing on out
+	out, prompt, err := runTestWithPrompt(s, "n\n", "-json", "cancel-all", "56")
+	if err != errAborted {
+		t.Errorf("Expected the command to be aborted; Actual: %v.", err)
+	}
+	if out != "" {
+		t.Errorf("Unexpected output: %q", out)
+	}
+	listing := fmt.Sprintf("\n%d ", orderId)
+	if i := strings.Index(prompt, listing); i < 0 || i > strings.Index(prompt, "Proceed?") {
+		t.Errorf("Expected order %d to be listed before the prompt: %q", orderId, prompt)
+	}
+}
+
+func TestUsageIsKeptOutOfResults(t *testing.T) {
+	s := newTestServer()
+	defer s.Close()
+
+	for _, args := range [][]string{{"-json"}, {"-json", "orders", "-bogus"}} {
+		out, err := runTest(s, "", args...)
+		if err == nil {
+			t.Errorf("Expected an error for %v.", args)
+		}
+		if out != "" {
+			t.Errorf("Unexpected output for %v: %q", args, out)
+		}
+	}
+}
@@ -1,17 +1,26 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"github.com/tobyjsullivan/shifty/exchange"
 	"github.com/tobyjsullivan/shifty/qryptos"
+	"github.com/tobyjsullivan/shifty/qryptos/candles"
 	"sort"
+	"time"
 )
 
 const qryptosApiUrl = "https://api.qryptos.com"
 const availableCapital = 0.03
 const topN = 5
 
+// Only liquidity within this fraction of the mid price counts towards a market's depth
+const depthBand = 0.02
+
 func main() {
-	productDetails, err := qryptos.DefaultClient().FetchProducts()
+	var md exchange.MarketData = qryptos.DefaultClient()
+
+	productDetails, err := md.FetchProductsContext(context.Background())
 	if err != nil {
 		panic(err)
 	}
@@ -23,7 +32,11 @@ func main() {
 			continue
 		}
 
-		r := buildReport(prodData)
+		r, err := buildReport(md, prodData)
+		if err != nil {
+			fmt.Println("Skipping", prodData.CurrencyPairCode, "-", err.Error())
+			continue
+		}
 		printReport(r)
 
 		reports = append(reports, r)
@@ -74,14 +87,47 @@ type report struct {
 	spread        float64
 	volume24Hr    float64
 	volume24HrBtc float64
+	bidDepth      float64
+	askDepth      float64
+	volatility    float64
+	trend         float64
 	weight        float64
 }
 
-func buildReport(details *qryptos.ProductDetails) *report {
+func buildReport(md exchange.MarketData, details *qryptos.ProductDetails) (*report, error) {
+	ctx := context.Background()
 
 	spread := details.MarketAsk - details.MarketBid
 	currentRate := (details.MarketBid + details.MarketAsk) / 2.0
-	volume24HrBtc := details.Volume24Hour * currentRate
+	volume24HrBtc, err := details.Volume24Hour.Multiply(currentRate, qryptos.RoundHalfEven)
+	if err != nil {
+		return nil, err
+	}
+
+	book, err := md.FetchOrderBookContext(ctx, details.ProductID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var band qryptos.Amount
+	band.FromDecimal(currentRate.ToDecimal() * depthBand)
+	bidDepth := book.DepthWithin(qryptos.OrderSideBuy, currentRate-band)
+	askDepth := book.DepthWithin(qryptos.OrderSideSell, currentRate+band)
+
+	trades, err := md.FetchExecutionsContext(ctx, details.ProductID, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	hourly, err := candles.Build(trades, candles.OneHour)
+	if err != nil {
+		return nil, err
+	}
+
+	// A market is only as deep as its thinner side since we need to both buy and sell
+	depth := bidDepth
+	if askDepth < depth {
+		depth = askDepth
+	}
 
 	return &report{
 		currencyPair:  details.CurrencyPairCode,
@@ -90,8 +136,12 @@ func buildReport(details *qryptos.ProductDetails) *report {
 		spread:        spread.ToDecimal(),
 		volume24Hr:    details.Volume24Hour.ToDecimal(),
 		volume24HrBtc: volume24HrBtc.ToDecimal(),
-		weight:        spread.ToDecimal() * details.Volume24Hour.ToDecimal(),
-	}
+		bidDepth:      bidDepth.ToDecimal(),
+		askDepth:      askDepth.ToDecimal(),
+		volatility:    candles.Volatility(hourly),
+		trend:         candles.Trend(hourly),
+		weight:        spread.ToDecimal() * depth.ToDecimal(),
+	}, nil
 }
 
 func printReport(r *report) {
@@ -101,6 +151,10 @@ func printReport(r *report) {
 	fmt.Println(fmt.Sprintf("- Spread: %.08f", r.spread))
 	fmt.Println(fmt.Sprintf("- Volume: %.08f", r.volume24Hr))
 	fmt.Println(fmt.Sprintf("- Volume (BTC): %.08f", r.volume24HrBtc))
+	fmt.Println(fmt.Sprintf("- Bid Depth: %.08f", r.bidDepth))
+	fmt.Println(fmt.Sprintf("- Ask Depth: %.08f", r.askDepth))
+	fmt.Println(fmt.Sprintf("- Hourly Volatility: %.04f", r.volatility))
+	fmt.Println(fmt.Sprintf("- 24h Trend: %.04f", r.trend))
 	fmt.Println(fmt.Sprintf("- Weight: %.08f", r.weight))
 
 	fmt.Println()
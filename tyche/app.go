@@ -1,7 +1,11 @@
 package main
 
 import (
+	"context"
+	"github.com/tobyjsullivan/shifty/exchange"
+	"github.com/tobyjsullivan/shifty/exchange/paper"
 	"github.com/tobyjsullivan/shifty/qryptos"
+	"github.com/tobyjsullivan/shifty/qryptos/valuation"
 	"log"
 	"os"
 	"time"
@@ -12,17 +16,31 @@ import (
 const (
 	envApiKey    = "QRYPTOS_API_TOKEN_ID"
 	envApiSecret = "QRYPTOS_API_SECRET_KEY"
+	envLiquidate = "TYCHE_LIQUIDATE"
+	envPaper     = "PAPER_TRADING"
+	envNonceFile = "QRYPTOS_NONCE_FILE"
 	loopDelay    = 10 * time.Second
+
+	// The catalog is refreshed in the background every loop, so lookups within a loop rarely wait on a fetch
+	productCacheTTL = loopDelay
+
+	// Paper trading starts with 0.01 BTC
+	paperBalance = qryptos.Amount(1000000)
 )
 
 var (
 	qryptosApiKey    = os.Getenv(envApiKey)
 	qryptosApiSecret = os.Getenv(envApiSecret)
-	publicClient     = qryptos.DefaultClient()
-	privateClient    = qryptos.NewPrivateClient(qryptosApiKey, qryptosApiSecret)
-	productIdLookup  = make(map[int]string)
+	paperTrading     = os.Getenv(envPaper) == "true"
+	liquidate        = os.Getenv(envLiquidate) == "true"
 )
 
+// venue is replaced by a paper exchange when paper trading
+var venue exchange.Exchange = exchange.NewQryptos(qryptos.DefaultClient(), qryptos.NewPrivateClient(qryptosApiKey, qryptosApiSecret))
+
+// catalog follows venue's products, and is replaced along with it when paper trading
+var catalog = qryptos.NewCatalog(venue, productCacheTTL)
+
 type currencyStatus struct {
 	currency       string
 	balance        qryptos.Amount
@@ -31,72 +49,101 @@ type currencyStatus struct {
 }
 
 func main() {
-	if qryptosApiKey == "" {
+	if qryptosApiKey == "" && !paperTrading {
 		log.Fatalln("Must set", envApiKey)
 	}
-	if qryptosApiSecret == "" {
+	if qryptosApiSecret == "" && !paperTrading {
 		log.Fatalln("Must set", envApiSecret)
 	}
 
+	if nonceFile := os.Getenv(envNonceFile); nonceFile != "" {
+		nonces, err := qryptos.NewFileNonceSource(nonceFile)
+		if err != nil {
+			log.Fatalln("error: failed to load nonce file:", err)
+		}
+		qryptos.SetSharedNonceSource(qryptosApiKey, nonces)
+	}
+
+	if liquidate {
+		log.Println("[main] Liquidation mode enabled. All holdings will be sold at market.")
+	}
+
 	log.Println("[main] Initializing...")
-	products, err := publicClient.FetchProducts()
+	products, err := catalog.Products(context.Background())
 	if err != nil {
 		log.Fatalln("error: failed to fetch products:", err)
 	}
 
-	for _, product := range products {
-		productIdLookup[product.ProductID] = product.CurrencyPairCode
+	if paperTrading {
+		log.Println("[main] Paper trading enabled. No real orders will be placed.")
+		sim := paper.New(products, map[string]qryptos.Amount{"BTC": paperBalance})
+		go sim.Follow(context.Background(), qryptos.DefaultClient(), loopDelay)
+		venue = sim
+		catalog = qryptos.NewCatalog(sim, productCacheTTL)
 	}
 
+	catalog.OnChange(logProductChanges)
+	go catalog.Run(context.Background(), loopDelay)
+
 	ticker := time.NewTicker(loopDelay)
 	for range ticker.C {
 		log.Println("[main] Triggering loop...")
-		go loop()
+		go loop(venue)
 	}
 }
 
-func loop() {
+func loop(ex exchange.Exchange) {
 	var p plan.Plan
 
-	log.Println("[loop] Fetching products...")
-	products, err := publicClient.FetchProducts()
+	// Bound the data-gathering phase so a hung request can't stack loops on top of each other
+	ctx, cancel := context.WithTimeout(context.Background(), loopDelay)
+	defer cancel()
+
+	// Products are refreshed in the background; this only fetches if that has fallen behind
+	products, err := catalog.Products(ctx)
 	if err != nil {
 		log.Println("error: failed to fetch products:", err)
 		return
 	}
 
-	productMap := make(map[string]*qryptos.ProductDetails)
-	productIdLookup := make(map[int]string)
-	for _, product := range products {
-		productMap[product.CurrencyPairCode] = product
-		productIdLookup[product.ProductID] = product.CurrencyPairCode
-	}
-
 	log.Println("[loop] Fetching balances...")
-	acctBalances, err := privateClient.FetchAccountBalances()
+	acctBalances, err := ex.FetchAccountBalancesContext(ctx)
 	if err != nil {
 		log.Println("error: failed to fetch balances:", err)
 		return
 	}
 
-	balanceMap := make(map[string]qryptos.Amount)
+	balanceMap := make(map[string]qryptos.Money)
 	for _, acctInfo := range acctBalances {
 		if acctInfo.Balance == qryptos.AmountZero {
 			continue
 		}
 
-		balanceMap[acctInfo.Currency] = acctInfo.Balance
+		balanceMap[acctInfo.Currency] = acctInfo.Money()
 	}
 
-	btcBalance := balanceMap["BTC"]
+	btcBalance := qryptos.NewMoney(balanceMap["BTC"].Amount, "BTC")
+
+	// Holdings without a BTC market of their own are still valued, through whatever pairs connect them
+	if equity, err := valuation.NewGraph(products).Value(acctBalances, "BTC", valuation.Bid); err != nil {
+		log.Println("error: failed to value balances:", err)
+	} else {
+		log.Println("[loop] Account value:", equity.Total, "; Unpriced:", equity.Unpriced)
+	}
 
 	log.Println("[loop] Fetching orders...")
-	orderDetails, err := privateClient.FetchOrders()
+	orderDetails, err := ex.FetchAllOrdersContext(ctx, &qryptos.OrdersQuery{Status: qryptos.OrderStatusLive})
 	if err != nil {
 		log.Println("error: failed to fetch orders:", err)
 		return
 	}
 
+	if liquidate {
+		planLiquidation(ctx, &p, ex, balanceMap)
+		p.Apply()
+		return
+	}
+
 	// TODO Identify currencies we should be buying (disragarding current orders)
 	// Hack: Just hardcoded for now
 	buyCurrencies := []string{
@@ -107,8 +154,8 @@ func loop() {
 	}
 
 	// Divy up buy budget
-	portion := qryptos.Amount(int(btcBalance) / len(buyCurrencies))
-	buyAmounts := make(map[string]qryptos.Amount)
+	portion := qryptos.NewMoney(btcBalance.Amount/qryptos.Amount(len(buyCurrencies)), btcBalance.Currency)
+	buyAmounts := make(map[string]qryptos.Money)
 	for _, pairCode := range buyCurrencies {
 		buyAmounts[pairCode] = portion
 	}
@@ -120,7 +167,11 @@ func loop() {
 		}
 
 		pairCode := fmt.Sprintf("%s%s", currency, "BTC")
-		product := productMap[pairCode]
+		product, err := catalog.ProductByPair(ctx, pairCode)
+		if err != nil {
+			log.Println("[loop] No product to sell", currency, ". Error:", err)
+			continue
+		}
 		if product.Disabled {
 			continue
 		}
@@ -129,7 +180,8 @@ func loop() {
 		mktAsk := product.MarketAsk
 
 		// Find any open orders for that product
-		pendingSells := qryptos.Amount(0)
+		pendingSells := qryptos.NewMoney(qryptos.AmountZero, product.BaseCurrency)
+		overpriced := false
 		for _, order := range orderDetails {
 			if order.Status != qryptos.OrderStatusLive {
 				continue
@@ -142,37 +194,74 @@ func loop() {
 				continue
 			}
 
-			// Cancel any sell orders with price greater than current marketAsk
+			// Sell orders priced above the current market ask are cancelled together below
 			if order.Price > mktAsk {
-				p.QueueStep(&CancelOrderStep{order.ID})
+				overpriced = true
 				continue
 			}
 
 			// Otherwise, count the unfilled amount of the sell order
-			pendingSells += order.Quantity
-			if _, ok := buyAmounts[product.CurrencyPairCode]; ok {
-				buyAmounts[product.CurrencyPairCode] -= order.Quantity.Multiply(mktAsk)
+			pendingSells, err = pendingSells.Add(order.Size())
+			if err != nil {
+				log.Println("error: failed to total pending sells:", err)
+				return
+			}
+			if buyAmt, ok := buyAmounts[product.CurrencyPairCode]; ok {
+				value, err := product.Ask().Convert(order.Size(), qryptos.RoundCeil)
+				if err != nil {
+					log.Println("error: failed to value sell order:", err)
+					return
+				}
+				if buyAmounts[product.CurrencyPairCode], err = buyAmt.Sub(value); err != nil {
+					log.Println("error: failed to update buy amount:", err)
+					return
+				}
 			}
 		}
 
+		if overpriced {
+			p.QueueStep(&CancelOrdersStep{ex, &qryptos.CancelFilter{
+				ProductID: product.ProductID,
+				Side:      qryptos.OrderSideSell,
+				Match:     func(o *qryptos.OrderDetails) bool { return o.Price > mktAsk },
+			}})
+		}
+
 		// Create a new order for any remaining balance
-		remBalance := bal - pendingSells
-		if min := qryptos.MinimumOrderQuantity(product.BaseCurrency); remBalance < min {
-			log.Println("[loop] Quantity too small for sell order. Book:", pairCode, "; Quantity:", remBalance, "; Min:", min)
+		remBalance, err := bal.Sub(pendingSells)
+		if err != nil {
+			log.Println("error: failed to compute remaining balance:", err)
+			return
+		}
+		sellQuantity := product.QuantizeQuantity(remBalance.Amount)
+		sellPrice := product.QuantizePrice(mktAsk-product.Tick(), qryptos.OrderSideSell)
+		if err := product.CheckOrder(sellQuantity, sellPrice); err != nil {
+			log.Println("[loop] Cannot place sell order. Book:", pairCode, "; Error:", err)
 		} else {
 			p.QueueStep(&CreateLimitOrderStep{
+				ex,
 				product.ProductID,
 				qryptos.OrderSideSell,
-				remBalance,
-				mktAsk - qryptos.MinimalUnit,
+				sellQuantity,
+				sellPrice,
 			})
 		}
-		if _, ok := buyAmounts[product.CurrencyPairCode]; ok {
-			buyAmounts[product.CurrencyPairCode] -= remBalance.Multiply(mktAsk)
+		if buyAmt, ok := buyAmounts[product.CurrencyPairCode]; ok {
+			value, err := product.Ask().Convert(remBalance, qryptos.RoundCeil)
+			if err != nil {
+				log.Println("error: failed to value remaining balance:", err)
+				return
+			}
+			if buyAmounts[product.CurrencyPairCode], err = buyAmt.Sub(value); err != nil {
+				log.Println("error: failed to update buy amount:", err)
+				return
+			}
 		}
 	}
 
-	// Cancel any current buy orders which are not in our buyList
+	// Cancel any current buy orders which are not in our buyList, in one step per product
+	var cancelProducts []int
+	cancelBuys := make(map[int]map[int]bool)
 	for _, order := range orderDetails {
 		if order.Status != qryptos.OrderStatusLive || order.Side != qryptos.OrderSideBuy {
 			continue
@@ -180,17 +269,45 @@ func loop() {
 
 		if buyAmt, wantToBuy := buyAmounts[order.CurrencyPairCode]; wantToBuy {
 			// Check if this is already at market bid
-			product := productMap[order.CurrencyPairCode]
-			desiredQty := buyAmt.Multiply(product.MarketBid)
-			cancelThreshold := desiredQty / 2
+			product, err := catalog.ProductByPair(ctx, order.CurrencyPairCode)
+			if err != nil {
+				log.Println("error: failed to find product:", err)
+				return
+			}
+			desiredQty, err := product.Bid().ConvertToBase(buyAmt, qryptos.RoundFloor)
+			if err != nil {
+				log.Println("error: failed to compute desired quantity:", err)
+				return
+			}
+			cancelThreshold := desiredQty.Amount / 2
 			if order.Price == product.MarketBid && order.Quantity > cancelThreshold {
 				log.Println("[loop] Current buy order for", order.CurrencyPairCode, "is good.")
-				buyAmounts[order.CurrencyPairCode] -= order.Quantity.Multiply(order.Price)
+				value, err := order.LimitPrice().Convert(order.Size(), qryptos.RoundCeil)
+				if err != nil {
+					log.Println("error: failed to value buy order:", err)
+					return
+				}
+				if buyAmounts[order.CurrencyPairCode], err = buyAmt.Sub(value); err != nil {
+					log.Println("error: failed to update buy amount:", err)
+					return
+				}
 				continue
 			}
 		}
 
-		p.QueueStep(&CancelOrderStep{order.ID})
+		if cancelBuys[order.ProductID] == nil {
+			cancelProducts = append(cancelProducts, order.ProductID)
+			cancelBuys[order.ProductID] = make(map[int]bool)
+		}
+		cancelBuys[order.ProductID][order.ID] = true
+	}
+	for _, productId := range cancelProducts {
+		orderIds := cancelBuys[productId]
+		p.QueueStep(&CancelOrdersStep{ex, &qryptos.CancelFilter{
+			ProductID: productId,
+			Side:      qryptos.OrderSideBuy,
+			Match:     func(o *qryptos.OrderDetails) bool { return orderIds[o.ID] },
+		}})
 	}
 
 	var i int
@@ -201,24 +318,35 @@ func loop() {
 
 	// create buy orders
 	for pairCode, amount := range buyAmounts {
-		product := productMap[pairCode]
+		product, err := catalog.ProductByPair(ctx, pairCode)
+		if err != nil {
+			log.Println("[loop] No product to buy", pairCode, ". Error:", err)
+			continue
+		}
 		if product.Disabled {
 			continue
 		}
 
-		bidPrice := product.MarketBid + qryptos.MinimalUnit
+		bidPrice := product.QuantizePrice(product.MarketBid+product.Tick(), qryptos.OrderSideBuy)
 		if bidPrice >= product.MarketAsk {
 			continue
 		}
 
-		quantity := amount.Divide(bidPrice)
+		bid := qryptos.NewPrice(bidPrice, product.BaseCurrency, product.QuotedCurrency)
+		buyQuantity, err := bid.ConvertToBase(amount, qryptos.RoundFloor)
+		if err != nil {
+			log.Println("error: failed to compute buy quantity:", err)
+			return
+		}
+		quantity := product.QuantizeQuantity(buyQuantity.Amount)
 
-		if quantity <= qryptos.MinimumOrderQuantity(product.BaseCurrency) {
-			log.Println("[loop] Order too small for", pairCode, ". Quantity:", quantity)
+		if err := product.CheckOrder(quantity, bidPrice); err != nil {
+			log.Println("[loop] Cannot place buy order for", pairCode, ". Error:", err)
 			continue
 		}
 
 		p.QueueStep(&CreateLimitOrderStep{
+			ex,
 			product.ProductID,
 			qryptos.OrderSideBuy,
 			quantity,
@@ -226,6 +354,10 @@ func loop() {
 		})
 	}
 
+	if reporter, ok := ex.(exchange.StatsReporter); ok {
+		log.Printf("[loop] Client stats: %+v\n", reporter.Stats())
+	}
+
 	log.Println("[loop] Finished planning")
 	for _, step := range p.Steps {
 		fmt.Println("[loop] Planned Step:", step.String())
@@ -234,34 +366,40 @@ func loop() {
 	p.Apply()
 }
 
-type CancelOrderStep struct {
-	orderId int
-}
-
-func (s *CancelOrderStep) Apply() error {
-	return privateClient.CancelOrder(s.orderId)
-}
-
-func (s *CancelOrderStep) String() string {
-	return fmt.Sprintf("Cancel order %d", s.orderId)
+// logProductChanges reports listings and delistings, which may call for a change to the hardcoded buy list.
+func logProductChanges(changes *qryptos.CatalogChanges) {
+	for _, product := range changes.Added {
+		log.Println("[catalog] Product added:", product.CurrencyPairCode)
+	}
+	for _, product := range changes.Removed {
+		log.Println("[catalog] Product removed:", product.CurrencyPairCode)
+	}
+	for _, product := range changes.Disabled {
+		log.Println("[catalog] Product disabled:", product.CurrencyPairCode)
+	}
+	for _, product := range changes.Enabled {
+		log.Println("[catalog] Product enabled:", product.CurrencyPairCode)
+	}
 }
 
 type EditOrderStep struct {
+	orders   exchange.OrderManager
 	orderId  int
 	quantity qryptos.Amount
 	price    qryptos.Amount
 }
 
 func (s *EditOrderStep) Apply() error {
-	return privateClient.EditOrder(s.orderId, s.quantity, s.price)
+	return classifyStepError(s.orders.EditOrderContext(context.Background(), s.orderId, s.quantity, s.price))
 }
 
 func (s *EditOrderStep) String() string {
-	return fmt.Sprintf("Edit order %d. Quantity: %.08f; Price: %.08f",
-		s.orderId, s.quantity.ToDecimal(), s.price.ToDecimal())
+	return fmt.Sprintf("Edit order %d. Quantity: %s; Price: %s",
+		s.orderId, s.quantity, s.price)
 }
 
 type CreateLimitOrderStep struct {
+	orders    exchange.OrderManager
 	productId int
 	side      string
 	quantity  qryptos.Amount
@@ -269,16 +407,35 @@ type CreateLimitOrderStep struct {
 }
 
 func (s *CreateLimitOrderStep) Apply() error {
-	orderId, err := privateClient.CreateLimitOrder(s.productId, s.side, s.quantity, s.price)
+	orderId, err := s.orders.CreateOrderContext(context.Background(), &qryptos.OrderRequest{
+		ProductID: s.productId,
+		Type:      qryptos.OrderTypeLimit,
+		Side:      s.side,
+		Quantity:  s.quantity,
+		Price:     s.price,
+	})
+	if qryptos.IsInsufficientFunds(err) {
+		// Balances moved since we planned; the next loop will re-plan with fresh numbers
+		log.Println("[CreateLimitOrderStep::Apply] Skipping order for insufficient funds:", err)
+		return nil
+	}
 	if err != nil {
 		log.Println("[CreateLimitOrderStep::Apply] Error creating order:", err)
-		return err
+		return classifyStepError(err)
 	}
 	log.Println("[CreateLimitOrderStep::Apply] Order created:", orderId)
 	return nil
 }
 
 func (s *CreateLimitOrderStep) String() string {
-	return fmt.Sprintf("Create limit order. ProductID: %d (%s); Side: %s; Quantity: %.08f; Price: %.08f",
-		s.productId, productIdLookup[s.productId], s.side, s.quantity.ToDecimal(), s.price.ToDecimal())
+	return fmt.Sprintf("Create limit order. ProductID: %d (%s); Side: %s; Quantity: %s; Price: %s",
+		s.productId, catalog.PairCode(s.productId), s.side, s.quantity, s.price)
+}
+
+// classifyStepError turns transient exchange errors into plan aborts so that the next loop can try again.
+func classifyStepError(err error) error {
+	if qryptos.IsRateLimited(err) || qryptos.IsInvalidNonce(err) {
+		return plan.Abort(err)
+	}
+	return err
 }
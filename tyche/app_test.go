@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tobyjsullivan/shifty/exchange"
+	"github.com/tobyjsullivan/shifty/exchange/paper"
+	"github.com/tobyjsullivan/shifty/qryptos"
+	"github.com/tobyjsullivan/shifty/qryptos/qryptostest"
+)
+
+func btcProduct(id int, base string, bid, ask qryptos.Amount) *qryptos.ProductDetails {
+	return &qryptos.ProductDetails{
+		ProductID:        id,
+		Currency:         "BTC",
+		BaseCurrency:     base,
+		QuotedCurrency:   "BTC",
+		CurrencyPairCode: base + "BTC",
+		MarketBid:        bid,
+		MarketAsk:        ask,
+		PriceTick:        qryptos.Amount(1000),
+		QuantityStep:     qryptos.Amount(1),
+		MinimumQuantity:  qryptos.Amount(1000000),
+	}
+}
+
+func TestLoop_AgainstServer(t *testing.T) {
+	s := qryptostest.NewServer([]*qryptos.ProductDetails{
+		btcProduct(1, "ETH", qryptos.Amount(5000000), qryptos.Amount(5020000)),
+		btcProduct(2, "LTC", qryptos.Amount(1500000), qryptos.Amount(1520000)),
+		btcProduct(3, "XMR", qryptos.Amount(2000000), qryptos.Amount(2020000)),
+		btcProduct(4, "UBTC", qryptos.Amount(100000), qryptos.Amount(120000)),
+	}, map[string]qryptos.Amount{
+		"BTC": qryptos.Amount(4000000),
+		"ETH": qryptos.Amount(100000000),
+	})
+	defer s.Close()
+
+	// A stale buy below the market bid should be replaced
+	staleId, err := s.Exchange().CreateOrderContext(context.Background(), &qryptos.OrderRequest{
+		ProductID: 2,
+		Type:      qryptos.OrderTypeLimit,
+		Side:      qryptos.OrderSideBuy,
+		Quantity:  qryptos.Amount(10000000),
+		Price:     qryptos.Amount(1400000),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	// So should a sell above the market ask
+	overpricedId, err := s.Exchange().CreateOrderContext(context.Background(), &qryptos.OrderRequest{
+		ProductID: 1,
+		Type:      qryptos.OrderTypeLimit,
+		Side:      qryptos.OrderSideSell,
+		Quantity:  qryptos.Amount(50000000),
+		Price:     qryptos.Amount(5100000),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	opts := []qryptos.ClientOption{qryptos.WithBaseURL(s.URL), qryptos.WithRateLimiter(nil)}
+	ex := exchange.NewQryptos(
+		qryptos.NewPublicClient(opts...),
+		qryptos.NewPrivateClient(qryptostest.DefaultTokenID, qryptostest.DefaultSecret, opts...),
+	)
+	catalog = qryptos.NewCatalog(ex, time.Minute)
+	loop(ex)
+
+	orders, err := s.Exchange().FetchAllOrdersContext(context.Background(), &qryptos.OrdersQuery{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	live := make(map[string]*qryptos.OrderDetails)
+	for _, o := range orders {
+		if o.ID == staleId || o.ID == overpricedId {
+			if o.Status != qryptos.OrderStatusCancelled {
+				t.Errorf("Expected the order to be cancelled: %+v", o)
+			}
+			continue
+		}
+		if o.Status == qryptos.OrderStatusLive {
+			live[o.CurrencyPairCode+" "+o.Side] = o
+		}
+	}
+
+	// The whole ETH balance is offered one tick inside the ask
+	sell := live["ETHBTC sell"]
+	if sell == nil {
+		t.Fatal("Expected a live ETH sell order.")
+	}
+	if sell.Quantity != qryptos.Amount(100000000) || sell.Price != qryptos.Amount(5019000) {
+		t.Errorf("Unexpected ETH sell: %+v", sell)
+	}
+
+	// ETH is already over its share of the budget, so only the others get bids one tick above the market
+	if live["ETHBTC buy"] != nil {
+		t.Errorf("Unexpected ETH buy: %+v", live["ETHBTC buy"])
+	}
+	for _, pair := range []string{"LTCBTC", "XMRBTC", "UBTCBTC"} {
+		buy := live[pair+" buy"]
+		if buy == nil {
+			t.Errorf("Expected a live %s buy order.", pair)
+			continue
+		}
+		if expected := map[string]qryptos.Amount{"LTCBTC": 1501000, "XMRBTC": 2001000, "UBTCBTC": 101000}[pair]; buy.Price != expected {
+			t.Errorf("Unexpected %s bid. Expected: %d; Actual: %d.", pair, expected, buy.Price)
+		}
+	}
+}
+
+func TestCancelOrdersStep(t *testing.T) {
+	sim := paper.New([]*qryptos.ProductDetails{
+		btcProduct(1, "ETH", qryptos.Amount(5000000), qryptos.Amount(5020000)),
+		btcProduct(2, "LTC", qryptos.Amount(1500000), qryptos.Amount(1520000)),
+	}, map[string]qryptos.Amount{"BTC": qryptos.Amount(4000000)})
+
+	for _, productId := range []int{1, 2} {
+		_, err := sim.CreateOrderContext(context.Background(), &qryptos.OrderRequest{
+			ProductID: productId,
+			Type:      qryptos.OrderTypeLimit,
+			Side:      qryptos.OrderSideBuy,
+			Quantity:  qryptos.Amount(10000000),
+			Price:     qryptos.Amount(1400000),
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err.Error())
+		}
+	}
+
+	step := &CancelOrdersStep{sim, &qryptos.CancelFilter{ProductID: 2}}
+	if err := step.Apply(); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	live, err := sim.FetchAllOrdersContext(context.Background(), &qryptos.OrdersQuery{Status: qryptos.OrderStatusLive})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if len(live) != 1 || live[0].ProductID != 1 {
+		t.Errorf("Expected only the ETH order to remain: %+v", live)
+	}
+}
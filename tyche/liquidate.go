@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/tobyjsullivan/shifty/exchange"
+	"github.com/tobyjsullivan/shifty/qryptos"
+	"github.com/tobyjsullivan/shifty/tyche/plan"
+)
+
+// planLiquidation cancels every live order and sells every non-BTC balance at market. It is meant for emergencies,
+// when getting out matters more than the price.
+func planLiquidation(ctx context.Context, p *plan.Plan, orders exchange.OrderManager, balanceMap map[string]qryptos.Money) {
+	p.QueueStep(&CancelOrdersStep{orders, &qryptos.CancelFilter{}})
+
+	for currency, bal := range balanceMap {
+		if currency == "BTC" {
+			continue
+		}
+
+		pairCode := currency + "BTC"
+		product, err := catalog.ProductByPair(ctx, pairCode)
+		if err != nil || product.Disabled {
+			log.Println("[planLiquidation] No market to sell", currency)
+			continue
+		}
+
+		// Orders being cancelled above release their funds before the market order is placed
+		quantity := product.QuantizeQuantity(bal.Amount)
+		if quantity < product.MinimumOrderQuantity() {
+			log.Println("[planLiquidation] Balance too small to sell. Book:", pairCode, "; Quantity:", quantity)
+			continue
+		}
+
+		p.QueueStep(&CreateOrderStep{orders, &qryptos.OrderRequest{
+			ProductID: product.ProductID,
+			Type:      qryptos.OrderTypeMarket,
+			Side:      qryptos.OrderSideSell,
+			Quantity:  quantity,
+		}})
+	}
+}
+
+// CancelOrdersStep cancels every live order matching filter in one go. Orders that fail to cancel are logged rather
+// than stopping the plan, since the rest of the plan may still be able to proceed.
+type CancelOrdersStep struct {
+	orders exchange.OrderManager
+	filter *qryptos.CancelFilter
+}
+
+func (s *CancelOrdersStep) Apply() error {
+	report, err := exchange.CancelOrders(context.Background(), s.orders, s.filter)
+	if err != nil {
+		log.Println("[CancelOrdersStep::Apply] Error listing orders:", err)
+		return classifyStepError(err)
+	}
+
+	for _, failed := range report.Failed() {
+		log.Println("[CancelOrdersStep::Apply] Failed to cancel order:", failed.Order.ID, "; Error:", failed.Err)
+	}
+	log.Println("[CancelOrdersStep::Apply] Cancelled", len(report.Cancelled()), "of", len(report.Results), "orders.")
+
+	return nil
+}
+
+func (s *CancelOrdersStep) String() string {
+	desc := "Cancel all"
+	if s.filter.Match != nil {
+		desc = "Cancel matching"
+	}
+	if s.filter.Side != "" {
+		desc += " " + s.filter.Side
+	}
+	desc += " orders"
+
+	if s.filter.ProductID != 0 {
+		return fmt.Sprintf("%s. ProductID: %d (%s)", desc, s.filter.ProductID, catalog.PairCode(s.filter.ProductID))
+	}
+	return desc
+}
+
+type CreateOrderStep struct {
+	orders exchange.OrderManager
+	order  *qryptos.OrderRequest
+}
+
+func (s *CreateOrderStep) Apply() error {
+	orderId, err := s.orders.CreateOrderContext(context.Background(), s.order)
+	if err != nil {
+		log.Println("[CreateOrderStep::Apply] Error creating order:", err)
+		return classifyStepError(err)
+	}
+	log.Println("[CreateOrderStep::Apply] Order created:", orderId)
+	return nil
+}
+
+func (s *CreateOrderStep) String() string {
+	return fmt.Sprintf("Create %s order. ProductID: %d (%s); Side: %s; Quantity: %s",
+		s.order.Type, s.order.ProductID, catalog.PairCode(s.order.ProductID), s.order.Side, s.order.Quantity)
+}
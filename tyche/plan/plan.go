@@ -11,6 +11,20 @@ type Plan struct {
 	Steps []Step
 }
 
+// AbortError tells Apply to stop applying the remaining steps without treating the failure as fatal.
+type AbortError struct {
+	Err error
+}
+
+func (e *AbortError) Error() string {
+	return "plan aborted: " + e.Err.Error()
+}
+
+// Abort wraps err so that Apply gives up on the rest of the plan instead of exiting.
+func Abort(err error) error {
+	return &AbortError{Err: err}
+}
+
 func (p *Plan) QueueStep(s Step) {
 	p.Steps = append(p.Steps, s)
 }
@@ -20,7 +34,10 @@ func (p *Plan) Apply() {
 		log.Println("[Plan::Apply] Applying step: ", step.String())
 
 		err := step.Apply()
-		if err != nil {
+		if abortErr, ok := err.(*AbortError); ok {
+			log.Println("[Plan::Apply] Step aborted plan:", abortErr.Err)
+			return
+		} else if err != nil {
 			log.Fatalln("[Plan::Apply] Step failed:", err)
 		} else {
 			log.Println("[Plan::Apply] Step applied successfully.")